@@ -4,13 +4,28 @@ Copyright © 2024 Ash Wilson <@ashdwilson>
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 
-	"github.com/ashdwilson/ole/internal/unpacker"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/ole"
+	"github.com/ashdwilson/ole/pkg/sinks"
 	"github.com/spf13/cobra"
 )
 
-var inFile, outDir string
+var inFile, inName, outDir, outFormat string
+var workers int
+
+// Unpacking limits
+var (
+	maxDepth, maxMembers      int
+	maxFileSize, maxTotalSize int64
+	maxRatio                  float64
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -27,11 +42,117 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&inFile, "infile", "i", "", "Input file path.")
-	rootCmd.Flags().StringVarP(&outDir, "outdir", "o", "", "Output directory for extracted assets.")
+	rootCmd.Flags().StringVarP(&inFile, "infile", "i", "", "Input file path. Use - to read from stdin.")
+	rootCmd.Flags().StringVarP(&inName, "name", "n", "stdin", "File name to use for input read from stdin.")
+	rootCmd.Flags().StringVarP(&outDir, "outdir", "o", "", "Output directory for extracted assets. For the tar and zip formats, this is the archive file to create.")
+	rootCmd.Flags().StringVarP(&outFormat, "format", "f", "dir", "Output format: dir, tar or zip. The tar and zip formats keep everything extracted in memory until the run ends, see --max-total-size.")
+	rootCmd.Flags().IntVarP(&workers, "workers", "w", 1, "Number of files to unpack in parallel.")
+	rootCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Maximum nesting depth to unpack. 0 means no limit.")
+	rootCmd.Flags().IntVar(&maxMembers, "max-members", 0, "Maximum number of members to extract from a single container. 0 means no limit.")
+	rootCmd.Flags().Int64Var(&maxFileSize, "max-file-size", 0, "Size in bytes of the largest file to unpack. 0 means no limit.")
+	rootCmd.Flags().Int64Var(&maxTotalSize, "max-total-size", 0, "Maximum number of bytes to extract in total. 0 means no limit.")
+	rootCmd.Flags().Float64Var(&maxRatio, "max-ratio", 0, "Maximum compression ratio for a compressed archive member. 0 means no limit.")
 }
 
 func unpack(cmd *cobra.Command, args []string) (err error) {
-	err = unpacker.Unpack(inFile, outDir)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
+
+	sink, closeOutput, err := openSink(outFormat, outDir)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if closeErr := closeOutput(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	u := ole.New(ole.Options{
+		Logger:              logger,
+		Sink:                sink,
+		Workers:             workers,
+		MaxDepth:            maxDepth,
+		MaxFileSize:         maxFileSize,
+		MaxMembers:          maxMembers,
+		MaxTotalBytes:       maxTotalSize,
+		MaxCompressionRatio: maxRatio,
+	})
+	var results *models.Results
+	if inFile == "-" {
+		// Pipes don't support random access, so stdin is read into memory.
+		var data []byte
+		data, err = io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return
+		}
+		results, err = u.UnpackReader(cmd.Context(), inName, bytes.NewReader(data), int64(len(data)))
+	} else {
+		results, err = u.Unpack(cmd.Context(), inFile)
+	}
+	if results == nil {
+		return
+	}
+
+	// Write results alongside the extracted files
+	logFile, logErr := sink.Create("ole.log")
+	if logErr != nil {
+		return logErr
+	}
+	logErr = json.NewEncoder(logFile).Encode(results)
+	if closeErr := logFile.Close(); logErr == nil {
+		logErr = closeErr
+	}
+	if err == nil {
+		err = logErr
+	}
+	return
+}
+
+// openSink creates the sink for the requested output format. The returned
+// function closes the sink and any file backing it.
+func openSink(format, out string) (sink sinks.Sink, closeOutput func() error, err error) {
+	if format == "dir" {
+		var outDirStat os.FileInfo
+		outDirStat, err = os.Stat(out)
+		if err != nil {
+			err = fmt.Errorf("%w: getting stat on output dir", err)
+			return
+		}
+		if !outDirStat.IsDir() {
+			err = fmt.Errorf("output path is not a dir")
+			return
+		}
+		sink = sinks.NewDir(out)
+		closeOutput = sink.Close
+		return
+	}
+
+	var outFile *os.File
+	switch format {
+	case "tar":
+		outFile, err = os.Create(out)
+		if err == nil {
+			sink = sinks.NewTar(outFile)
+		}
+	case "zip":
+		outFile, err = os.Create(out)
+		if err == nil {
+			sink = sinks.NewZip(outFile)
+		}
+	default:
+		err = fmt.Errorf("unsupported output format: %s", format)
+	}
+	if err != nil {
+		return
+	}
+	closeOutput = func() error {
+		sinkErr := sink.Close()
+		fileErr := outFile.Close()
+		if sinkErr != nil {
+			return sinkErr
+		}
+		return fileErr
+	}
 	return
 }
@@ -5,12 +5,12 @@ go 1.20
 require (
 	github.com/gabriel-vasile/mimetype v1.4.3
 	github.com/richardlehane/mscfb v1.0.4
+	github.com/richardlehane/msoleps v1.0.1
 	github.com/spf13/cobra v1.8.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/richardlehane/msoleps v1.0.1 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/net v0.17.0 // indirect
 )
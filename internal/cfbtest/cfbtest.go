@@ -0,0 +1,321 @@
+// Package cfbtest builds small MS-CFB (OLE 2.0 compound) files for tests.
+//
+// Files are written in version 3 format, with 512-byte sectors. Streams
+// shorter than 4096 bytes go in the mini stream, like Office does. Only
+// as many FAT sectors as fit in the header's DIFAT are supported, which
+// caps the file size at about 7MB.
+package cfbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	sectorSize     = 512
+	miniSectorSize = 64
+	miniCutoff     = 4096
+	dirEntrySize   = 128
+
+	freeSect   uint32 = 0xFFFFFFFF
+	endOfChain uint32 = 0xFFFFFFFE
+	fatSect    uint32 = 0xFFFFFFFD
+	noStream   uint32 = 0xFFFFFFFF
+
+	typeStorage = 1
+	typeStream  = 2
+	typeRoot    = 5
+)
+
+// A Builder collects storages and streams, and lays them out as a compound file.
+type Builder struct {
+	root *node
+}
+
+type node struct {
+	name     string
+	kind     byte
+	clsid    [16]byte
+	data     []byte
+	children []*node
+
+	// Set while laying out the file.
+	id    uint32
+	right uint32
+	start uint32
+}
+
+// New returns a Builder for an empty compound file.
+func New() *Builder {
+	return &Builder{root: &node{name: "Root Entry", kind: typeRoot}}
+}
+
+// SetCLSID sets the class ID of the storage at path. The root storage's
+// path is "". Missing storages are created along the way.
+func (b *Builder) SetCLSID(path string, clsid [16]byte) {
+	b.storage(path).clsid = clsid
+}
+
+// Storage creates an empty storage at path, along with any missing parents.
+func (b *Builder) Storage(path string) {
+	b.storage(path)
+}
+
+// Stream adds a stream at path, creating missing storages along the way.
+// Elements of path are separated by slashes, and may start with control
+// characters like "\x01Ole".
+func (b *Builder) Stream(path string, data []byte) {
+	dir, name := "", path
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		dir, name = path[:i], path[i+1:]
+	}
+	parent := b.storage(dir)
+	parent.children = append(parent.children, &node{name: name, kind: typeStream, data: data})
+}
+
+func (b *Builder) storage(path string) (n *node) {
+	n = b.root
+	if path == "" {
+		return
+	}
+next:
+	for _, name := range strings.Split(path, "/") {
+		for _, c := range n.children {
+			if c.name == name && c.kind == typeStorage {
+				n = c
+				continue next
+			}
+		}
+		c := &node{name: name, kind: typeStorage}
+		n.children = append(n.children, c)
+		n = c
+	}
+	return
+}
+
+// Bytes lays out the compound file.
+func (b *Builder) Bytes() []byte {
+	// Number the directory entries, root first.
+	entries := []*node{}
+	var number func(n *node)
+	number = func(n *node) {
+		n.id = uint32(len(entries))
+		n.right = noStream
+		entries = append(entries, n)
+		sortChildren(n.children)
+		for _, c := range n.children {
+			number(c)
+		}
+		for i := 1; i < len(n.children); i++ {
+			n.children[i-1].right = n.children[i].id
+		}
+	}
+	number(b.root)
+
+	// Small streams are packed into the mini stream, large
+	// ones get sectors of their own.
+	ministream := &bytes.Buffer{}
+	minifat := []uint32{}
+	large := []*node{}
+	for _, n := range entries {
+		if n.kind != typeStream {
+			continue
+		}
+		switch {
+		case len(n.data) == 0:
+			n.start = endOfChain
+		case len(n.data) < miniCutoff:
+			n.start = uint32(len(minifat))
+			count := sectors(len(n.data), miniSectorSize)
+			minifat = append(minifat, chain(n.start, count)...)
+			ministream.Write(pad(n.data, miniSectorSize))
+		default:
+			large = append(large, n)
+		}
+	}
+
+	dirSectors := sectors(len(entries)*dirEntrySize, sectorSize)
+	miniFatSectors := sectors(len(minifat)*4, sectorSize)
+	miniStreamSectors := sectors(ministream.Len(), sectorSize)
+	dataSectors := 0
+	for _, n := range large {
+		dataSectors += sectors(len(n.data), sectorSize)
+	}
+	total := dirSectors + miniFatSectors + miniStreamSectors + dataSectors
+	fatSectors := 0
+	for fatSectors*sectorSize/4 < total+fatSectors {
+		fatSectors++
+	}
+	if fatSectors > 109 {
+		panic(fmt.Sprintf("cfbtest: file too large, %d FAT sectors", fatSectors))
+	}
+
+	// Lay out the sectors, FAT first.
+	fat := []uint32{}
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, fatSect)
+	}
+	alloc := func(count int) (start uint32) {
+		if count == 0 {
+			return endOfChain
+		}
+		start = uint32(len(fat))
+		fat = append(fat, chain(start, count)...)
+		return
+	}
+	dirStart := alloc(dirSectors)
+	miniFatStart := alloc(miniFatSectors)
+	b.root.start = alloc(miniStreamSectors)
+	b.root.data = ministream.Bytes()
+	for _, n := range large {
+		n.start = alloc(sectors(len(n.data), sectorSize))
+	}
+	for len(fat)%(sectorSize/4) != 0 {
+		fat = append(fat, freeSect)
+	}
+
+	out := &bytes.Buffer{}
+	out.Write(header(fatSectors, dirStart, miniFatStart, miniFatSectors))
+	out.Write(pad(uint32s(fat), sectorSize))
+	dir := &bytes.Buffer{}
+	for _, n := range entries {
+		dir.Write(dirEntry(n))
+	}
+	for dir.Len()%sectorSize != 0 {
+		dir.Write(emptyDirEntry())
+	}
+	out.Write(dir.Bytes())
+	out.Write(pad(uint32s(fillFree(minifat, sectorSize/4)), sectorSize))
+	out.Write(pad(ministream.Bytes(), sectorSize))
+	for _, n := range large {
+		out.Write(pad(n.data, sectorSize))
+	}
+	return out.Bytes()
+}
+
+// CLSID converts a class ID in its usual text form,
+// "{00020906-0000-0000-C000-000000000046}", to its on-disk form.
+func CLSID(s string) (clsid [16]byte) {
+	raw, err := hex.DecodeString(strings.NewReplacer("{", "", "}", "", "-", "").Replace(s))
+	if err != nil || len(raw) != 16 {
+		panic(fmt.Sprintf("cfbtest: bad CLSID %q", s))
+	}
+	binary.LittleEndian.PutUint32(clsid[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(clsid[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(clsid[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(clsid[8:], raw[8:])
+	return
+}
+
+// Siblings are kept in a tree, which we let degenerate into a list
+// linked through the right pointers, in the order CFB compares names.
+func sortChildren(children []*node) {
+	sort.Slice(children, func(i, j int) bool {
+		a, b := utf16.Encode([]rune(children[i].name)), utf16.Encode([]rune(children[j].name))
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return strings.ToUpper(children[i].name) < strings.ToUpper(children[j].name)
+	})
+}
+
+func header(fatSectors int, dirStart, miniFatStart uint32, miniFatSectors int) []byte {
+	h := make([]byte, sectorSize)
+	copy(h, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	le := binary.LittleEndian
+	le.PutUint16(h[24:], 0x003E)
+	le.PutUint16(h[26:], 3)
+	le.PutUint16(h[28:], 0xFFFE)
+	le.PutUint16(h[30:], 9)
+	le.PutUint16(h[32:], 6)
+	le.PutUint32(h[44:], uint32(fatSectors))
+	le.PutUint32(h[48:], dirStart)
+	le.PutUint32(h[56:], miniCutoff)
+	le.PutUint32(h[60:], miniFatStart)
+	le.PutUint32(h[64:], uint32(miniFatSectors))
+	le.PutUint32(h[68:], endOfChain)
+	for i := 0; i < 109; i++ {
+		sect := freeSect
+		if i < fatSectors {
+			sect = uint32(i)
+		}
+		le.PutUint32(h[76+i*4:], sect)
+	}
+	return h
+}
+
+func dirEntry(n *node) []byte {
+	e := make([]byte, dirEntrySize)
+	le := binary.LittleEndian
+	name := utf16.Encode([]rune(n.name))
+	if len(name) > 31 {
+		panic(fmt.Sprintf("cfbtest: name too long: %q", n.name))
+	}
+	for i, u := range name {
+		le.PutUint16(e[i*2:], u)
+	}
+	le.PutUint16(e[64:], uint16((len(name)+1)*2))
+	e[66] = n.kind
+	e[67] = 1 // black
+	le.PutUint32(e[68:], noStream)
+	le.PutUint32(e[72:], n.right)
+	le.PutUint32(e[76:], noStream)
+	if len(n.children) > 0 {
+		le.PutUint32(e[76:], n.children[0].id)
+	}
+	copy(e[80:], n.clsid[:])
+	le.PutUint32(e[116:], n.start)
+	le.PutUint32(e[120:], uint32(len(n.data)))
+	return e
+}
+
+// An unused directory entry.
+func emptyDirEntry() []byte {
+	e := make([]byte, dirEntrySize)
+	binary.LittleEndian.PutUint32(e[68:], noStream)
+	binary.LittleEndian.PutUint32(e[72:], noStream)
+	binary.LittleEndian.PutUint32(e[76:], noStream)
+	return e
+}
+
+// Number of sectors needed for size bytes.
+func sectors(size, sectorSize int) int {
+	return (size + sectorSize - 1) / sectorSize
+}
+
+// FAT entries for count sectors, starting at start.
+func chain(start uint32, count int) (entries []uint32) {
+	for i := 1; i < count; i++ {
+		entries = append(entries, start+uint32(i))
+	}
+	return append(entries, endOfChain)
+}
+
+// Pad the table with free entries to a multiple of size.
+func fillFree(table []uint32, size int) []uint32 {
+	for len(table)%size != 0 {
+		table = append(table, freeSect)
+	}
+	return table
+}
+
+// Pad data with zeroes to a multiple of size.
+func pad(data []byte, size int) []byte {
+	if len(data)%size == 0 {
+		return data
+	}
+	return append(append([]byte{}, data...), make([]byte, size-len(data)%size)...)
+}
+
+func uint32s(values []uint32) []byte {
+	out := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(out[i*4:], v)
+	}
+	return out
+}
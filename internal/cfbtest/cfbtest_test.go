@@ -0,0 +1,63 @@
+package cfbtest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// What we build reads back the same through mscfb.
+func TestBuilder(t *testing.T) {
+	clsid := "{00020906-0000-0000-C000-000000000046}"
+	large := bytes.Repeat([]byte("large stream "), 1000)
+	b := New()
+	b.SetCLSID("", CLSID(clsid))
+	b.Stream("\x01CompObj", []byte("compobj"))
+	b.Stream("WordDocument", large)
+	b.Stream("ObjectPool/_1234/\x01Ole10Native", []byte("native"))
+	b.SetCLSID("ObjectPool/_1234", CLSID(clsid))
+	b.Storage("Empty")
+	data := b.Bytes()
+
+	rdr, err := mscfb.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdr.ID() != clsid {
+		t.Errorf("root CLSID mismatch - expected %s got %s", clsid, rdr.ID())
+	}
+	expected := map[string]string{
+		"CompObj":                      "compobj",
+		"WordDocument":                 string(large),
+		"ObjectPool/_1234/Ole10Native": "native",
+		"ObjectPool/_1234":             "",
+		"ObjectPool":                   "",
+		"Empty":                        "",
+	}
+	found := 0
+	for entry, err := rdr.Next(); err == nil; entry, err = rdr.Next() {
+		name := strings.Join(append(append([]string{}, entry.Path...), entry.Name), "/")
+		body, ok := expected[name]
+		if !ok {
+			t.Errorf("unexpected entry %s", name)
+			continue
+		}
+		found++
+		if name == "ObjectPool/_1234" && entry.ID() != clsid {
+			t.Errorf("storage CLSID mismatch - expected %s got %s", clsid, entry.ID())
+		}
+		got, err := io.ReadAll(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != body {
+			t.Errorf("content mismatch for %s - got %d bytes", name, len(got))
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected %d entries, found %d", len(expected), found)
+	}
+}
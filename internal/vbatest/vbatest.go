@@ -0,0 +1,216 @@
+// Package vbatest builds the streams of small VBA projects for tests:
+// MS-OVBA compressed containers, module streams holding p-code, and
+// _VBA_PROJECT streams, and puts them together in MS-CFB files.
+package vbatest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+)
+
+// Size of a decompressed chunk.
+const chunkSize = 4096
+
+// Compress data into an MS-OVBA compressed container. Each 4096-byte
+// block becomes a compressed chunk, or an uncompressed one if that
+// would come out smaller.
+//
+//	Args:
+//		data ([]byte):	The data to compress.
+//
+//	Returns:
+//		out ([]byte):	The compressed container.
+func Compress(data []byte) (out []byte) {
+	buf := bytes.NewBuffer([]byte{0x01})
+	for start := 0; start < len(data); start += chunkSize {
+		block := data[start:]
+		if len(block) > chunkSize {
+			block = block[:chunkSize]
+		}
+		chunk := compressChunk(block)
+		if len(chunk) > chunkSize {
+			binary.Write(buf, binary.LittleEndian, uint16(0x3FFF))
+			buf.Write(block)
+			buf.Write(make([]byte, chunkSize-len(block)))
+			continue
+		}
+		binary.Write(buf, binary.LittleEndian, uint16(0xB000|(len(chunk)+2-3)))
+		buf.Write(chunk)
+	}
+	out = buf.Bytes()
+	return
+}
+
+// Compress a block of up to 4096 bytes.
+func compressChunk(block []byte) []byte {
+	chunk := &bytes.Buffer{}
+	pos := 0
+	for pos < len(block) {
+		flagsAt := chunk.Len()
+		chunk.WriteByte(0)
+		var flags byte
+		for bit := 0; bit < 8 && pos < len(block); bit++ {
+			bitCount := 4
+			for 1<<bitCount < pos {
+				bitCount++
+			}
+			// Take the nearest of the longest matches, then trim it
+			// to what a token can hold.
+			bestLength, bestOffset := 0, 0
+			for candidate := pos - 1; candidate >= 0; candidate-- {
+				length := 0
+				for pos+length < len(block) && block[candidate+length] == block[pos+length] {
+					length++
+				}
+				if length > bestLength {
+					bestLength, bestOffset = length, pos-candidate
+				}
+			}
+			if maxLength := int(uint16(0xFFFF)>>bitCount) + 3; bestLength > maxLength {
+				bestLength = maxLength
+			}
+			if bestLength < 3 {
+				chunk.WriteByte(block[pos])
+				pos++
+				continue
+			}
+			token := uint16(bestOffset-1)<<(16-bitCount) | uint16(bestLength-3)
+			binary.Write(chunk, binary.LittleEndian, token)
+			flags |= 1 << bit
+			pos += bestLength
+		}
+		chunk.Bytes()[flagsAt] = flags
+	}
+	return chunk.Bytes()
+}
+
+// Lay out the p-code section of a module stream, the way
+// parsers.DisassemblePCode expects it, around lines of encoded
+// instructions. The compressed source code goes after it.
+//
+//	Args:
+//		lines ([][]byte):	The instructions of each line.
+//
+//	Returns:
+//		stream ([]byte):	The start of a module stream.
+func PCode(lines [][]byte) (stream []byte) {
+	// Leave the offset at 0x19 as 0, which puts the line table at 0x3C.
+	header := make([]byte, 0x3C)
+	binary.LittleEndian.PutUint16(header, 0x01CC)
+	buf := bytes.NewBuffer(header)
+	binary.Write(buf, binary.LittleEndian, uint16(0xCAFE))
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, uint16(len(lines)))
+	offset := 0
+	for _, line := range lines {
+		buf.Write(make([]byte, 4))
+		binary.Write(buf, binary.LittleEndian, uint16(len(line)))
+		buf.Write(make([]byte, 2))
+		binary.Write(buf, binary.LittleEndian, uint32(offset))
+		offset += len(line)
+	}
+	buf.Write(make([]byte, 10))
+	for _, line := range lines {
+		buf.Write(line)
+	}
+	stream = buf.Bytes()
+	return
+}
+
+// Lay out a little-endian _VBA_PROJECT stream, with a performance cache
+// holding no references or modules, just the identifiers, the way
+// parsers.NewVBAProjectStream walks it.
+//
+//	Args:
+//		version (uint16):	The VBA version.
+//		identifiers ([]string):	The project's identifiers.
+//
+//	Returns:
+//		stream ([]byte):	The _VBA_PROJECT stream.
+func Project(version uint16, identifiers []string) (stream []byte) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, []uint16{0x61CC, version})
+	// The rest of the header, and the cache up to the references.
+	buf.Write(make([]byte, 0x1E-buf.Len()))
+	// No references, class table or constants.
+	buf.Write(make([]byte, 2+2+2+2+2))
+	// No type info ID, description or help file.
+	buf.Write(make([]byte, 3*4+0x64))
+	// No modules.
+	buf.Write(make([]byte, 2+6+4+6))
+	n := uint16(len(identifiers))
+	binary.Write(buf, binary.LittleEndian, []uint16{n, n, 0})
+	buf.Write(make([]byte, 4))
+	for _, id := range identifiers {
+		buf.Write([]byte{byte(len(id)), 0})
+		buf.WriteString(id)
+		buf.Write(make([]byte, 4))
+	}
+	stream = buf.Bytes()
+	return
+}
+
+// A Module of a project built by AddProject.
+type Module struct {
+	Name   string
+	Class  bool
+	Source string
+
+	// Encoded instructions for each line of 32-bit p-code
+	PCode [][]byte
+}
+
+// Add a 32-bit VBA project with the given modules to cfb, in the VBA
+// storage under dir ("" for vbaProject.bin). Modules without p-code get
+// some stand-in bytes ahead of their compressed source, and unless one
+// has p-code, the project is versioned 0xFFFF, as tools write them.
+// The identifiers go in _VBA_PROJECT, see ID.
+//
+//	Args:
+//		cfb (*cfbtest.Builder):	The file to add the project to.
+//		dir (string):		The storage holding the VBA storage.
+//		modules ([]Module):	The project's modules.
+//		identifiers (...string):	The project's identifiers.
+func AddProject(cfb *cfbtest.Builder, dir string, modules []Module, identifiers ...string) {
+	vba := path.Join(dir, "VBA")
+	record := func(buf *bytes.Buffer, id uint16, data []byte) {
+		binary.Write(buf, binary.LittleEndian, id)
+		binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	version := uint16(0xFFFF)
+	records := &bytes.Buffer{}
+	record(records, 0x0001, []byte{0x01, 0x00, 0x00, 0x00})
+	record(records, 0x0003, []byte{0xE4, 0x04})
+	record(records, 0x0004, []byte("Project"))
+	for _, m := range modules {
+		pcode := bytes.Repeat([]byte{0x01, 0x16, 0x03, 0x00}, 16)
+		if m.PCode != nil {
+			pcode = PCode(m.PCode)
+			version = 0xB2
+		}
+		record(records, 0x0019, []byte(m.Name))
+		record(records, 0x001A, []byte(m.Name))
+		record(records, 0x0031, binary.LittleEndian.AppendUint32(nil, uint32(len(pcode))))
+		if m.Class {
+			record(records, 0x0022, nil)
+		} else {
+			record(records, 0x0021, nil)
+		}
+		record(records, 0x002B, nil)
+		cfb.Stream(vba+"/"+m.Name, append(append([]byte{}, pcode...), Compress([]byte(m.Source))...))
+	}
+	record(records, 0x0010, nil)
+	cfb.Stream(vba+"/dir", Compress(records.Bytes()))
+	cfb.Stream(vba+"/_VBA_PROJECT", Project(version, identifiers))
+	cfb.Stream(path.Join(dir, "PROJECT"), []byte("ID=\"{00000000-0000-0000-0000-000000000000}\"\r\n"))
+}
+
+// The ID by which 32-bit VBA 7 p-code refers to the project's identifier
+// at index.
+func ID(index int) uint16 {
+	return uint16(0x100+4+index) << 1
+}
@@ -0,0 +1,82 @@
+package vbatest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/richardlehane/mscfb"
+)
+
+// Data with nothing to match comes out exactly as in the example from
+// [MS-OVBA] 3.2.1. Otherwise, there may be several matches to pick from.
+func TestCompress(t *testing.T) {
+	expected := []byte{
+		0x01, 0x19, 0xB0, 0x00, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x00, 0x69, 0x6A, 0x6B,
+		0x6C, 0x6D, 0x6E, 0x6F, 0x70, 0x00, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x2E,
+	}
+	if out := Compress([]byte("abcdefghijklmnopqrstuv.")); !bytes.Equal(out, expected) {
+		t.Errorf("expected % X got % X", expected, out)
+	}
+}
+
+// What we build disassembles the same through parsers.
+func TestPCode(t *testing.T) {
+	// FuncDefn, then EndSub.
+	stream := PCode([][]byte{{0x96, 0x00, 0x10, 0x00, 0x00, 0x00}, {0x6F, 0x00}})
+	lines, err := parsers.DisassemblePCode(stream, 0xB2, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].Instructions[0].String() != "FuncDefn func_00000010" || lines[1].Instructions[0].Mnemonic != "EndSub" {
+		t.Errorf("p-code mismatch: %+v", lines)
+	}
+}
+
+// What we build gives its identifiers back through parsers.
+func TestProject(t *testing.T) {
+	p, err := parsers.NewVBAProjectStream(Project(0x97, []string{"AutoOpen", "Shell"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 0x97 || len(p.Identifiers) != 2 || p.Identifiers[0] != "AutoOpen" || p.Identifiers[1] != "Shell" {
+		t.Errorf("project mismatch: %+v", p)
+	}
+}
+
+// What we build reads back as a project through mscfb and parsers.
+func TestAddProject(t *testing.T) {
+	cfb := cfbtest.New()
+	AddProject(cfb, "Macros", []Module{{Name: "Module1", Source: "Attribute VB_Name = \"Module1\"\r\n"}})
+	rdr, err := mscfb.New(bytes.NewReader(cfb.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := map[string][]byte{}
+	for entry, err := rdr.Next(); err == nil; entry, err = rdr.Next() {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		data, err := io.ReadAll(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		streams[entry.Name] = data
+	}
+	d, err := parsers.NewVBADir(streams["dir"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Modules) != 1 || d.Modules[0].Name != "Module1" || d.Modules[0].Type != parsers.VBAModuleProcedural {
+		t.Fatalf("dir mismatch: %+v", d)
+	}
+	source, err := d.Modules[0].Source(streams["Module1"])
+	if err != nil || string(source) != "Attribute VB_Name = \"Module1\"\r\n" {
+		t.Errorf("expected the module source, got %q, %v", source, err)
+	}
+	if _, ok := streams["PROJECT"]; !ok {
+		t.Errorf("expected a PROJECT stream")
+	}
+}
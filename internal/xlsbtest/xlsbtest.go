@@ -0,0 +1,46 @@
+// Package xlsbtest builds the BIFF12 records of small .xlsb parts for
+// tests.
+package xlsbtest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// Append a BIFF12 record, with its type and size as variable-length
+// integers.
+//
+//	Args:
+//		buf (*bytes.Buffer):	The part to append the record to.
+//		recordType (int):	The record type.
+//		data (...[]byte):	The record's data, in pieces.
+func Record(buf *bytes.Buffer, recordType int, data ...[]byte) {
+	body := bytes.Join(data, nil)
+	for _, v := range []int{recordType, len(body)} {
+		for v >= 0x80 {
+			buf.WriteByte(byte(v) | 0x80)
+			v >>= 7
+		}
+		buf.WriteByte(byte(v))
+	}
+	buf.Write(body)
+}
+
+// U32 returns the values as little-endian uint32s.
+func U32(v ...uint32) (b []byte) {
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint32(b, x)
+	}
+	return
+}
+
+// Wide returns a UTF-16 string, prefixed by its length as a uint32.
+func Wide(s string) (b []byte) {
+	chars := utf16.Encode([]rune(s))
+	b = U32(uint32(len(chars)))
+	for _, c := range chars {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return
+}
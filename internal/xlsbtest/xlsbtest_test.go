@@ -0,0 +1,16 @@
+package xlsbtest
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Types and sizes of 0x80 and up take more than a byte.
+func TestRecord(t *testing.T) {
+	buf := &bytes.Buffer{}
+	Record(buf, 156, U32(1), Wide("A"))
+	expected := []byte{0x9C, 0x01, 0x0A, 1, 0, 0, 0, 1, 0, 0, 0, 'A', 0}
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("expected % X got % X", expected, buf.Bytes())
+	}
+}
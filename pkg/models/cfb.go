@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Metadata from a storage in an MS-CFB (OLE 2.0) file
+type Storage struct {
+	// Class ID of the storage, if set
+	CLSID string `json:",omitempty"`
+
+	// From the storage's CompObj stream, if it has one
+	CompObj *CompObj `json:",omitempty"`
+
+	// From the storage's Ole stream, if it has one
+	Ole *OleStream `json:",omitempty"`
+
+	// From the storage's SummaryInformation and
+	// DocumentSummaryInformation property sets, if it has them
+	Summary *Summary `json:",omitempty"`
+
+	// The VBA project, for VBA storages with a dir stream
+	VBA *VBAProject `json:",omitempty"`
+}
+
+// Metadata from a CompObj stream, which names the
+// application an embedded object belongs to
+type CompObj struct {
+	// Display name of the object's type
+	UserType string
+
+	// A standard clipboard format, or the name of a registered one
+	ClipboardFormat     uint32 `json:",omitempty"`
+	ClipboardFormatName string `json:",omitempty"`
+
+	// Programmatic ID of the object's class, e.g. "Word.Document.8"
+	ProgID string
+
+	// Unicode copies of the fields above, when present
+	UnicodeUserType            string `json:",omitempty"`
+	UnicodeClipboardFormatName string `json:",omitempty"`
+	UnicodeProgID              string `json:",omitempty"`
+}
+
+// Metadata from an Ole stream, which says whether an
+// object is linked, and where the link points
+type OleStream struct {
+	Flags            uint32
+	LinkUpdateOption uint32
+
+	// Set for linked objects, whose data lives somewhere else
+	Linked bool
+
+	// Where a link points: a path, UNC path or URL
+	Target string `json:",omitempty"`
+
+	// The monikers the target comes from
+	RelativeSource *Moniker `json:",omitempty"`
+	AbsoluteSource *Moniker `json:",omitempty"`
+
+	// Class ID and display name of the link source
+	CLSID       string `json:",omitempty"`
+	DisplayName string `json:",omitempty"`
+}
+
+// A moniker names something to link to
+type Moniker struct {
+	// File, URL, Item, Anti, Composite or Unknown
+	Kind  string
+	CLSID string
+
+	// What this moniker points at. For composites, this is
+	// the concatenation of the parts.
+	Target string `json:",omitempty"`
+
+	// The parts of a composite moniker
+	Monikers []*Moniker `json:",omitempty"`
+}
+
+// Document metadata from the SummaryInformation and
+// DocumentSummaryInformation property sets
+type Summary struct {
+	// Code page of the strings in the property sets
+	CodePage uint16 `json:",omitempty"`
+
+	Title       string `json:",omitempty"`
+	Subject     string `json:",omitempty"`
+	Author      string `json:",omitempty"`
+	LastSavedBy string `json:",omitempty"`
+	Keywords    string `json:",omitempty"`
+	Comments    string `json:",omitempty"`
+	Template    string `json:",omitempty"`
+	Revision    string `json:",omitempty"`
+	Application string `json:",omitempty"`
+
+	Created     *time.Time `json:",omitempty"`
+	LastSaved   *time.Time `json:",omitempty"`
+	LastPrinted *time.Time `json:",omitempty"`
+
+	// From DocumentSummaryInformation
+	Company  string `json:",omitempty"`
+	Manager  string `json:",omitempty"`
+	Category string `json:",omitempty"`
+
+	// User-defined properties, by name
+	Custom map[string]string `json:",omitempty"`
+}
+
+// Metadata from an OlePres stream, which holds a picture of an
+// embedded object
+type OlePresentation struct {
+	// A standard clipboard format, or the name of a registered one
+	ClipboardFormat     uint32 `json:",omitempty"`
+	ClipboardFormatName string `json:",omitempty"`
+
+	// 1 for the object's content, 2 for a thumbnail, 4 for an
+	// icon and 8 for a printer rendering
+	Aspect uint32
+
+	Width  uint32
+	Height uint32
+
+	// Size of the presentation data
+	Size int64
+}
@@ -0,0 +1,41 @@
+package models
+
+// The kind of Office Open XML document a zip package holds, going by
+// the content type of its main part
+type OfficeDocument struct {
+	// Usual extension of the kind, like docm or potx
+	Kind string
+
+	// Word, Excel, PowerPoint, or Office for themes
+	Application string
+
+	// Name and content type of the main part, like word/document.xml
+	MainPart    string
+	ContentType string
+
+	// Set for kinds which can hold VBA macros
+	MacroEnabled bool `json:",omitempty"`
+}
+
+// A part of an Open Packaging Conventions (OPC) package, like an Office
+// Open XML document
+type PackagePart struct {
+	// Content type, from [Content_Types].xml
+	ContentType string `json:",omitempty"`
+
+	// Relationships pointing at the part
+	References []*PartReference `json:",omitempty"`
+
+	// Set if nothing points at the part. Unreferenced parts are ignored
+	// by Office, which makes them a place to hide things.
+	Orphaned bool `json:",omitempty"`
+}
+
+// A relationship from one part of a package to another
+type PartReference struct {
+	// Part the relationship is from, or empty for the package itself
+	Source string
+
+	ID   string
+	Type string
+}
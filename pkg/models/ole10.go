@@ -0,0 +1,80 @@
+package models
+
+// Metadata from an OLE 1.0 (Ole10Native) stream
+type Ole10 struct {
+	// What the stream holds: "Package" for a file wrapped up by
+	// Packager, or the kind of native data written by another
+	// OLE 1.0 server. Only Package objects have header words,
+	// labels, paths and trailing data.
+	Kind string
+
+	// Size of the stream following the total size field, as recorded
+	TotalSize int64
+
+	// Header words: Flags is usually 2 and Reserved 0. ObjectType
+	// is 3 for embedded files and 1 for links.
+	Flags      uint16
+	Reserved   uint16
+	ObjectType uint16
+
+	// Label shown for the object, usually the name of the enclosed file
+	Label string
+
+	// Paths recorded by the packaging program
+	SourcePath string
+	TempPath   string
+
+	// Size of the enclosed object
+	Size int64
+
+	// Unicode copies of the fields above, found after the enclosed object
+	UnicodeLabel      string
+	UnicodeSourcePath string
+	UnicodeTempPath   string
+
+	// Size of everything after the enclosed object
+	TrailingSize int64
+
+	// Size of any trailing data beyond the Unicode fields. Non-zero
+	// values are worth a closer look.
+	UnknownTrailingSize int64
+}
+
+// Metadata from a serialized OLE 1.0 object
+type Ole1Object struct {
+	// 1 for linked objects, 2 for embedded ones
+	FormatID uint32
+
+	// OLE 1.0 class of the object, e.g. "Package" or "PBrush"
+	ClassName string
+
+	// For links, the linked file and the part of it
+	TopicName string
+	ItemName  string
+
+	// Linked objects only
+	NetworkName      string
+	LinkUpdateOption uint32
+
+	// Size of the native data, for embedded objects
+	NativeSize int64
+
+	// How the object was last drawn, if recorded
+	Presentation *Ole1Presentation `json:",omitempty"`
+}
+
+// An OLE 1.0 presentation object
+type Ole1Presentation struct {
+	// METAFILEPICT, BITMAP or DIB. Empty for other formats, which
+	// are described by the clipboard format instead.
+	ClassName string
+
+	Width  uint32
+	Height int32
+
+	ClipboardFormat     uint32
+	ClipboardFormatName string
+
+	// Size of the presentation data
+	Size int64
+}
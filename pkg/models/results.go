@@ -1,10 +1,42 @@
 package models
 
+import "sync"
+
 // Scan Results
+//
+// Results are safe for concurrent use through Get and Set. Each Result is
+// only ever modified by the worker unpacking that file, so once a Result
+// has been fetched, its fields can be updated without further locking.
 type Results struct {
+	mu sync.RWMutex
+
 	ParsedFiles map[string]*Result
 }
 
+// Create an empty set of results.
+func NewResults() (r *Results) {
+	r = &Results{ParsedFiles: map[string]*Result{}}
+	return
+}
+
+// Get returns the result for key, or nil if there isn't one.
+func (r *Results) Get(key string) (result *Result) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result = r.ParsedFiles[key]
+	return
+}
+
+// Set stores the result for key, replacing any existing result.
+func (r *Results) Set(key string, result *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ParsedFiles == nil {
+		r.ParsedFiles = map[string]*Result{}
+	}
+	r.ParsedFiles[key] = result
+}
+
 // A single result item
 type Result struct {
 	// MIME type of file
@@ -18,4 +50,87 @@ type Result struct {
 
 	// Any error output
 	Error string
+
+	// Hashes of the file contents
+	Hashes Hashes
+
+	// Key of the container this file was extracted from. Empty for the input file.
+	Parent string
+
+	// Nesting depth. The input file is at depth 0, its members at depth 1, etc.
+	Depth int
+
+	// Name of the unpacker registration which extracted this file.
+	Unpacker string
+
+	// Name of this file inside its parent container, as found in the container.
+	MemberName string
+
+	// Name of this file in its parent's members directory. This is MemberName
+	// after sanitizing it and resolving collisions with other members, so the
+	// key of this result is always "${Parent}-members/${SafeName}".
+	SafeName string
+
+	// Keys of the files extracted from this one, in extraction order.
+	Children []string
+
+	// Limits which stopped this file from being fully unpacked.
+	LimitsExceeded []LimitExceeded
+
+	// Format-specific metadata. Only the fields relevant to the file's
+	// format are set, and the rest are left out of the JSON output.
+
+	// Metadata from an OLE 1.0 (Ole10Native) stream
+	Ole10 *Ole10 `json:",omitempty"`
+
+	// Metadata from a serialized OLE 1.0 object
+	Ole1Object *Ole1Object `json:",omitempty"`
+
+	// Metadata from an OlePres stream
+	OlePresentation *OlePresentation `json:",omitempty"`
+
+	// Metadata from the root storage of an MS-CFB file, and from
+	// the storages inside it, keyed by their path in the file
+	Storage  *Storage            `json:",omitempty"`
+	Storages map[string]*Storage `json:",omitempty"`
+
+	// The kind of Office Open XML document a zip package holds
+	Office *OfficeDocument `json:",omitempty"`
+
+	// Where a part of a zip package fits in the package's structure
+	Part *PackagePart `json:",omitempty"`
+
+	// Sheets, defined names and macros from an Excel workbook
+	Workbook *Workbook `json:",omitempty"`
+}
+
+// Hex-encoded cryptographic hashes of a file
+type Hashes struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// Names of the limits reported in LimitExceeded
+const (
+	LimitDepth            = "depth"
+	LimitFileSize         = "file_size"
+	LimitMembers          = "members"
+	LimitTotalBytes       = "total_bytes"
+	LimitCompressionRatio = "compression_ratio"
+)
+
+// A limit which was hit while unpacking a file
+type LimitExceeded struct {
+	// Which limit was hit (one of the Limit* constants)
+	Limit string
+
+	// The configured maximum
+	Max float64
+
+	// The value which exceeded the maximum
+	Value float64
+
+	// The member involved, if the limit applies to a single member
+	Member string
 }
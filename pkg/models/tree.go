@@ -0,0 +1,59 @@
+package models
+
+import "sort"
+
+// A Node is one file in the containment tree built by Results.Tree.
+type Node struct {
+	// Key of the file in Results.ParsedFiles.
+	Path string
+
+	*Result
+
+	// The files extracted from this one, in extraction order.
+	Nodes []*Node
+}
+
+// Tree returns the containment tree of all results. Normally there is a
+// single root, the input file; any result whose parent is missing is
+// returned as an additional root. Roots are sorted by path.
+func (r *Results) Tree() (roots []*Node) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make(map[string]*Node, len(r.ParsedFiles))
+	for path, result := range r.ParsedFiles {
+		nodes[path] = &Node{Path: path, Result: result}
+	}
+	for _, node := range nodes {
+		if _, ok := nodes[node.Parent]; !ok {
+			roots = append(roots, node)
+		}
+		for _, child := range node.Children {
+			if childNode, ok := nodes[child]; ok {
+				node.Nodes = append(node.Nodes, childNode)
+			}
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool { return roots[i].Path < roots[j].Path })
+	return
+}
+
+// Lineage returns the chain of containers leading to the file at path,
+// starting with the input file and ending with path itself. For example:
+//
+//	doc.docx → doc.docx-members/oleObject1.bin → .../Ole10Native → .../Untitled.msg
+//
+// Nil is returned if there is no result for path.
+func (r *Results) Lineage(path string) (chain []string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	seen := map[string]bool{}
+	for result, ok := r.ParsedFiles[path]; ok && !seen[path]; result, ok = r.ParsedFiles[path] {
+		seen[path] = true
+		chain = append([]string{path}, chain...)
+		if result.Parent == "" {
+			break
+		}
+		path = result.Parent
+	}
+	return
+}
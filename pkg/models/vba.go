@@ -0,0 +1,53 @@
+package models
+
+// A VBA project, from the dir stream of a VBA storage
+type VBAProject struct {
+	Name     string
+	CodePage uint16
+
+	// Platform the project was saved on: 0 for 16-bit Windows, 1 for
+	// 32-bit Windows, 2 for Mac, or 3 for 64-bit Windows
+	SysKind uint32
+
+	// Version of VBA which compiled the p-code, from _VBA_PROJECT
+	Version uint16 `json:",omitempty"`
+
+	Modules []*VBAModule
+}
+
+// A module of a VBA project
+type VBAModule struct {
+	Name string
+
+	// Name of the module's stream in the VBA storage
+	StreamName string
+
+	// Procedural, or Class for document, class and form modules
+	Type string
+
+	// Offset of the compressed source code in the module stream.
+	// The compiled p-code comes before it.
+	TextOffset uint32
+
+	ReadOnly bool `json:",omitempty"`
+	Private  bool `json:",omitempty"`
+
+	// MemberName of the decompressed source code, if it was extracted
+	Source string `json:",omitempty"`
+
+	// MemberName of the disassembled p-code, if it was extracted, and
+	// the number of lines in it
+	PCode      string `json:",omitempty"`
+	PCodeLines int    `json:",omitempty"`
+
+	// Set if the p-code doesn't match the source code. Office runs the
+	// p-code when the VBA version matches, so a stomped module can do
+	// something other than what its source says. The identifier table
+	// this relies on is undocumented, and it hasn't been checked against
+	// real stomped files yet, so it isn't rolled up into a verdict on
+	// the file: take it as a lead, not a finding.
+	Stomped bool `json:",omitempty"`
+
+	// Why the module was flagged as stomped
+	StompingReason string `json:",omitempty"`
+}
@@ -0,0 +1,57 @@
+package models
+
+// Metadata from the Workbook stream of an .xls file, for finding
+// Excel 4.0 (XLM) macros
+type Workbook struct {
+	// Set if the workbook is encrypted, in which case only the sheets
+	// are listed
+	Encrypted bool `json:",omitempty"`
+
+	Sheets []*Sheet
+	Names  []*DefinedName `json:",omitempty"`
+
+	// Formulas from the macro sheets, in the order they're stored
+	Macros []*XLMFormula `json:",omitempty"`
+}
+
+// A sheet of a workbook
+type Sheet struct {
+	Name string
+
+	// Worksheet, Macro, Chart or VBModule
+	Type string
+
+	// Visible, Hidden or VeryHidden. Very hidden sheets can't be shown
+	// from Excel's user interface.
+	Visibility string
+}
+
+// A defined name of a workbook
+type DefinedName struct {
+	Name string
+
+	// The sheet the name is scoped to, if it isn't the whole workbook
+	Sheet string `json:",omitempty"`
+
+	// What the name refers to, like =Macro1!$A$1
+	Formula string
+
+	Hidden  bool `json:",omitempty"`
+	Builtin bool `json:",omitempty"`
+	Macro   bool `json:",omitempty"`
+
+	// Set for Auto_Open, Auto_Close and the like, which Excel runs
+	// on its own
+	AutoRun bool `json:",omitempty"`
+}
+
+// A formula from a macro sheet
+type XLMFormula struct {
+	Sheet string
+
+	// The cell, in A1 notation
+	Cell string
+
+	// The formula, like =EXEC("calc.exe")
+	Formula string
+}
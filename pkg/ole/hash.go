@@ -0,0 +1,37 @@
+package ole
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"github.com/ashdwilson/ole/pkg/models"
+)
+
+// hasher computes all of the hashes we report in a single pass. Write
+// everything to it, then call Sum.
+type hasher struct {
+	io.Writer
+	md5    hash.Hash
+	sha1   hash.Hash
+	sha256 hash.Hash
+}
+
+func newHasher() (h *hasher) {
+	h = &hasher{md5: md5.New(), sha1: sha1.New(), sha256: sha256.New()}
+	h.Writer = io.MultiWriter(h.md5, h.sha1, h.sha256)
+	return
+}
+
+// Sum returns the hashes of everything written so far.
+func (h *hasher) Sum() (hashes models.Hashes) {
+	hashes = models.Hashes{
+		MD5:    hex.EncodeToString(h.md5.Sum(nil)),
+		SHA1:   hex.EncodeToString(h.sha1.Sum(nil)),
+		SHA256: hex.EncodeToString(h.sha256.Sum(nil)),
+	}
+	return
+}
@@ -0,0 +1,321 @@
+package ole
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
+	"github.com/ashdwilson/ole/pkg/unpackers"
+)
+
+// memberSink is the sink handed to an unpackers.Unpacker. It scopes the
+// underlying sink to the members directory of the archive being unpacked,
+// gives every member a safe and unique name, and queues every file created
+// in it for further unpacking.
+type memberSink struct {
+	sinks.Sink
+
+	// Directory in the underlying sink that members are written to.
+	prefix string
+
+	// Path of the archive being unpacked.
+	parent string
+
+	// Name of the registration unpacking the archive.
+	unpacker string
+
+	// Number of members created so far.
+	members int
+
+	// Hands out unique names for the members of this archive.
+	namer *unpackers.Namer
+
+	// The names handed out to files.
+	files map[string]bool
+
+	// With more than one worker, the directories and files created so
+	// far, in order, waiting for flush. Also the number of bytes written
+	// to the files, which can't exceed MaxTotalBytes.
+	pending  []pendingMember
+	buffered int64
+
+	j *job
+}
+
+// Open opens a member by the name the Namer gave it (its SafeName), which
+// is not necessarily the name it was created with. Members can only be
+// opened once they're in the underlying sink: with more than one worker,
+// that is after the unpacker returns.
+func (m *memberSink) Open(name string) (f fs.File, err error) {
+	if !m.files[name] {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		return
+	}
+	f, err = m.Sink.Open(path.Join(m.prefix, name))
+	return
+}
+
+// Create creates a member. It is queued up once the writer is closed.
+func (m *memberSink) Create(name string) (w io.WriteCloser, err error) {
+	w, err = m.CreateTyped(name, "")
+	return
+}
+
+// CreateTyped implements unpackers.Typer. The member's MIME type is
+// recorded on its result, and used in place of detection.
+func (m *memberSink) CreateTyped(name, mimeType string) (w io.WriteCloser, err error) {
+	w, err = m.CreateDescribed(name, &models.Result{FileType: mimeType})
+	return
+}
+
+// CreateDescribed implements unpackers.Describer. The member's result
+// starts out as result, once it's closed.
+func (m *memberSink) CreateDescribed(name string, result *models.Result) (w io.WriteCloser, err error) {
+	if max := m.j.opts.MaxMembers; max > 0 && m.members >= max {
+		m.j.limitExceeded(m.parent, models.LimitExceeded{Limit: models.LimitMembers, Max: float64(max), Value: float64(m.members + 1), Member: name})
+		err = fmt.Errorf("%w: more than %d members", unpackers.ErrLimitExceeded, max)
+		return
+	}
+	m.members++
+	safe := m.namer.File(name)
+	m.files[safe] = true
+	full := path.Join(m.prefix, safe)
+	mw := &memberWriter{name: full, member: name, safe: safe, result: result, sink: m, hash: newHasher()}
+	if m.j.buffered {
+		mw.buf = &bytes.Buffer{}
+		m.pending = append(m.pending, pendingMember{file: mw})
+		w = mw
+		return
+	}
+	mw.out, err = m.Sink.Create(full)
+	if err != nil {
+		return
+	}
+	w = mw
+	return
+}
+
+// Mkdir creates a directory under the members directory.
+func (m *memberSink) Mkdir(name string) (err error) {
+	full := path.Join(m.prefix, m.namer.Dir(name))
+	if m.j.buffered {
+		m.pending = append(m.pending, pendingMember{dir: full})
+		return
+	}
+	err = m.Sink.Mkdir(full)
+	return
+}
+
+// CheckRatio implements unpackers.Limiter.
+func (m *memberSink) CheckRatio(name string, compressedSize, uncompressedSize int64) (err error) {
+	max := m.j.opts.MaxCompressionRatio
+	if max <= 0 || uncompressedSize == 0 {
+		return
+	}
+	ratio := float64(uncompressedSize) / float64(max64(compressedSize, 1))
+	if ratio > max {
+		m.j.limitExceeded(m.parent, models.LimitExceeded{Limit: models.LimitCompressionRatio, Max: max, Value: ratio, Member: name})
+		err = fmt.Errorf("%w: compression ratio of %s is %.1f", unpackers.ErrLimitExceeded, name, ratio)
+	}
+	return
+}
+
+// Close is a no-op, the underlying sink outlives the archive.
+func (m *memberSink) Close() (err error) {
+	return
+}
+
+// flush writes out the buffered members, in the order they were created,
+// and queues them. The byte budget is charged along the way, so which
+// members MaxTotalBytes cuts short doesn't depend on scheduling. It must
+// only be called on the archive's turn, see job.done.
+func (m *memberSink) flush() (err error) {
+	var errs []error
+	for _, p := range m.pending {
+		if p.file == nil {
+			errs = append(errs, m.Sink.Mkdir(p.dir))
+			continue
+		}
+		errs = append(errs, p.file.flush())
+	}
+	m.pending = nil
+	err = errors.Join(errs...)
+	return
+}
+
+// A pendingMember is a directory or file waiting to be flushed.
+type pendingMember struct {
+	dir  string
+	file *memberWriter
+}
+
+// memberWriter hashes its file as it is written, and queues it once
+// it has been closed. With more than one worker, the file is buffered
+// until its archive's turn comes, and written out by flush.
+type memberWriter struct {
+	name   string
+	member string
+	safe   string
+	sink   *memberSink
+	hash   *hasher
+	closed bool
+
+	// The file in the underlying sink, or the buffer standing in for it.
+	out io.WriteCloser
+	buf *bytes.Buffer
+
+	// The member's result, as far as the unpacker filled it in.
+	result *models.Result
+
+	// Set if the member was truncated by MaxTotalBytes.
+	truncated bool
+
+	// Bytes refused while buffering, because the archive went over
+	// MaxTotalBytes by itself.
+	refused int64
+}
+
+func (w *memberWriter) Write(p []byte) (n int, err error) {
+	if w.buf != nil {
+		n, err = w.bufferWrite(p)
+		return
+	}
+	j := w.sink.j
+	granted, total := j.reserve(int64(len(p)))
+	if granted < int64(len(p)) {
+		w.truncate(total)
+		p = p[:granted]
+		err = fmt.Errorf("%w: more than %d bytes written", unpackers.ErrLimitExceeded, j.opts.MaxTotalBytes)
+	}
+	n, writeErr := w.out.Write(p)
+	w.hash.Write(p[:n])
+	if writeErr != nil {
+		err = writeErr
+	}
+	return
+}
+
+// bufferWrite holds on to p until flush. Whatever happens then, an archive
+// can't write more than MaxTotalBytes, so that much is all we buffer.
+func (w *memberWriter) bufferWrite(p []byte) (n int, err error) {
+	if max := w.sink.j.opts.MaxTotalBytes; max > 0 && w.sink.buffered+int64(len(p)) > max {
+		w.refused += w.sink.buffered + int64(len(p)) - max
+		p = p[:max-w.sink.buffered]
+		err = fmt.Errorf("%w: more than %d bytes written", unpackers.ErrLimitExceeded, max)
+	}
+	n, _ = w.buf.Write(p)
+	w.sink.buffered += int64(n)
+	return
+}
+
+// truncate records that the member was cut short, having tried to bring
+// the run's total to total bytes.
+func (w *memberWriter) truncate(total int64) {
+	if !w.truncated {
+		w.sink.j.limitExceeded(w.sink.parent, models.LimitExceeded{Limit: models.LimitTotalBytes, Max: float64(w.sink.j.opts.MaxTotalBytes), Value: float64(total), Member: w.member})
+	}
+	w.truncated = true
+}
+
+func (w *memberWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	if w.buf != nil {
+		return
+	}
+	err = w.out.Close()
+	if err != nil {
+		return
+	}
+	w.finish()
+	return
+}
+
+// flush writes a buffered member to the underlying sink, as much of it as
+// the byte budget allows, and queues it. Members the unpacker didn't
+// close are dropped, the way their files are in the underlying sink.
+func (w *memberWriter) flush() (err error) {
+	if !w.closed {
+		return
+	}
+	j := w.sink.j
+	data := w.buf.Bytes()
+	w.buf = nil
+	granted, total := j.reserve(int64(len(data)))
+	if granted < int64(len(data)) || w.refused > 0 {
+		w.truncate(total + w.refused)
+	}
+	data = data[:granted]
+	w.out, err = w.sink.Sink.Create(w.name)
+	if err != nil {
+		return
+	}
+	_, err = w.out.Write(data)
+	w.hash.Write(data)
+	if err != nil {
+		w.out.Close()
+		return
+	}
+	err = w.out.Close()
+	if err != nil {
+		return
+	}
+	w.finish()
+	return
+}
+
+// finish records the member's result, and queues it for unpacking.
+func (w *memberWriter) finish() {
+	j := w.sink.j
+	parent := j.results.Get(w.sink.parent)
+	r := w.result
+	r.Hashes = w.hash.Sum()
+	r.Parent = w.sink.parent
+	r.Depth = parent.Depth + 1
+	r.Unpacker = w.sink.unpacker
+	r.MemberName = w.member
+	r.SafeName = w.safe
+	j.results.Set(w.name, r)
+	parent.Children = append(parent.Children, w.name)
+
+	// Don't bother unpacking a member we didn't write in full.
+	if w.truncated {
+		j.limitExceeded(w.name, models.LimitExceeded{Limit: models.LimitTotalBytes, Max: float64(j.opts.MaxTotalBytes)})
+		return
+	}
+	j.push(w.name)
+}
+
+// reserve takes up to n bytes out of the run's budget. It returns the
+// number of bytes granted, and the total the run would have come to with
+// all n.
+func (j *job) reserve(n int64) (granted, total int64) {
+	max := j.opts.MaxTotalBytes
+	if max <= 0 {
+		granted = n
+		return
+	}
+	j.totalBytesMu.Lock()
+	defer j.totalBytesMu.Unlock()
+	total = j.totalBytes + n
+	granted = n
+	if total > max {
+		granted = max64(max-j.totalBytes, 0)
+	}
+	j.totalBytes += granted
+	return
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
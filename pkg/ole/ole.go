@@ -0,0 +1,107 @@
+// Package ole recursively unpacks Office documents, including any embedded
+// OLE objects, and reports what it found along the way.
+//
+// This is the embeddable counterpart to the ole command:
+//
+//	u := ole.New(ole.Options{OutputDir: "/tmp/out"})
+//	results, err := u.Unpack(ctx, "/tmp/invoice.docx")
+package ole
+
+import (
+	"log/slog"
+
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// Options control the behavior of an Unpacker.
+type Options struct {
+	// Logger receives diagnostic output. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// OutputDir is the directory extracted files are written to. It
+	// must already exist. Ignored if Sink is set.
+	OutputDir string
+
+	// Sink receives the input file and everything extracted from it. If
+	// nil, a sinks.Dir rooted at OutputDir is used. The Unpacker does not
+	// close the sink.
+	Sink sinks.Sink
+
+	// MaxDepth is the maximum nesting depth we will unpack. The input
+	// file is at depth 0, its members are at depth 1, and so on. Files
+	// at MaxDepth are still unpacked, so with a MaxDepth of 1 we extract
+	// the input's members, and stop there. Files beyond the limit that
+	// we would otherwise unpack are reported, but not unpacked. Files
+	// we don't unpack anyway are reported as usual. Zero means no limit.
+	MaxDepth int
+
+	// MaxFileSize is the size, in bytes, of the largest file we will
+	// attempt to unpack. Larger files are reported, but not unpacked.
+	// Zero means no limit.
+	MaxFileSize int64
+
+	// MaxMembers is the maximum number of members we will extract from
+	// a single container. Zero means no limit.
+	MaxMembers int
+
+	// MaxTotalBytes is the maximum number of bytes we will write to the
+	// sink over the whole run, not counting the input file. Zero means
+	// no limit. Members are charged in the order they are written to
+	// the sink (see Workers), so the same members are cut short on
+	// every run.
+	MaxTotalBytes int64
+
+	// MaxCompressionRatio is the highest uncompressed to compressed size
+	// ratio we accept for a member of a compressed archive, such as a zip
+	// member. Members over the limit are skipped. Zero means no limit.
+	MaxCompressionRatio float64
+
+	// Workers is the number of files unpacked in parallel. Members of
+	// a single container are always extracted by one worker, so output
+	// names don't depend on scheduling. With more than one worker, each
+	// container's members are held in memory until every container
+	// queued ahead of it has been written, so members reach the sink in
+	// the same order on every run. Values below 1 mean 1.
+	Workers int
+
+	// Unpackers lists the names of the registrations (see
+	// unpackers.Register) which are allowed to run. Files matched by
+	// any other registration are reported as supported, but are not
+	// unpacked. If empty, all registrations are enabled.
+	Unpackers []string
+}
+
+// An Unpacker recursively extracts members from Office documents.
+// It is safe to reuse an Unpacker for several files.
+type Unpacker struct {
+	opts   Options
+	logger *slog.Logger
+}
+
+// Create a new Unpacker.
+//
+//	Args:
+//		opts (Options):	Configuration for the new Unpacker.
+//
+//	Returns:
+//		u (*Unpacker):	The configured Unpacker.
+func New(opts Options) (u *Unpacker) {
+	u = &Unpacker{opts: opts, logger: opts.Logger}
+	if u.logger == nil {
+		u.logger = slog.Default()
+	}
+	return
+}
+
+// enabled reports whether the named registration is allowed to run.
+func (u *Unpacker) enabled(name string) bool {
+	if len(u.opts.Unpackers) == 0 {
+		return true
+	}
+	for _, n := range u.opts.Unpackers {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
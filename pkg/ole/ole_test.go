@@ -0,0 +1,1019 @@
+package ole
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+	"github.com/ashdwilson/ole/internal/vbatest"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+	"github.com/ashdwilson/ole/pkg/unpackers"
+)
+
+var pathToSampleDataDir = "../../test/data/"
+
+// Build a minimal .docx which carries sample1.ole as an Ole10Native stream.
+func writeSampleDocx(t *testing.T, dir string) (docxPath string) {
+	t.Helper()
+	ole10, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docxPath = writeDocx(t, dir, "sample.docx", []zipMember{{"word/Ole10Native", ole10}})
+	return
+}
+
+type zipMember struct {
+	name string
+	body []byte
+}
+
+// Build a minimal .docx holding the extra members.
+func writeDocx(t *testing.T, dir, name string, extra []zipMember) (docxPath string) {
+	t.Helper()
+	members := []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`)},
+		{"word/document.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"/>`)},
+	}
+	return writeZip(t, dir, name, append(members, extra...))
+}
+
+// Write a zip archive of the members, in order.
+func writeZip(t *testing.T, dir, name string, members []zipMember) (zipPath string) {
+	t.Helper()
+	zipPath = path.Join(dir, name)
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(m.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// Unpack a docx all the way down to the file inside the OLE 1.0 object.
+func TestUnpackE2E(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+	docxPath := writeSampleDocx(t, inDir)
+
+	u := New(Options{OutputDir: outDir})
+	results, err := u.Unpack(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgPath := "sample.docx-members/word/Ole10Native-members/Untitled.msg"
+	r, ok := results.ParsedFiles[msgPath]
+	if !ok {
+		t.Fatalf("expected a result for %s", msgPath)
+	}
+	if r.FileType != "application/vnd.ms-outlook" {
+		t.Errorf("file type mismatch - expected application/vnd.ms-outlook got %s", r.FileType)
+	}
+	if _, err = os.Stat(path.Join(outDir, msgPath)); err != nil {
+		t.Errorf("extracted file missing: %s", err)
+	}
+	expectedSHA256 := "00c69e9c47e86bc70102f3e665f6bc9a62d0af180086d96f56d092c57f127774"
+	if r.Hashes.SHA256 != expectedSHA256 {
+		t.Errorf("hash mismatch - expected %s got %s", expectedSHA256, r.Hashes.SHA256)
+	}
+	if results.ParsedFiles["sample.docx"].Hashes.MD5 == "" {
+		t.Errorf("input file was not hashed")
+	}
+}
+
+// MaxDepth stops the recursion without failing the run. Files at
+// MaxDepth are unpacked, files beyond it aren't.
+func TestUnpackMaxDepth(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+	inner, err := os.ReadFile(writeSampleDocx(t, inDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docxPath := writeDocx(t, inDir, "outer.docx", []zipMember{{"word/embeddings/sample.docx", inner}})
+
+	u := New(Options{OutputDir: outDir, MaxDepth: 1})
+	results, err := u.Unpack(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := results.ParsedFiles["outer.docx-members/word/embeddings/sample.docx"]
+	if embedded == nil || embedded.Depth != 1 {
+		t.Fatalf("expected a result for the embedded document at depth 1, got %+v", embedded)
+	}
+	if !embedded.Expanded || len(embedded.LimitsExceeded) != 0 {
+		t.Errorf("expected the embedded document, at MaxDepth, to be unpacked: %+v", embedded.LimitsExceeded)
+	}
+	stream := results.ParsedFiles["outer.docx-members/word/embeddings/sample.docx-members/word/Ole10Native"]
+	if stream == nil {
+		t.Fatal("expected a result for the Ole10Native stream")
+	}
+	if stream.Expanded || len(stream.LimitsExceeded) != 1 || stream.LimitsExceeded[0].Limit != models.LimitDepth || stream.LimitsExceeded[0].Value != 2 {
+		t.Errorf("expected the Ole10Native stream, past MaxDepth, to be reported but not expanded: %+v", stream.LimitsExceeded)
+	}
+}
+
+// Each of the bomb limits stops its branch and is reported on the container.
+func TestUnpackLimits(t *testing.T) {
+	docxPath := writeSampleDocx(t, t.TempDir())
+	cases := []struct {
+		opts          Options
+		expectedLimit string
+		container     string
+	}{
+		{Options{MaxMembers: 2}, models.LimitMembers, "sample.docx"},
+		{Options{MaxTotalBytes: 1000}, models.LimitTotalBytes, "sample.docx"},
+		{Options{MaxCompressionRatio: 1.01}, models.LimitCompressionRatio, "sample.docx"},
+		{Options{MaxFileSize: 1000}, models.LimitFileSize, "sample.docx"},
+	}
+	for _, c := range cases {
+		_, results, err := New(c.opts).UnpackInMemory(context.Background(), docxPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := results.ParsedFiles[c.container]
+		if len(r.LimitsExceeded) == 0 || r.LimitsExceeded[0].Limit != c.expectedLimit {
+			t.Errorf("expected %s limit on %s, got %+v", c.expectedLimit, c.container, r.LimitsExceeded)
+		}
+		if r.Error != "" {
+			t.Errorf("hitting the %s limit should not be an error, got %s", c.expectedLimit, r.Error)
+		}
+		if _, ok := results.ParsedFiles["sample.docx-members/word/Ole10Native-members/Untitled.msg"]; ok {
+			t.Errorf("expected the %s limit to stop unpacking before the embedded object", c.expectedLimit)
+		}
+	}
+}
+
+// A cancelled context stops the run.
+func TestUnpackCancelled(t *testing.T) {
+	inDir := t.TempDir()
+	outDir := t.TempDir()
+	docxPath := writeSampleDocx(t, inDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := New(Options{OutputDir: outDir}).Unpack(ctx, docxPath)
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// The in-memory mode hands back the whole tree as an fs.FS.
+func TestUnpackInMemory(t *testing.T) {
+	docxPath := writeSampleDocx(t, t.TempDir())
+
+	fsys, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walked := 0
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		walked++
+		if _, ok := results.ParsedFiles[name]; !ok {
+			t.Errorf("no result for %s", name)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if walked != len(results.ParsedFiles) {
+		t.Errorf("file count mismatch - results have %d, fs.FS has %d", len(results.ParsedFiles), walked)
+	}
+	msg, err := fs.ReadFile(fsys, "sample.docx-members/word/Ole10Native-members/Untitled.msg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	control, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, control) {
+		t.Errorf("extracted object does not match sample1.msg")
+	}
+}
+
+// Input handed over as an io.ReaderAt is unpacked in place, without a copy in the sink.
+func TestUnpackReader(t *testing.T) {
+	docx, err := os.ReadFile(writeSampleDocx(t, t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := sinks.NewMemory()
+	results, err := New(Options{Sink: sink}).UnpackReader(context.Background(), "piped.docx", bytes.NewReader(docx), int64(len(docx)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.ParsedFiles["piped.docx"]; r == nil || !r.Expanded {
+		t.Errorf("expected the input to be expanded")
+	}
+	if _, ok := results.ParsedFiles["piped.docx-members/word/Ole10Native-members/Untitled.msg"]; !ok {
+		t.Errorf("expected a result for the embedded object")
+	}
+	if _, err = fs.Stat(sink, "piped.docx"); err == nil {
+		t.Errorf("the input should not be copied into the sink")
+	}
+}
+
+// Every result knows where it came from, and the tree can be walked from the input.
+func TestUnpackTree(t *testing.T) {
+	docxPath := writeSampleDocx(t, t.TempDir())
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgPath := "sample.docx-members/word/Ole10Native-members/Untitled.msg"
+	expectedLineage := []string{"sample.docx", "sample.docx-members/word/Ole10Native", msgPath}
+	lineage := results.Lineage(msgPath)
+	if strings.Join(lineage, " → ") != strings.Join(expectedLineage, " → ") {
+		t.Errorf("lineage mismatch - expected %v got %v", expectedLineage, lineage)
+	}
+	msg := results.ParsedFiles[msgPath]
+	if msg.Depth != 2 || msg.Unpacker != "ole10native" || msg.MemberName != "Untitled.msg" {
+		t.Errorf("unexpected provenance for %s: depth %d, unpacker %s, member name %s", msgPath, msg.Depth, msg.Unpacker, msg.MemberName)
+	}
+
+	roots := results.Tree()
+	if len(roots) != 1 || roots[0].Path != "sample.docx" {
+		t.Fatalf("expected sample.docx to be the only root, got %d roots", len(roots))
+	}
+	if len(roots[0].Nodes) != len(roots[0].Children) || len(roots[0].Nodes) != 3 {
+		t.Errorf("expected 3 members under the root, got %d", len(roots[0].Nodes))
+	}
+}
+
+// A worker pool produces the same results as a single worker.
+func TestUnpackWorkers(t *testing.T) {
+	docxPath := writeSampleDocx(t, t.TempDir())
+	var expected []byte
+	for _, workers := range []int{1, 4, 4} {
+		_, results, err := New(Options{Workers: workers}).UnpackInMemory(context.Background(), docxPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := json.Marshal(results)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected == nil {
+			expected = encoded
+			continue
+		}
+		if !bytes.Equal(encoded, expected) {
+			t.Errorf("results with %d workers differ from a single worker", workers)
+		}
+	}
+}
+
+// With several workers, tar entries come out in the same order on every
+// run, and the total bytes limit cuts the same members short.
+func TestUnpackWorkersOrder(t *testing.T) {
+	dir := t.TempDir()
+	var nested []zipMember
+	for i := 0; i < 8; i++ {
+		inner, err := os.ReadFile(writeDocx(t, dir, "inner.docx", []zipMember{
+			{"word/media/a.bin", bytes.Repeat([]byte{byte(i)}, 300)},
+			{"word/media/b.bin", bytes.Repeat([]byte{byte(i)}, 300)},
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		nested = append(nested, zipMember{fmt.Sprintf("word/embeddings/doc%d.docx", i), inner})
+	}
+	docxPath := writeDocx(t, dir, "outer.docx", nested)
+
+	// A single worker's unpackers stop at the first member cut short by
+	// the limit, with several they only find out later, so runs are only
+	// compared against one worker without a limit.
+	cases := []struct {
+		maxTotalBytes int64
+		workers       []int
+	}{
+		{0, []int{1, 4, 4, 4}},
+		{10000, []int{4, 4, 4, 4}},
+	}
+	for _, c := range cases {
+		maxTotalBytes := c.maxTotalBytes
+		var expectedEntries []string
+		var expectedResults []byte
+		for _, workers := range c.workers {
+			var out bytes.Buffer
+			sink := sinks.NewTar(&out)
+			results, err := New(Options{Sink: sink, Workers: workers, MaxTotalBytes: maxTotalBytes}).Unpack(context.Background(), docxPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = sink.Close(); err != nil {
+				t.Fatal(err)
+			}
+			var entries []string
+			tr := tar.NewReader(&out)
+			for h, err := tr.Next(); err == nil; h, err = tr.Next() {
+				entries = append(entries, h.Name)
+			}
+			encoded, err := json.Marshal(results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limited := bytes.Contains(encoded, []byte(models.LimitTotalBytes)); limited != (maxTotalBytes > 0) {
+				t.Errorf("expected the total bytes limit to be hit only when set, limit %d", maxTotalBytes)
+			}
+			if expectedEntries == nil {
+				expectedEntries, expectedResults = entries, encoded
+				continue
+			}
+			if strings.Join(entries, "\n") != strings.Join(expectedEntries, "\n") {
+				t.Errorf("tar entries with %d workers and a limit of %d differ from the first run", workers, maxTotalBytes)
+			}
+			if !bytes.Equal(encoded, expectedResults) {
+				t.Errorf("results with %d workers and a limit of %d differ from the first run", workers, maxTotalBytes)
+			}
+		}
+	}
+}
+
+// Members shorter than the header we read are reported as unsupported,
+// not as a read error.
+func TestUnpackShortMember(t *testing.T) {
+	docxPath := writeDocx(t, t.TempDir(), "short.docx", []zipMember{{"word/short.bin", []byte{0x00, 0x01}}})
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["short.docx-members/word/short.bin"]
+	if r == nil || !strings.HasPrefix(r.Error, "unsupported") {
+		t.Errorf("expected the member to be unsupported, got %+v", r)
+	}
+}
+
+// Crafted member names can't escape the members directory.
+func TestUnpackZipSlip(t *testing.T) {
+	docxPath := writeDocx(t, t.TempDir(), "slip.docx", []zipMember{{"../../evil.txt", []byte("gotcha")}})
+	outDir := t.TempDir()
+	results, err := New(Options{OutputDir: outDir}).Unpack(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.Get("slip.docx-members/__/__/evil.txt")
+	if r == nil {
+		t.Fatal("expected the member to be extracted under a safe name")
+	}
+	if r.MemberName != "../../evil.txt" || r.SafeName != "__/__/evil.txt" {
+		t.Errorf("unexpected names - member name %q, safe name %q", r.MemberName, r.SafeName)
+	}
+	if _, err = os.Stat(path.Join(outDir, "slip.docx-members/__/__/evil.txt")); err != nil {
+		t.Errorf("extracted file missing: %s", err)
+	}
+}
+
+// Members with the same name are all kept.
+func TestUnpackCollisions(t *testing.T) {
+	docxPath := writeDocx(t, t.TempDir(), "dupes.docx", []zipMember{
+		{"word/embeddings/invoice.pdf", []byte("first")},
+		{"word/embeddings/invoice.pdf", []byte("second")},
+		{"word/embeddings/INVOICE.pdf", []byte("third")},
+	})
+	fsys, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"dupes.docx-members/word/embeddings/invoice.pdf":   "first",
+		"dupes.docx-members/word/embeddings/invoice~2.pdf": "second",
+		"dupes.docx-members/word/embeddings/INVOICE~3.pdf": "third",
+	}
+	for name, body := range expected {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			t.Errorf("reading %s: %s", name, err)
+			continue
+		}
+		if string(data) != body {
+			t.Errorf("content mismatch for %s - expected %q got %q", name, body, data)
+		}
+		if r := results.Get(name); r == nil || !strings.EqualFold(r.MemberName, "word/embeddings/invoice.pdf") {
+			t.Errorf("expected %s to record its original member name", name)
+		}
+	}
+}
+
+// A member renamed to keep clear of another member's output is still
+// dispatched by its name in the container.
+func TestUnpackRenamedMember(t *testing.T) {
+	ole10, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docxPath := writeDocx(t, t.TempDir(), "renamed.docx", []zipMember{
+		{"word/Ole10Native-members", []byte("junk")},
+		{"word/Ole10Native", ole10},
+	})
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.Get("renamed.docx-members/word/Ole10Native~2")
+	if r == nil || r.MemberName != "word/Ole10Native" {
+		t.Fatalf("expected the Ole10Native stream to be renamed, got %+v", r)
+	}
+	if r.Ole10 == nil || !r.Supported || r.Error != "" {
+		t.Errorf("expected the renamed Ole10Native stream to be unpacked, got %+v", r)
+	}
+}
+
+// Members are opened by the name they were given, not the one they were
+// created with.
+func TestMemberSinkOpen(t *testing.T) {
+	j := New(Options{}).newJob(sinks.NewMemory())
+	j.results.Set("dupes.zip", &models.Result{})
+	members := &memberSink{Sink: j.sink, prefix: "dupes.zip-members", parent: "dupes.zip", namer: unpackers.NewNamer(), files: map[string]bool{}, j: j}
+	for _, body := range []string{"first", "second"} {
+		w, err := members.Create("invoice.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, body := range map[string]string{"invoice.pdf": "first", "invoice~2.pdf": "second"} {
+		data, err := fs.ReadFile(members, name)
+		if err != nil {
+			t.Errorf("opening %s: %s", name, err)
+			continue
+		}
+		if string(data) != body {
+			t.Errorf("content mismatch for %s - expected %q got %q", name, body, data)
+		}
+	}
+	if _, err := members.Open("invoice~3.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a name which wasn't handed out not to exist, got %v", err)
+	}
+}
+
+// The data after an OLE 1.0 object is extracted, and the header and trailer
+// are described on the stream's result.
+func TestUnpackOle10Trailing(t *testing.T) {
+	docxPath := writeSampleDocx(t, t.TempDir())
+
+	fsys, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := results.ParsedFiles["sample.docx-members/word/Ole10Native"]
+	if stream == nil || stream.Ole10 == nil {
+		t.Fatal("expected OLE 1.0 metadata on the Ole10Native stream")
+	}
+	if stream.Ole10.Label != "Untitled.msg" || stream.Ole10.UnicodeLabel != "Untitled.msg" {
+		t.Errorf("label mismatch - expected Untitled.msg got %q and %q", stream.Ole10.Label, stream.Ole10.UnicodeLabel)
+	}
+	if stream.Ole10.ObjectType != 3 || stream.Ole10.SourcePath == "" || stream.Ole10.SourcePath != stream.Ole10.UnicodeSourcePath {
+		t.Errorf("header not reported: %+v", stream.Ole10)
+	}
+	if stream.Ole10.TrailingSize != 474 || stream.Ole10.UnknownTrailingSize != 0 {
+		t.Errorf("expected 474 bytes of trailing data, all parsed - got %d, %d unknown", stream.Ole10.TrailingSize, stream.Ole10.UnknownTrailingSize)
+	}
+	trailerPath := "sample.docx-members/word/Ole10Native-members/ole10-trailing.bin"
+	trailer, err := fs.ReadFile(fsys, trailerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trailer) != 474 {
+		t.Errorf("trailer size mismatch - expected 474 got %d", len(trailer))
+	}
+	if r := results.ParsedFiles[trailerPath]; r == nil || r.Error != "" {
+		t.Errorf("expected the trailer to be reported without an error")
+	}
+}
+
+// Build an Ole10Native stream holding a Packager object, for a file
+// named label.
+func packagerStream(label string, objectType uint16, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint16(2))
+	buf.WriteString(label + "\x00")
+	buf.WriteString(`C:\` + label + "\x00")
+	binary.Write(buf, binary.LittleEndian, []uint16{0, objectType})
+	if objectType == parsers.Ole10Embedded {
+		tempPath := `C:\Temp\` + label + "\x00"
+		binary.Write(buf, binary.LittleEndian, uint32(len(tempPath)))
+		buf.WriteString(tempPath)
+		binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	return append(binary.LittleEndian.AppendUint32(nil, uint32(buf.Len())), buf.Bytes()...)
+}
+
+// The header and trailer are reported even when the total bytes limit
+// cuts the object short.
+func TestUnpackOle10Truncated(t *testing.T) {
+	ole10, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docxPath := writeSampleDocx(t, t.TempDir())
+
+	// Room for the docx's members, but not the object inside the stream.
+	_, results, err := New(Options{MaxTotalBytes: int64(len(ole10)) + 1000}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := results.ParsedFiles["sample.docx-members/word/Ole10Native"]
+	if stream == nil || stream.Ole10 == nil {
+		t.Fatal("expected OLE 1.0 metadata on the Ole10Native stream")
+	}
+	if !stream.Expanded || stream.Ole10.UnicodeLabel != "Untitled.msg" || stream.Ole10.TrailingSize != 474 {
+		t.Errorf("expected the stream to be expanded and its trailer reported, got %+v and %+v", stream, stream.Ole10)
+	}
+	if r := results.ParsedFiles["sample.docx-members/word/Ole10Native-members/Untitled.msg"]; r == nil || len(r.LimitsExceeded) == 0 {
+		t.Errorf("expected the object to be cut short, got %+v", r)
+	}
+}
+
+// A linked Packager object is reported, with the path it links to.
+func TestUnpackOle10Linked(t *testing.T) {
+	stream := packagerStream("report.pdf", parsers.Ole10Linked, nil)
+	docxPath := writeDocx(t, t.TempDir(), "linked.docx", []zipMember{{"word/Ole10Native", stream}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["linked.docx-members/word/Ole10Native"]
+	if r == nil || r.Ole10 == nil || r.Error != "" {
+		t.Fatalf("expected a linked object to be parsed without error, got %+v", r)
+	}
+	if r.Ole10.ObjectType != parsers.Ole10Linked || r.Ole10.SourcePath != `C:\report.pdf` || r.Ole10.Size != 0 || r.Expanded {
+		t.Errorf("linked object mismatch: %+v", r.Ole10)
+	}
+	if len(r.Children) != 0 {
+		t.Errorf("expected nothing to be extracted, got %v", r.Children)
+	}
+}
+
+// An object named like the trailing data is renamed, so it's still
+// unpacked.
+func TestUnpackOle10TrailingName(t *testing.T) {
+	stream := packagerStream(unpackers.TrailingDataName, parsers.Ole10Embedded, []byte("BM not really a bitmap"))
+	docxPath := writeDocx(t, t.TempDir(), "trailing.docx", []zipMember{{"word/Ole10Native", stream}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.ParsedFiles["trailing.docx-members/word/Ole10Native"]; r == nil || r.Error != "" {
+		t.Fatalf("expected the stream to be unpacked without error, got %+v", r)
+	}
+	if _, ok := results.ParsedFiles["trailing.docx-members/word/Ole10Native-members/"+unpackers.TrailingDataName]; ok {
+		t.Errorf("expected the object not to take the name of the trailing data")
+	}
+	r := results.ParsedFiles["trailing.docx-members/word/Ole10Native-members/_"+unpackers.TrailingDataName]
+	if r == nil || r.Unpacker != "ole10native" {
+		t.Errorf("expected the object to be extracted under another name, got %+v", r)
+	}
+}
+
+// A serialized OLE 1.0 Package object is unpacked down to the file
+// inside, and its presentation is extracted alongside.
+func TestUnpackOle1Object(t *testing.T) {
+	ole10, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The native data of a Package object is an Ole10Native
+	// stream, less the size at the start.
+	native := ole10[4:]
+	obj := &bytes.Buffer{}
+	binary.Write(obj, binary.LittleEndian, []uint32{0x0501, 2, 8})
+	obj.WriteString("Package\x00")
+	binary.Write(obj, binary.LittleEndian, []uint32{0, 0, uint32(len(native))})
+	obj.Write(native)
+	binary.Write(obj, binary.LittleEndian, []uint32{0x0501, 5, 13})
+	obj.WriteString("METAFILEPICT\x00")
+	binary.Write(obj, binary.LittleEndian, []uint32{320, 0xffffff10, 12})
+	obj.WriteString("8 bytes!WMF?")
+
+	objPath := path.Join(t.TempDir(), "object.bin")
+	if err = os.WriteFile(objPath, obj.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fsys, results, err := New(Options{}).UnpackInMemory(context.Background(), objPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["object.bin"]
+	if r == nil || r.Ole1Object == nil || r.Ole1Object.ClassName != "Package" {
+		t.Fatalf("expected OLE 1.0 object metadata, got %+v", r)
+	}
+	if p := r.Ole1Object.Presentation; p == nil || p.ClassName != "METAFILEPICT" || p.Height != -240 {
+		t.Errorf("presentation mismatch: %+v", p)
+	}
+	stream, err := fs.ReadFile(fsys, "object.bin-members/Ole10Native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stream, ole10) {
+		t.Errorf("the native data should be extracted as the original Ole10Native stream")
+	}
+	if _, ok := results.ParsedFiles["object.bin-members/Ole10Native-members/Untitled.msg"]; !ok {
+		t.Errorf("expected the Package object to be unpacked")
+	}
+	wmf, err := fs.ReadFile(fsys, "object.bin-members/presentation.wmf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wmf) != "WMF?" {
+		t.Errorf("expected the METAFILEPICT header to be stripped, got %q", wmf)
+	}
+}
+
+// The objects in an RTF document are unpacked as OLE 1.0 objects, down
+// to the Word picture each carries as native data.
+func TestUnpackRTF(t *testing.T) {
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), path.Join(pathToSampleDataDir, "rtf.rtf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.ParsedFiles["rtf.rtf"]; r == nil || !r.Expanded {
+		t.Fatalf("expected the document to be expanded, got %+v", r)
+	}
+	for _, obj := range []string{"rtf.rtf-members/objdata.bin", "rtf.rtf-members/objdata~2.bin"} {
+		r := results.ParsedFiles[obj]
+		if r == nil || r.Ole1Object == nil || r.Ole1Object.ClassName != "Word.Picture.8" {
+			t.Errorf("%s: expected OLE 1.0 object metadata, got %+v", obj, r)
+			continue
+		}
+		if p := r.Ole1Object.Presentation; p == nil || p.ClassName != "METAFILEPICT" {
+			t.Errorf("%s: presentation mismatch: %+v", obj, p)
+		}
+		if r := results.ParsedFiles[obj+"-members/presentation.wmf"]; r == nil || !r.Supported {
+			t.Errorf("%s: expected the presentation to be extracted, got %+v", obj, r)
+		}
+		if r := results.ParsedFiles[obj+"-members/native.bin"]; r == nil || !r.Expanded || r.Storage == nil || r.Storage.CompObj == nil {
+			t.Errorf("%s: expected the native data to be unpacked as an MS-CFB file, got %+v", obj, r)
+		}
+	}
+}
+
+// The picture in an OlePres stream is extracted, and only the members the
+// OLE 1.0 and OlePres unpackers name are labelled as native or presentation
+// data.
+func TestUnpackOlePres(t *testing.T) {
+	pres := &bytes.Buffer{}
+	binary.Write(pres, binary.LittleEndian, []uint32{0xFFFFFFFF, parsers.ClipboardFormatDIB, 4})
+	binary.Write(pres, binary.LittleEndian, []uint32{1, 0xFFFFFFFF, 2, 0, 2540, 1270, 8})
+	pres.WriteString("DIB data")
+	cfb := cfbtest.New()
+	cfb.Stream("ObjectPool/_1/\x02OlePres000", pres.Bytes())
+	docxPath := writeDocx(t, t.TempDir(), "pres.docx", []zipMember{
+		{"word/embeddings/oleObject1.bin", cfb.Bytes()},
+		{"word/native.bin", []byte{0x00, 0x01, 0x02, 0x03}},
+	})
+
+	fsys, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamPath := "pres.docx-members/word/embeddings/oleObject1.bin-members/ObjectPool/_1/OlePres000"
+	r := results.ParsedFiles[streamPath]
+	if r == nil || r.OlePresentation == nil || r.OlePresentation.ClipboardFormat != parsers.ClipboardFormatDIB || r.OlePresentation.Height != 1270 {
+		t.Fatalf("expected presentation metadata on the OlePres stream, got %+v", r)
+	}
+	dib, err := fs.ReadFile(fsys, streamPath+"-members/presentation.dib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dib) != "DIB data" {
+		t.Errorf("data mismatch - expected %q got %q", "DIB data", dib)
+	}
+	if r := results.ParsedFiles[streamPath+"-members/presentation.dib"]; r == nil || !r.Supported {
+		t.Errorf("expected the presentation data to be recognized, got %+v", r)
+	}
+	if r := results.ParsedFiles["pres.docx-members/word/native.bin"]; r == nil || r.Supported {
+		t.Errorf("expected a zip member named native.bin not to be taken for native data, got %+v", r)
+	}
+}
+
+// Ole10Native streams which don't hold a Packager object have their native
+// data extracted, and are labelled with its kind.
+func TestUnpackOle10Native(t *testing.T) {
+	bmp := []byte("BM\x1e\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x0c\x00\x00\x00\x01\x00\x01\x00\x01\x00\x18\x00\x00\x00\xff\x00")
+	stream := binary.LittleEndian.AppendUint32(nil, uint32(len(bmp)))
+	stream = append(stream, bmp...)
+	docxPath := writeDocx(t, t.TempDir(), "paint.docx", []zipMember{{"word/Ole10Native", stream}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["paint.docx-members/word/Ole10Native"]
+	if r == nil || r.Ole10 == nil || r.Ole10.Kind != "PBrush" || r.Error != "" {
+		t.Fatalf("expected a Paintbrush object, got %+v", r)
+	}
+	native := results.ParsedFiles["paint.docx-members/word/Ole10Native-members/native.bmp"]
+	if native == nil || native.FileType != "image/bmp" {
+		t.Errorf("expected the native data to be extracted as a bitmap, got %+v", native)
+	}
+}
+
+// MS Graph charts have their BIFF native data extracted under its own name.
+func TestUnpackOle10Graph(t *testing.T) {
+	// A BIFF5 BOF record for a chart, then EOF.
+	biff := []byte{0x09, 0x08, 0x08, 0x00, 0x00, 0x05, 0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00}
+	stream := binary.LittleEndian.AppendUint32(nil, uint32(len(biff)))
+	stream = append(stream, biff...)
+	docxPath := writeDocx(t, t.TempDir(), "graph.docx", []zipMember{{"word/Ole10Native", stream}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["graph.docx-members/word/Ole10Native"]
+	if r == nil || r.Ole10 == nil || r.Ole10.Kind != parsers.Ole10KindGraph || r.Error != "" {
+		t.Fatalf("expected an MS Graph object, got %+v", r)
+	}
+	native := results.ParsedFiles["graph.docx-members/word/Ole10Native-members/native.biff"]
+	if native == nil || !native.Supported || native.Error != "" {
+		t.Errorf("expected the native data to be extracted as native.biff, got %+v", native)
+	}
+}
+
+// The storage metadata of an MS-CFB member ends up on the member's
+// result, and the objects in it get unpacked.
+func TestUnpackObjectStorage(t *testing.T) {
+	ole10, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packageCLSID := "{0003000C-0000-0000-C000-000000000046}"
+	cfb := cfbtest.New()
+	cfb.SetCLSID("", cfbtest.CLSID(packageCLSID))
+	cfb.Stream("\x01Ole10Native", ole10)
+	docxPath := writeDocx(t, t.TempDir(), "embed.docx", []zipMember{{"word/embeddings/oleObject1.bin", cfb.Bytes()}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["embed.docx-members/word/embeddings/oleObject1.bin"]
+	if r == nil || r.Storage == nil || r.Storage.CLSID != packageCLSID {
+		t.Fatalf("expected storage metadata on the MS-CFB file, got %+v", r)
+	}
+	if _, ok := results.ParsedFiles["embed.docx-members/word/embeddings/oleObject1.bin-members/Ole10Native-members/Untitled.msg"]; !ok {
+		t.Errorf("expected the object to be unpacked")
+	}
+}
+
+// Embedded Word documents are MS-CFB files too, and get unpacked.
+func TestUnpackEmbeddedDoc(t *testing.T) {
+	wordCLSID := "{00020906-0000-0000-C000-000000000046}"
+	cfb := cfbtest.New()
+	cfb.SetCLSID("", cfbtest.CLSID(wordCLSID))
+	cfb.Stream("WordDocument", []byte("not much of a document"))
+	docxPath := writeDocx(t, t.TempDir(), "embed.docx", []zipMember{{"word/embeddings/Microsoft_Word_97_-_2003_Document.doc", cfb.Bytes()}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["embed.docx-members/word/embeddings/Microsoft_Word_97_-_2003_Document.doc"]
+	if r == nil || r.FileType != "application/msword" || !r.Expanded {
+		t.Fatalf("expected the document to be unpacked, got %+v", r)
+	}
+	if r.Storage == nil || r.Storage.CLSID != wordCLSID {
+		t.Errorf("root storage mismatch: %+v", r.Storage)
+	}
+}
+
+// The summary property set streams of a real document are recognized,
+// and parsed without error.
+func TestUnpackSummaryInformation(t *testing.T) {
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), path.Join(pathToSampleDataDir, "novpapplan.doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["novpapplan.doc"]
+	if r == nil || r.Error != "" || r.Storage == nil || r.Storage.Summary == nil {
+		t.Fatalf("expected summary metadata without error, got %+v", r)
+	}
+	for _, name := range []string{"SummaryInformation", "DocumentSummaryInformation"} {
+		member := results.ParsedFiles["novpapplan.doc-members/"+name]
+		if member == nil || !member.Supported || member.Error != "" {
+			t.Errorf("expected %s to be supported without error, got %+v", name, member)
+		}
+	}
+}
+
+var autoOpen = vbatest.Module{Name: "Module1", Source: "Attribute VB_Name = \"Module1\"\r\nSub AutoOpen()\r\n    Shell \"calc.exe\"\r\nEnd Sub\r\n"}
+
+// The streams of a VBA project, and the source decompressed out of it,
+// aren't reported as unsupported.
+func TestUnpackVBAProject(t *testing.T) {
+	cfb := cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{
+		{Name: "ThisDocument", Class: true, Source: "Attribute VB_Name = \"ThisDocument\"\r\n"},
+		autoOpen,
+	})
+	docxPath := writeDocx(t, t.TempDir(), "macro.docx", []zipMember{{"word/vbaProject.bin", cfb.Bytes()}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "macro.docx-members/word/vbaProject.bin"
+	r := results.ParsedFiles[key]
+	if r == nil || r.Error != "" {
+		t.Errorf("expected vbaProject.bin to be unpacked without error, got %+v", r)
+	}
+	for _, name := range []string{"VBA/Module1", "VBA/ThisDocument", "VBA/dir", "VBA/_VBA_PROJECT", "VBA/Module1.bas", "VBA/ThisDocument.cls", "PROJECT"} {
+		member := results.ParsedFiles[key+"-members/"+name]
+		if member == nil || !member.Supported || member.Error != "" {
+			t.Errorf("expected %s to be supported without error, got %+v", name, member)
+		}
+	}
+	if module := results.ParsedFiles[key+"-members/VBA/Module1"]; module.FileType != "application/x-vba-module" {
+		t.Errorf("expected the module stream to be typed, got %s", module.FileType)
+	}
+}
+
+// P-code listings are recognized, and a stomped module is flagged on
+// its project.
+func TestUnpackVBAStomping(t *testing.T) {
+	sub := binary.LittleEndian.AppendUint32([]byte{150, 0}, 0x10)
+	litStr := binary.LittleEndian.AppendUint16([]byte{182, 0}, 8)
+	stomped := autoOpen
+	stomped.PCode = [][]byte{sub, append(litStr, "cmd.exe "...), {111, 0}}
+	cfb := cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{stomped})
+	docxPath := writeDocx(t, t.TempDir(), "stomped.docx", []zipMember{{"word/vbaProject.bin", cfb.Bytes()}})
+
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), docxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "stomped.docx-members/word/vbaProject.bin"
+	r := results.ParsedFiles[key]
+	if r == nil || r.Error != "" || r.Storages["VBA"] == nil || r.Storages["VBA"].VBA == nil {
+		t.Fatalf("expected vbaProject.bin to be unpacked without error, got %+v", r)
+	}
+	if m := r.Storages["VBA"].VBA.Modules[0]; !m.Stomped {
+		t.Errorf("expected Module1 to be flagged as stomped, got %+v", m)
+	}
+	if member := results.ParsedFiles[key+"-members/VBA/Module1.pcode"]; member == nil || !member.Supported {
+		t.Errorf("expected the listing to be supported, got %+v", member)
+	}
+}
+
+// The Workbook stream of a real .xls file is recognized and parsed.
+func TestUnpackXLMWorkbook(t *testing.T) {
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), path.Join(pathToSampleDataDir, "test.xls"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["test.xls-members/Workbook"]
+	if r == nil || r.Workbook == nil {
+		t.Fatalf("expected a workbook on the Workbook stream, got %+v", r)
+	}
+	// Nothing is extracted from the stream, so it isn't expanded.
+	if !r.Supported || r.Expanded || r.Error != "" {
+		t.Errorf("expected the Workbook stream to be parsed without error, got %+v", r)
+	}
+}
+
+// The macro sheet of an .xlsm file is typed and recognized, and the
+// workbook's macros are listed on the file's result.
+func TestUnpackXLMOOXML(t *testing.T) {
+	xlsmPath := writeZip(t, t.TempDir(), "macro.xlsm", []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.ms-excel.sheet.macroEnabled.main+xml"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`)},
+		{"xl/workbook.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Macro1" sheetId="1" r:id="rId1"/></sheets></workbook>`)},
+		{"xl/_rels/workbook.xml.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet" Target="macrosheets/sheet1.xml"/></Relationships>`)},
+		{"xl/macrosheets/sheet1.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main"><sheetData><row r="1"><c r="A1"><f>EXEC("calc.exe")</f></c></row></sheetData></xm:macrosheet>`)},
+	})
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), xlsmPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.ParsedFiles["macro.xlsm"]
+	if r == nil || r.Workbook == nil || len(r.Workbook.Macros) != 1 || r.Error != "" {
+		t.Errorf("expected a workbook with one macro formula, got %+v", r)
+	}
+	sheet := results.ParsedFiles["macro.xlsm-members/xl/macrosheets/sheet1.xml"]
+	if sheet == nil || !sheet.Supported || sheet.FileType != unpackers.XLSXMacroSheetType {
+		t.Errorf("expected a macro sheet of type %s, got %+v", unpackers.XLSXMacroSheetType, sheet)
+	}
+}
+
+// Write an Office Open XML package whose main part has the content type.
+func writeOPC(t *testing.T, dir, name, mainPart, contentType string) (opcPath string) {
+	t.Helper()
+	return writeZip(t, dir, name, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/` + mainPart + `" ContentType="` + contentType + `"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="` + mainPart + `"/></Relationships>`)},
+		{mainPart, []byte(`<?xml version="1.0" encoding="UTF-8"?><main/>`)},
+	})
+}
+
+// Office documents are typed by the content type of their main part,
+// and unpacked.
+func TestUnpackOfficeVariants(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		path, kind, fileType string
+	}{
+		{path.Join(pathToSampleDataDir, "docx.docx"), "docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{path.Join(pathToSampleDataDir, "pptx.pptx"), "pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		{writeOPC(t, dir, "report.docm", "word/document.xml", "application/vnd.ms-word.document.macroEnabled.main+xml"),
+			"docm", "application/vnd.ms-word.document.macroEnabled.12"},
+		// mimetype takes themes for plain zip archives.
+		{writeOPC(t, dir, "brand.thmx", "theme/theme/theme1.xml", "application/vnd.openxmlformats-officedocument.theme+xml"),
+			"thmx", "application/vnd.ms-officetheme"},
+	}
+	for _, c := range cases {
+		_, results, err := New(Options{}).UnpackInMemory(context.Background(), c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := path.Base(c.path)
+		r := results.ParsedFiles[name]
+		if r.FileType != c.fileType || !r.Supported || !r.Expanded || r.Unpacker != "" || r.Error != "" {
+			t.Errorf("%s: expected %s to be unpacked, got %+v", name, c.fileType, r)
+		}
+		if r.Office == nil || r.Office.Kind != c.kind {
+			t.Errorf("%s: expected a %s document, got %+v", name, c.kind, r.Office)
+		}
+	}
+
+	// Zip archives which aren't packages are left alone.
+	zipPath := writeZip(t, dir, "plain.zip", []zipMember{{"notes.txt", []byte("hello")}})
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.ParsedFiles["plain.zip"]; r.FileType != "application/zip" || r.Supported || r.Office != nil {
+		t.Errorf("expected plain.zip to be left alone, got %+v", r)
+	}
+}
+
+// The parts of a package keep what OfficeZip says of them in their
+// results, and other OPC packages, like XPS documents, are unpacked too.
+func TestUnpackOPCParts(t *testing.T) {
+	_, results, err := New(Options{}).UnpackInMemory(context.Background(), path.Join(pathToSampleDataDir, "docx.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := results.ParsedFiles["docx.docx-members/word/document.xml"]
+	if doc == nil || doc.Part == nil || len(doc.Part.References) != 1 || doc.Part.ContentType != "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml" {
+		t.Errorf("expected the main part to be described, got %+v", doc)
+	}
+
+	// Other OPC packages, like XPS documents, are unpacked the same way.
+	xpsPath := writeZip(t, t.TempDir(), "doc.xps", []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="fdseq" ContentType="application/vnd.ms-package.xps-fixeddocumentsequence+xml"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="R0" Type="http://schemas.microsoft.com/xps/2005/06/fixedrepresentation" Target="/FixedDocSeq.fdseq"/></Relationships>`)},
+		{"FixedDocSeq.fdseq", []byte(`<FixedDocumentSequence xmlns="http://schemas.microsoft.com/xps/2005/06"/>`)},
+	})
+	_, results, err = New(Options{}).UnpackInMemory(context.Background(), xpsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := results.ParsedFiles["doc.xps"]; r.FileType != "application/x-opc-package" || !r.Expanded || r.Office != nil {
+		t.Errorf("expected doc.xps to be unpacked as an OPC package, got %+v", r)
+	}
+	seq := results.ParsedFiles["doc.xps-members/FixedDocSeq.fdseq"]
+	if seq == nil || seq.Part == nil || seq.Part.ContentType != "application/vnd.ms-package.xps-fixeddocumentsequence+xml" || len(seq.Part.References) != 1 || seq.Part.Orphaned {
+		t.Errorf("expected a referenced document sequence, got %+v", seq)
+	}
+}
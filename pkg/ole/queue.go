@@ -0,0 +1,58 @@
+package ole
+
+import "context"
+
+// push queues a file for unpacking.
+func (j *job) push(name string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.queue.PushBack(name)
+	j.cond.Signal()
+}
+
+// pop takes the next file off the queue, waiting for one to show up if
+// other workers are still busy. It returns false once the queue is drained
+// and no worker is left that could add to it, or once ctx is cancelled.
+// Files are handed a ticket in the order they are popped, which is the
+// order they were queued in. Every successful pop must be followed by a
+// call to done with the ticket.
+func (j *job) pop(ctx context.Context) (name string, ticket int, ok bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for j.queue.Len() == 0 && j.active > 0 && ctx.Err() == nil {
+		j.cond.Wait()
+	}
+	if j.queue.Len() == 0 || ctx.Err() != nil {
+		return
+	}
+	name, ok = j.queue.Remove(j.queue.Front()).(string), true
+	ticket = j.tickets
+	j.tickets++
+	j.active++
+	return
+}
+
+// done marks a popped file as finished. It waits for the files popped
+// ahead of it to be done, then has members write out what it buffered.
+// This way members land in the sink, and are queued, in the same order
+// whatever the number of workers.
+func (j *job) done(ticket int, members *memberSink) (err error) {
+	j.mu.Lock()
+	for j.turn != ticket {
+		j.turnCond.Wait()
+	}
+	j.mu.Unlock()
+
+	// Nobody else can have the turn until we pass it on.
+	if members != nil {
+		err = members.flush()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.turn++
+	j.turnCond.Broadcast()
+	j.active--
+	j.cond.Broadcast()
+	return
+}
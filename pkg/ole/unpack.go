@@ -0,0 +1,424 @@
+package ole
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
+	"github.com/ashdwilson/ole/pkg/unpackers"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// A job tracks the state of a single recursive unpacking run.
+type job struct {
+	opts    *Options
+	results *models.Results
+	sink    sinks.Sink
+
+	// Files waiting to be unpacked, and the number of files being
+	// unpacked right now. Both are guarded by mu; cond is signalled
+	// whenever either changes.
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  *list.List
+	active int
+
+	// Tickets handed out to popped files so far, and the ticket of the
+	// file whose members are written next. Both are guarded by mu;
+	// turnCond is signalled whenever turn changes.
+	tickets  int
+	turn     int
+	turnCond *sync.Cond
+
+	// With more than one worker, members are buffered until their
+	// container's turn comes, see done.
+	buffered bool
+
+	// Bytes written to the sink so far, not counting the input file.
+	totalBytes   int64
+	totalBytesMu sync.Mutex
+
+	// When the input is handed to us as an io.ReaderAt, it is read from
+	// directly instead of from the sink.
+	inputName string
+	input     io.ReaderAt
+	inputSize int64
+}
+
+// Unpack copies the input file into the sink, then recursively extracts
+// all members from it and from anything found inside.
+//
+// The returned results are populated even when err is non-nil, provided the
+// run got as far as the extraction loop. Errors for individual files are
+// recorded on their results, rather than returned. If ctx is cancelled, the
+// run stops before the next file and returns ctx.Err().
+//
+//	Args:
+//		ctx (context.Context):	Used for cancellation.
+//		infilePath (string):	Path to the input file.
+//
+//	Returns:
+//		results (*models.Results):	What we found, keyed by path in the sink.
+//		err (error)
+func (u *Unpacker) Unpack(ctx context.Context, infilePath string) (results *models.Results, err error) {
+	sink, err := u.sink()
+	if err != nil {
+		return
+	}
+	results, err = u.unpack(ctx, infilePath, sink)
+	return
+}
+
+// UnpackInMemory works like Unpack, but nothing is written to disk: the
+// input file is read into memory once, and the whole recursion runs on
+// in-memory buffers. OutputDir and Sink are ignored.
+//
+//	Args:
+//		ctx (context.Context):	Used for cancellation.
+//		infilePath (string):	Path to the input file.
+//
+//	Returns:
+//		fsys (fs.FS):		The extracted tree. Paths match the keys in results.
+//		results (*models.Results):	What we found.
+//		err (error)
+func (u *Unpacker) UnpackInMemory(ctx context.Context, infilePath string) (fsys fs.FS, results *models.Results, err error) {
+	sink := sinks.NewMemory()
+	results, err = u.unpack(ctx, infilePath, sink)
+	fsys = sink
+	return
+}
+
+// UnpackReader works like Unpack, but reads the input from r instead of a
+// file. The input is read in place, so it is not copied into the sink and
+// no temporary copy is made. Members are written to the configured sink;
+// set Options.Sink to a sinks.Memory to keep everything in memory.
+//
+//	Args:
+//		ctx (context.Context):	Used for cancellation.
+//		name (string):		Logical file name of the input. This is the key for the
+//					input in results, and members land under ${name}-members/.
+//		r (io.ReaderAt):	The input data.
+//		size (int64):		Size of the input data.
+//
+//	Returns:
+//		results (*models.Results):	What we found, keyed by path in the sink.
+//		err (error)
+func (u *Unpacker) UnpackReader(ctx context.Context, name string, r io.ReaderAt, size int64) (results *models.Results, err error) {
+	name = path.Base(name)
+	if name == "." || name == "/" || name == ".." {
+		err = fmt.Errorf("invalid input name: %q", name)
+		return
+	}
+	sink, err := u.sink()
+	if err != nil {
+		return
+	}
+	j := u.newJob(sink)
+	j.inputName, j.input, j.inputSize = name, r, size
+	h := newHasher()
+	_, err = io.Copy(h, io.NewSectionReader(r, 0, size))
+	if err != nil {
+		err = fmt.Errorf("%w: hashing input", err)
+		return
+	}
+	j.results.Set(name, &models.Result{Hashes: h.Sum()})
+	results, err = u.run(ctx, j, name)
+	return
+}
+
+// unpack copies the input file into the sink and unpacks it from there.
+func (u *Unpacker) unpack(ctx context.Context, infilePath string, sink sinks.Sink) (results *models.Results, err error) {
+	hashes, err := copyFileToSink(infilePath, sink)
+	if err != nil {
+		return
+	}
+	j := u.newJob(sink)
+	j.results.Set(path.Base(infilePath), &models.Result{Hashes: hashes})
+	results, err = u.run(ctx, j, path.Base(infilePath))
+	return
+}
+
+func (u *Unpacker) newJob(sink sinks.Sink) (j *job) {
+	j = &job{
+		opts:    &u.opts,
+		results: models.NewResults(),
+		queue:   list.New(),
+		sink:    sink,
+	}
+	j.cond = sync.NewCond(&j.mu)
+	j.turnCond = sync.NewCond(&j.mu)
+	j.buffered = u.opts.Workers > 1
+	return
+}
+
+// run drives the extraction queue for a single run, starting with the input file.
+// Files are handed out to Options.Workers workers in the order they were queued,
+// and their members are written and queued in that same order.
+func (u *Unpacker) run(ctx context.Context, j *job, inputName string) (results *models.Results, err error) {
+	results = j.results
+	// Add the input file to the queue
+	j.push(inputName)
+
+	workers := u.opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			u.work(ctx, j)
+		}()
+	}
+	wg.Wait()
+	err = ctx.Err()
+	return
+}
+
+// work unpacks files from the queue until it is drained, or ctx is cancelled.
+func (u *Unpacker) work(ctx context.Context, j *job) {
+	for nextPath, ticket, ok := j.pop(ctx); ok; nextPath, ticket, ok = j.pop(ctx) {
+		members, unpackErr := u.unpackFile(nextPath, j)
+		flushErr := j.done(ticket, members)
+		if err := errors.Join(unpackErr, flushErr); err != nil {
+			if r := j.results.Get(nextPath); r != nil {
+				r.Error = err.Error()
+			} else {
+				u.logger.Error("unpacking file", "file_name", nextPath, "error", err.Error())
+			}
+		}
+	}
+}
+
+// sink returns the configured sink, or a directory sink for OutputDir.
+func (u *Unpacker) sink() (sink sinks.Sink, err error) {
+	if u.opts.Sink != nil {
+		sink = u.opts.Sink
+		return
+	}
+
+	// Check that OutputDir exists and is a directory
+	outDirStat, err := os.Stat(u.opts.OutputDir)
+	if err != nil {
+		err = fmt.Errorf("%w: getting stat on output dir", err)
+		return
+	}
+	if !outDirStat.IsDir() {
+		err = fmt.Errorf("output path is not a dir")
+		return
+	}
+	sink = sinks.NewDir(u.opts.OutputDir)
+	return
+}
+
+// unpackFile unpacks all members from the file (if supported) and updates the results
+// struct. The members are returned, to be queued once it's the file's turn.
+func (u *Unpacker) unpackFile(fname string, j *job) (members *memberSink, err error) {
+	results := j.results
+	stream, fileSize, closeFile, err := j.open(fname)
+	if err != nil {
+		return
+	}
+	defer closeFile()
+	// The result was created, with hashes, when the file was written.
+	result := results.Get(fname)
+	if result == nil {
+		result = &models.Result{}
+		results.Set(fname, result)
+	}
+	// Unpackers may have told us the type already.
+	mTypeStr := result.FileType
+	if mTypeStr == "" {
+		mTypeStr = "application/octet-stream"
+		if mType := u.getTypeFromReader(fname, stream, fileSize); mType != nil {
+			mTypeStr = mType.String()
+			// Office Open XML variants are zip archives which mimetype
+			// mostly can't tell apart.
+			if isZip(mType) {
+				if officeType := unpackers.OfficeType(stream, fileSize); officeType != "" {
+					mTypeStr = officeType
+				}
+			}
+		}
+		result.FileType = mTypeStr
+	}
+
+	header := make([]byte, unpackers.HeaderSize)
+	n, err := stream.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: reading file header", err)
+		return
+	}
+	err = nil
+	fileName := path.Base(fname)
+	parentType := ""
+	if parent := results.Get(result.Parent); parent != nil {
+		parentType = parent.FileType
+	}
+	// Match members by the name they were given in their container,
+	// without any suffix added to tell them apart from another member.
+	matchName := fileName
+	if result.MemberName != "" {
+		matchName = path.Base(unpackers.SanitizeName(result.MemberName))
+	}
+	reg, ok := unpackers.Lookup(unpackers.Candidate{
+		MIMEType:    mTypeStr,
+		FileName:    matchName,
+		Header:      header[:n],
+		ParentType:  parentType,
+		ExtractedBy: result.Unpacker,
+	})
+
+	// If we find a file we don't recognize, we want it to surface
+	// as an error in the log output.
+	if !ok {
+		if mTypeStr == "application/octet-stream" {
+			result.Error = fmt.Sprintf("unsupported filename/extension pattern for extraction from application/octet-stream: %s/%s", fileName, filepath.Ext(fileName))
+			return
+		}
+		result.Error = fmt.Sprintf("unsupported file type for extraction: %s", mTypeStr)
+		return
+	}
+	result.Supported = true
+	if reg.Unpacker == nil {
+		return
+	}
+	if !u.enabled(reg.Name) {
+		u.logger.Info("skipping file, unpacker is disabled", "file_name", fname, "unpacker", reg.Name)
+		return
+	}
+
+	// Stop here if this file is out of bounds.
+	if depth := result.Depth; u.opts.MaxDepth > 0 && depth > u.opts.MaxDepth {
+		j.limitExceeded(fname, models.LimitExceeded{Limit: models.LimitDepth, Max: float64(u.opts.MaxDepth), Value: float64(depth)})
+		return
+	}
+	if u.opts.MaxFileSize > 0 && fileSize > u.opts.MaxFileSize {
+		j.limitExceeded(fname, models.LimitExceeded{Limit: models.LimitFileSize, Max: float64(u.opts.MaxFileSize), Value: float64(fileSize)})
+		return
+	}
+
+	members = &memberSink{
+		Sink:     j.sink,
+		prefix:   fmt.Sprintf("%s-members", fname),
+		parent:   fname,
+		unpacker: reg.Name,
+		namer:    unpackers.NewNamer(),
+		files:    map[string]bool{},
+		j:        j,
+	}
+	err = reg.Unpacker.UnpackStream(fname, stream, fileSize, results, members)
+	return
+}
+
+// limitExceeded records a limit that stopped fname from being fully unpacked.
+func (j *job) limitExceeded(fname string, limit models.LimitExceeded) {
+	r := j.results.Get(fname)
+	r.LimitsExceeded = append(r.LimitsExceeded, limit)
+}
+
+// open returns random access to a file in the sink, or to the input if
+// it was handed to us as an io.ReaderAt.
+func (j *job) open(fname string) (stream io.ReaderAt, size int64, closeFile func() error, err error) {
+	if j.input != nil && fname == j.inputName {
+		stream, size, closeFile = j.input, j.inputSize, func() error { return nil }
+		return
+	}
+	inFile, err := j.sink.Open(fname)
+	if err != nil {
+		err = fmt.Errorf("%w: opening input file", err)
+		return
+	}
+	closeFile = inFile.Close
+	fileInfo, err := inFile.Stat()
+	if err != nil {
+		inFile.Close()
+		err = fmt.Errorf("%w: unable to stat file", err)
+		return
+	}
+	size = fileInfo.Size()
+	stream, err = readerAt(inFile)
+	if err != nil {
+		inFile.Close()
+		err = fmt.Errorf("%w: reading input file", err)
+	}
+	return
+}
+
+// Use the reader to determine the type. If the MIME type determination errors, return nil.
+func (u *Unpacker) getTypeFromReader(fname string, rdr io.ReaderAt, size int64) (mType *mimetype.MIME) {
+	mType, err := mimetype.DetectReader(io.NewSectionReader(rdr, 0, size))
+	if err != nil {
+		u.logger.Error("getting mime type", "file_name", fname, "error", err.Error())
+		mType = nil
+	}
+	return
+}
+
+// isZip reports whether mimetype found a zip archive, or a format
+// based on one.
+func isZip(mType *mimetype.MIME) bool {
+	for ; mType != nil; mType = mType.Parent() {
+		if mType.Is("application/zip") {
+			return true
+		}
+	}
+	return false
+}
+
+// readerAt returns f as an io.ReaderAt, reading it into memory if the
+// sink's files don't support random access.
+func readerAt(f io.Reader) (ra io.ReaderAt, err error) {
+	if ra, ok := f.(io.ReaderAt); ok {
+		return ra, nil
+	}
+	data, err := io.ReadAll(f)
+	ra = bytes.NewReader(data)
+	return
+}
+
+// copyFileToSink copies the input file into the sink, hashing it on the way.
+func copyFileToSink(fname string, sink sinks.Sink) (hashes models.Hashes, err error) {
+	// Check and open the source file.
+	srcStat, err := os.Stat(fname)
+	if err != nil {
+		return
+	}
+	if !srcStat.Mode().IsRegular() {
+		err = fmt.Errorf("not a regular file: %s", fname)
+		return
+	}
+	srcHandle, err := os.Open(fname)
+	if err != nil {
+		return
+	}
+	defer srcHandle.Close()
+
+	// Create and open the destination file.
+	destHandle, err := sink.Create(path.Base(fname))
+	if err != nil {
+		return
+	}
+
+	// Do the copy.
+	h := newHasher()
+	_, err = io.Copy(io.MultiWriter(destHandle, h), srcHandle)
+	if err != nil {
+		destHandle.Close()
+		return
+	}
+	err = destHandle.Close()
+	hashes = h.Sum()
+	return
+}
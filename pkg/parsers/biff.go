@@ -0,0 +1,482 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	ErrBIFFVersion = errors.New("not a BIFF8 workbook stream")
+)
+
+// Sheet types, from BOUNDSHEET records.
+const (
+	SheetWorksheet = "Worksheet"
+	SheetMacro     = "Macro"
+	SheetChart     = "Chart"
+	SheetVBModule  = "VBModule"
+)
+
+// Sheet visibility, from BOUNDSHEET records. Very hidden sheets can only
+// be shown again through VBA.
+const (
+	SheetVisible    = "Visible"
+	SheetHidden     = "Hidden"
+	SheetVeryHidden = "VeryHidden"
+)
+
+// Record types in a BIFF8 Workbook stream ([MS-XLS] 2.3).
+const (
+	biffFormula     = 0x0006
+	biffEOF         = 0x000A
+	biffExternSheet = 0x0017
+	biffName        = 0x0018
+	biffExternName  = 0x0023
+	biffFilePass    = 0x002F
+	biffContinue    = 0x003C
+	biffBoundSheet  = 0x0085
+	biffSupBook     = 0x01AE
+	biffBOF         = 0x0809
+)
+
+// Names of built-in defined names, by the character stored in their
+// NAME records.
+var builtinNames = []string{
+	"Consolidate_Area", "Auto_Open", "Auto_Close", "Extract", "Database",
+	"Criteria", "Print_Area", "Print_Titles", "Recorder", "Data_Form",
+	"Auto_Activate", "Auto_Deactivate", "Sheet_Title", "_FilterDatabase",
+}
+
+// A BIFF8 Workbook stream, from an .xls file. The stream is a series
+// of records ([MS-XLS] 2.1.4, integers are little-endian):
+//
+//	uint16	Type
+//	uint16	Size
+//	[]byte	Data, Size bytes
+//
+// Records with more data than fits in one carry on in CONTINUE records
+// right after them, which are joined back on before the record is read.
+// Some records, like SST, start a string split across a CONTINUE over
+// with a byte of flags. That byte isn't taken out.
+//
+// It starts with the workbook globals: a BOF record, then records for
+// the whole workbook, including a BOUNDSHEET for each sheet, and NAME
+// records for defined names. Each sheet follows, from its own BOF record
+// to an EOF. Only what's needed to find macros is parsed: the sheets,
+// the defined names, and the formulas on macro sheets, which are where
+// Excel 4.0 (XLM) macros live.
+type Workbook struct {
+	Sheets []*Sheet
+	Names  []*DefinedName
+
+	// Set if the workbook is encrypted. Parsing stops at the FILEPASS
+	// record, since everything after it is encrypted.
+	Encrypted bool
+
+	// References to sheets and other workbooks, from the EXTERNSHEET
+	// and SUPBOOK records. Formulas use them to point at other sheets.
+	externSheets []externSheet
+	supBooks     []*supBook
+
+	// Set for .xlsb workbooks, whose formulas are BIFF12.
+	biff12 bool
+}
+
+// A sheet, from its BOUNDSHEET record.
+type Sheet struct {
+	Name string
+
+	// One of the Sheet constants, for the type and visibility.
+	Type       string
+	Visibility string
+
+	// Offset of the sheet's BOF record in the stream.
+	Offset uint32
+
+	// For OOXML workbooks, the ID of the relationship to the sheet's part.
+	RelID string
+
+	// Decoded formulas, for macro sheets.
+	Formulas []*CellFormula
+}
+
+// A formula on a sheet.
+type CellFormula struct {
+	Row uint32
+	Col uint16
+
+	// The formula, like `=EXEC("calc.exe")`.
+	Formula string
+}
+
+// Cell returns the formula's address in A1 notation.
+func (f *CellFormula) Cell() string {
+	return cellName(f.Row, f.Col, false, false)
+}
+
+// A defined name, from a NAME record.
+type DefinedName struct {
+	Name string
+
+	// The sheet the name is scoped to, or "" for the whole workbook.
+	Sheet string
+
+	// What the name refers to, like `=Macro1!$A$1`.
+	Formula string
+
+	Hidden  bool
+	Builtin bool
+
+	// Set for names of macros, including functions.
+	Macro bool
+}
+
+// AutoRun reports whether Excel runs the name's macro on its own, when
+// the workbook is opened or closed. Excel only looks at the start of the
+// name, so Auto_Open2 runs too.
+func (n *DefinedName) AutoRun() bool {
+	name := strings.ToLower(n.Name)
+	for _, prefix := range []string{"auto_open", "auto_close", "auto_activate", "auto_deactivate"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type externSheet struct {
+	supBook     uint16
+	first, last int16
+}
+
+type supBook struct {
+	// Set for the SUPBOOK referring to this workbook.
+	self bool
+
+	// Names from the EXTERNNAME records following the SUPBOOK.
+	names []string
+}
+
+// Parse a BIFF8 Workbook stream.
+//
+//	Args:
+//		data ([]byte):	The Workbook stream.
+//
+//	Returns:
+//		w (*Workbook):	The workbook.
+//		err (error):	Non-nil if the stream can't be parsed. Whatever was
+//				parsed up to that point is kept.
+func NewWorkbook(data []byte) (w *Workbook, err error) {
+	w = &Workbook{}
+	pos := 0
+	var sheet *Sheet
+	// Names and formulas can refer to names defined after them, so
+	// they're decoded at the end.
+	var names [][]byte
+	type formula struct {
+		sheet  *Sheet
+		record []byte
+	}
+	var formulas []formula
+	globals := true
+	substreams, depth := 0, 0
+	for pos+4 <= len(data) {
+		recordType := binary.LittleEndian.Uint16(data[pos:])
+		size := int(binary.LittleEndian.Uint16(data[pos+2:]))
+		start := pos
+		pos += 4
+		if pos+size > len(data) {
+			err = fmt.Errorf("record 0x%04X of %d bytes at offset %d overruns the stream", recordType, size, start)
+			break
+		}
+		record := data[pos : pos+size]
+		pos += size
+		for pos+4 <= len(data) && binary.LittleEndian.Uint16(data[pos:]) == biffContinue {
+			size = int(binary.LittleEndian.Uint16(data[pos+2:]))
+			if pos+4+size > len(data) {
+				break
+			}
+			record = append(record[:len(record):len(record)], data[pos+4:pos+4+size]...)
+			pos += 4 + size
+		}
+
+		switch recordType {
+		case biffBOF:
+			if start == 0 {
+				if len(record) < 4 || binary.LittleEndian.Uint16(record) != 0x0600 || binary.LittleEndian.Uint16(record[2:]) != 0x0005 {
+					err = ErrBIFFVersion
+					return
+				}
+				continue
+			}
+			// Sheets are found by their BOUNDSHEET offsets, or failing
+			// that, in order. Charts embedded in a sheet have their own
+			// BOF and EOF records, inside the sheet's.
+			globals = false
+			depth++
+			if depth > 1 {
+				continue
+			}
+			sheet = nil
+			for _, s := range w.Sheets {
+				if s.Offset == uint32(start) {
+					sheet = s
+				}
+			}
+			if sheet == nil && substreams < len(w.Sheets) {
+				sheet = w.Sheets[substreams]
+			}
+			substreams++
+		case biffEOF:
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 {
+				sheet = nil
+			}
+		case biffFilePass:
+			if globals {
+				w.Encrypted = true
+				return
+			}
+		case biffBoundSheet:
+			w.parseBoundSheet(record)
+		case biffExternSheet:
+			w.parseExternSheet(record)
+		case biffSupBook:
+			w.supBooks = append(w.supBooks, &supBook{self: len(record) >= 4 && binary.LittleEndian.Uint16(record[2:]) == 0x0401})
+		case biffExternName:
+			if len(w.supBooks) > 0 && len(record) > 6 {
+				sb := w.supBooks[len(w.supBooks)-1]
+				name, _ := shortXLString(record[6:])
+				sb.names = append(sb.names, name)
+			}
+		case biffName:
+			names = append(names, record)
+		case biffFormula:
+			if depth == 1 && sheet != nil && sheet.Type == SheetMacro {
+				formulas = append(formulas, formula{sheet, record})
+			}
+		}
+	}
+	w.parseNames(names)
+	for _, f := range formulas {
+		f.sheet.Formulas = append(f.sheet.Formulas, w.parseFormula(f.record))
+	}
+	return
+}
+
+func (w *Workbook) parseBoundSheet(record []byte) {
+	if len(record) < 6 {
+		return
+	}
+	sheet := &Sheet{Offset: binary.LittleEndian.Uint32(record)}
+	switch record[4] & 0x03 {
+	case 0:
+		sheet.Visibility = SheetVisible
+	case 1:
+		sheet.Visibility = SheetHidden
+	default:
+		sheet.Visibility = SheetVeryHidden
+	}
+	switch record[5] {
+	case 0x01:
+		sheet.Type = SheetMacro
+	case 0x02:
+		sheet.Type = SheetChart
+	case 0x06:
+		sheet.Type = SheetVBModule
+	default:
+		sheet.Type = SheetWorksheet
+	}
+	sheet.Name, _ = shortXLString(record[6:])
+	w.Sheets = append(w.Sheets, sheet)
+}
+
+func (w *Workbook) parseExternSheet(record []byte) {
+	if len(record) < 2 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint16(record))
+	for i := 0; i < count && 2+i*6+6 <= len(record); i++ {
+		xti := record[2+i*6:]
+		w.externSheets = append(w.externSheets, externSheet{
+			supBook: binary.LittleEndian.Uint16(xti),
+			first:   int16(binary.LittleEndian.Uint16(xti[2:])),
+			last:    int16(binary.LittleEndian.Uint16(xti[4:])),
+		})
+	}
+}
+
+// A FORMULA record ([MS-XLS] 2.4.127):
+//
+//	uint16	row
+//	uint16	column
+//	uint16	XF index
+//	[8]byte	cached value
+//	uint16	flags
+//	uint32	reserved
+//	uint16	size of the parsed expression
+//	[]byte	parsed expression, then any extra data it refers to
+func (w *Workbook) parseFormula(record []byte) (f *CellFormula) {
+	f = &CellFormula{}
+	if len(record) < 22 {
+		f.Formula = "=(truncated)"
+		return
+	}
+	f.Row = uint32(binary.LittleEndian.Uint16(record))
+	f.Col = binary.LittleEndian.Uint16(record[2:])
+	size := int(binary.LittleEndian.Uint16(record[20:]))
+	f.Formula = "=" + w.formulaText(record[22:], size)
+	return
+}
+
+// Decode the NAME records ([MS-XLS] 2.4.150):
+//
+//	uint16	flags
+//	byte	keyboard shortcut
+//	byte	length of the name
+//	uint16	size of the parsed expression
+//	uint16	reserved
+//	uint16	sheet index, 1-based, or 0 for the workbook
+//	[4]byte	reserved
+//	[]byte	name, as an XLUnicodeStringNoCch
+//	[]byte	parsed expression
+func (w *Workbook) parseNames(records [][]byte) {
+	expressions := make([][]byte, len(records))
+	for i, record := range records {
+		w.Names = append(w.Names, &DefinedName{})
+		if len(record) < 15 {
+			continue
+		}
+		name := w.Names[i]
+		flags := binary.LittleEndian.Uint16(record)
+		name.Hidden = flags&0x0001 != 0
+		name.Macro = flags&0x0008 != 0
+		name.Builtin = flags&0x0020 != 0
+		length := int(record[3])
+		if index := int(binary.LittleEndian.Uint16(record[8:])); index > 0 && index <= len(w.Sheets) {
+			name.Sheet = w.Sheets[index-1].Name
+		}
+		var n int
+		name.Name, n = xlString(record[14:], length)
+		if name.Builtin && len(name.Name) == 1 && int(name.Name[0]) < len(builtinNames) {
+			name.Name = builtinNames[name.Name[0]]
+		}
+		expressions[i] = record[14+n:]
+	}
+	for i, name := range w.Names {
+		if expressions[i] != nil {
+			size := int(binary.LittleEndian.Uint16(records[i][4:]))
+			name.Formula = "=" + w.formulaText(expressions[i], size)
+		}
+	}
+}
+
+// Decode a parsed expression of size bytes, at the start of data, or
+// explain why it can't be.
+func (w *Workbook) formulaText(data []byte, size int) string {
+	if size > len(data) {
+		return fmt.Sprintf("(expression of %d bytes overruns the record)", size)
+	}
+	text, err := w.decodeFormula(data[:size])
+	if err != nil {
+		return fmt.Sprintf("%s (%s)", text, err)
+	}
+	return text
+}
+
+// sheetName returns the name of the sheet a 3-D reference points at.
+func (w *Workbook) sheetName(xti uint16) string {
+	if int(xti) >= len(w.externSheets) {
+		return fmt.Sprintf("[xti%d]", xti)
+	}
+	e := w.externSheets[xti]
+	if int(e.supBook) >= len(w.supBooks) || !w.supBooks[e.supBook].self {
+		return fmt.Sprintf("[%d]", e.supBook)
+	}
+	name := func(i int16) string {
+		if i < 0 || int(i) >= len(w.Sheets) {
+			return "#REF"
+		}
+		return quoteSheet(w.Sheets[i].Name)
+	}
+	if e.first == e.last {
+		return name(e.first)
+	}
+	return name(e.first) + ":" + name(e.last)
+}
+
+// Quote a sheet name for use in a reference, if it needs it.
+func quoteSheet(name string) string {
+	for _, r := range name {
+		if !(r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7F) {
+			return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+		}
+	}
+	return name
+}
+
+// Read a ShortXLUnicodeString: a byte of length, then an XLUnicodeStringNoCch.
+func shortXLString(data []byte) (s string, n int) {
+	if len(data) < 1 {
+		return
+	}
+	s, n = xlString(data[1:], int(data[0]))
+	n++
+	return
+}
+
+// Read an XLUnicodeStringNoCch of length characters: a byte of flags,
+// then the characters. They're UTF-16 if the low bit of the flags is set,
+// or the low bytes of UTF-16 if not.
+func xlString(data []byte, length int) (s string, n int) {
+	if len(data) < 1 {
+		return
+	}
+	wide := data[0]&0x01 != 0
+	n = 1
+	if wide {
+		if n+length*2 > len(data) {
+			length = (len(data) - n) / 2
+		}
+		chars := make([]uint16, length)
+		for i := range chars {
+			chars[i] = binary.LittleEndian.Uint16(data[n+i*2:])
+		}
+		s = string(utf16.Decode(chars))
+		n += length * 2
+		return
+	}
+	if n+length > len(data) {
+		length = len(data) - n
+	}
+	runes := make([]rune, length)
+	for i, b := range data[n : n+length] {
+		runes[i] = rune(b)
+	}
+	s = string(runes)
+	n += length
+	return
+}
+
+// cellName returns a cell's address in A1 notation, with $ for the
+// absolute parts.
+func cellName(row uint32, col uint16, absRow, absCol bool) string {
+	name := ""
+	if absCol {
+		name = "$"
+	}
+	letters := ""
+	for c := int(col) + 1; c > 0; c = (c - 1) / 26 {
+		letters = string(rune('A'+(c-1)%26)) + letters
+	}
+	name += letters
+	if absRow {
+		name += "$"
+	}
+	return name + fmt.Sprint(int(row)+1)
+}
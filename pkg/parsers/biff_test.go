@@ -0,0 +1,326 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/richardlehane/mscfb"
+)
+
+// Read the stream called name out of a compound file in the test data.
+func sampleStream(t *testing.T, sample, name string) (data []byte) {
+	t.Helper()
+	f, err := os.Open(path.Join(pathToSampleDataDir, sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rdr, err := mscfb.New(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for entry, err := rdr.Next(); err == nil; entry, err = rdr.Next() {
+		if entry.Name == name {
+			data, err = io.ReadAll(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("no %s stream in %s", name, sample)
+	return
+}
+
+// Append a BIFF record.
+func biffRecord(buf *bytes.Buffer, recordType uint16, data ...[]byte) {
+	body := bytes.Join(data, nil)
+	binary.Write(buf, binary.LittleEndian, recordType)
+	binary.Write(buf, binary.LittleEndian, uint16(len(body)))
+	buf.Write(body)
+}
+
+func le16(v ...uint16) (b []byte) {
+	for _, x := range v {
+		b = binary.LittleEndian.AppendUint16(b, x)
+	}
+	return
+}
+
+func bof(dt uint16) []byte {
+	return append(le16(0x0600, dt), make([]byte, 12)...)
+}
+
+// A ShortXLUnicodeString, in compressed (8-bit) form.
+func shortString(s string) []byte {
+	return append([]byte{byte(len(s)), 0}, s...)
+}
+
+// A FORMULA record's data, for the expression rgce at row and col.
+func formulaRecord(row, col uint16, rgce ...[]byte) []byte {
+	expression := bytes.Join(rgce, nil)
+	return bytes.Join([][]byte{le16(row, col, 0x0F), make([]byte, 8), le16(0), make([]byte, 4), le16(uint16(len(expression))), expression}, nil)
+}
+
+// A NAME record's data. Built-in names are given by their character.
+func nameRecord(flags uint16, name string, sheet uint16, rgce ...[]byte) []byte {
+	expression := bytes.Join(rgce, nil)
+	return bytes.Join([][]byte{le16(flags), {0, byte(len(name))}, le16(uint16(len(expression)), 0, sheet), make([]byte, 4), {0}, []byte(name), expression}, nil)
+}
+
+// A sheet for buildWorkbook: its BOUNDSHEET's state and type bytes, its
+// name, the type in its BOF record, and what goes between that and EOF.
+type testSheet struct {
+	state, dt byte
+	name      string
+	bofType   uint16
+	records   []func(*bytes.Buffer)
+}
+
+// Build a Workbook stream with the given sheets, and extra records in
+// the globals.
+func buildWorkbook(sheets []testSheet, globals ...func(*bytes.Buffer)) []byte {
+	substreams := []*bytes.Buffer{}
+	for _, s := range sheets {
+		buf := &bytes.Buffer{}
+		biffRecord(buf, biffBOF, bof(s.bofType))
+		for _, r := range s.records {
+			r(buf)
+		}
+		biffRecord(buf, biffEOF)
+		substreams = append(substreams, buf)
+	}
+	build := func(offsets []uint32) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		biffRecord(buf, biffBOF, bof(0x0005))
+		for i, s := range sheets {
+			biffRecord(buf, biffBoundSheet, binary.LittleEndian.AppendUint32(nil, offsets[i]), []byte{s.state, s.dt}, shortString(s.name))
+		}
+		for _, g := range globals {
+			g(buf)
+		}
+		biffRecord(buf, biffEOF)
+		return buf
+	}
+	offsets := make([]uint32, len(sheets))
+	offset := uint32(build(offsets).Len())
+	for i, s := range substreams {
+		offsets[i] = offset
+		offset += uint32(s.Len())
+	}
+	buf := build(offsets)
+	for _, s := range substreams {
+		buf.Write(s.Bytes())
+	}
+	return buf.Bytes()
+}
+
+func TestWorkbook(t *testing.T) {
+	exec := append(append([]byte{0x17}, shortString("calc.exe")...), 0x42, 1, 110, 0)
+	data := buildWorkbook([]testSheet{
+		{0, 0, "Sheet1", 0x0010, nil},
+		{2, 1, "Macro1", 0x0040, []func(*bytes.Buffer){
+			func(b *bytes.Buffer) { biffRecord(b, biffFormula, formulaRecord(0, 0, exec)) },
+			// An embedded chart's formulas aren't the macro sheet's.
+			func(b *bytes.Buffer) { biffRecord(b, biffBOF, bof(0x0020)) },
+			func(b *bytes.Buffer) { biffRecord(b, biffFormula, formulaRecord(9, 9, exec)) },
+			func(b *bytes.Buffer) { biffRecord(b, biffEOF) },
+			func(b *bytes.Buffer) {
+				biffRecord(b, biffFormula, formulaRecord(1, 0, []byte{0x1E}, le16(65), []byte{0x41}, le16(111), []byte{0x17}, shortString(`"x`), []byte{0x08}))
+			},
+			func(b *bytes.Buffer) {
+				biffRecord(b, biffFormula, formulaRecord(2, 0, []byte{0x43}, []byte{1, 0, 0, 0}, []byte{0x41}, le16(53)))
+			},
+			// RUN is a command, with a relative reference.
+			func(b *bytes.Buffer) {
+				biffRecord(b, biffFormula, formulaRecord(3, 0, []byte{0x24}, le16(0, 0xC000), []byte{0x42, 1}, le16(0x8000|17)))
+			},
+			func(b *bytes.Buffer) { biffRecord(b, biffFormula, formulaRecord(4, 27, []byte{0x42, 0}, le16(54))) },
+		}},
+	},
+		func(b *bytes.Buffer) { biffRecord(b, biffSupBook, le16(2, 0x0401)) },
+		func(b *bytes.Buffer) { biffRecord(b, biffExternSheet, le16(1, 0, 1, 1)) },
+		func(b *bytes.Buffer) {
+			biffRecord(b, biffName, nameRecord(0x0020, "\x01", 0, []byte{0x3A}, le16(0, 0, 0)))
+		},
+		func(b *bytes.Buffer) {
+			biffRecord(b, biffName, nameRecord(0x0009, "Auto_Open2", 2, []byte{0x3B}, le16(0, 0, 1, 0, 0)))
+		},
+	)
+
+	w, err := NewWorkbook(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Sheets) != 2 {
+		t.Fatalf("expected 2 sheets, got %d", len(w.Sheets))
+	}
+	for i, expected := range []Sheet{
+		{Name: "Sheet1", Type: SheetWorksheet, Visibility: SheetVisible},
+		{Name: "Macro1", Type: SheetMacro, Visibility: SheetVeryHidden},
+	} {
+		s := w.Sheets[i]
+		if s.Name != expected.Name || s.Type != expected.Type || s.Visibility != expected.Visibility {
+			t.Errorf("sheet %d mismatch - expected %+v got %+v", i, expected, s)
+		}
+	}
+	if len(w.Sheets[0].Formulas) != 0 {
+		t.Errorf("expected no formulas on the worksheet, got %d", len(w.Sheets[0].Formulas))
+	}
+
+	for i, expected := range []struct{ cell, formula string }{
+		{"A1", `=EXEC("calc.exe")`},
+		{"A2", `=CHAR(65)&"""x"`},
+		{"A3", `=GOTO(Auto_Open)`},
+		{"A4", `=RUN(A1)`},
+		{"AB5", `=HALT()`},
+	} {
+		if i >= len(w.Sheets[1].Formulas) {
+			t.Fatalf("expected 5 formulas, got %d", len(w.Sheets[1].Formulas))
+		}
+		f := w.Sheets[1].Formulas[i]
+		if f.Cell() != expected.cell || f.Formula != expected.formula {
+			t.Errorf("formula %d mismatch - expected %s %s got %s %s", i, expected.cell, expected.formula, f.Cell(), f.Formula)
+		}
+	}
+
+	if len(w.Names) != 2 {
+		t.Fatalf("expected 2 names, got %d", len(w.Names))
+	}
+	if n := w.Names[0]; n.Name != "Auto_Open" || !n.Builtin || n.Formula != "=Macro1!$A$1" || !n.AutoRun() {
+		t.Errorf("built-in name mismatch: %+v", n)
+	}
+	if n := w.Names[1]; n.Name != "Auto_Open2" || n.Sheet != "Macro1" || !n.Hidden || !n.Macro || n.Formula != "=Macro1!$A$1:$A$2" || !n.AutoRun() {
+		t.Errorf("name mismatch: %+v", n)
+	}
+}
+
+// The Workbook stream of a real .xls file, with three worksheets and
+// no macros.
+func TestWorkbookSample(t *testing.T) {
+	w, err := NewWorkbook(sampleStream(t, "test.xls", "Workbook"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Encrypted || len(w.Names) != 0 {
+		t.Errorf("expected no encryption or names, got %+v", w)
+	}
+	if len(w.Sheets) != 3 {
+		t.Fatalf("expected 3 sheets, got %d", len(w.Sheets))
+	}
+	for i, name := range []string{"Test sheet 1", "Test sheet 2", "Sheet3"} {
+		s := w.Sheets[i]
+		if s.Name != name || s.Type != SheetWorksheet || s.Visibility != SheetVisible || len(s.Formulas) != 0 {
+			t.Errorf("sheet %d mismatch - expected a visible worksheet %s got %+v", i, name, s)
+		}
+	}
+}
+
+// Append a BIFF record, split into CONTINUE records at the offsets
+// given, as Excel does for records too long to fit in one.
+func biffSplitRecord(buf *bytes.Buffer, recordType uint16, data []byte, at ...int) {
+	start := 0
+	for _, end := range append(at, len(data)) {
+		biffRecord(buf, recordType, data[start:end])
+		recordType = biffContinue
+		start = end
+	}
+}
+
+// Records split across CONTINUE records are read as a whole.
+func TestWorkbookContinue(t *testing.T) {
+	exec := append(append([]byte{0x17}, shortString("calc.exe")...), 0x42, 1, 110, 0)
+	formula := formulaRecord(0, 0, exec)
+	data := buildWorkbook([]testSheet{
+		{0, 1, "Macro1", 0x0040, []func(*bytes.Buffer){
+			func(b *bytes.Buffer) { biffSplitRecord(b, biffFormula, formula, 10, 26) },
+			func(b *bytes.Buffer) { biffRecord(b, biffFormula, formulaRecord(1, 0, []byte{0x42, 0}, le16(54))) },
+		}},
+	},
+		func(b *bytes.Buffer) { biffRecord(b, biffSupBook, le16(1, 0x0401)) },
+		func(b *bytes.Buffer) { biffRecord(b, biffExternSheet, le16(1, 0, 0, 0)) },
+		func(b *bytes.Buffer) {
+			biffSplitRecord(b, biffName, nameRecord(0x0008, "Auto_Open", 1, []byte{0x3A}, le16(0, 0, 0)), 18)
+		},
+	)
+	w, err := NewWorkbook(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Sheets) != 1 || len(w.Sheets[0].Formulas) != 2 {
+		t.Fatalf("expected a sheet with 2 formulas, got %+v", w.Sheets)
+	}
+	for i, expected := range []string{`=EXEC("calc.exe")`, `=HALT()`} {
+		if f := w.Sheets[0].Formulas[i]; f.Formula != expected {
+			t.Errorf("formula %d mismatch - expected %s got %s", i, expected, f.Formula)
+		}
+	}
+	if len(w.Names) != 1 || w.Names[0].Name != "Auto_Open" || w.Names[0].Formula != "=Macro1!$A$1" {
+		t.Errorf("expected a split NAME record to be read whole, got %+v", w.Names)
+	}
+
+	buf := &bytes.Buffer{}
+	biffRecord(buf, biffBOF, bof(0x0005))
+	biffSplitRecord(buf, biffBoundSheet, bytes.Join([][]byte{le16(0, 0), {0, 1}, shortString("Macro sheet")}, nil), 11)
+	biffRecord(buf, biffEOF)
+	w, err = NewWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Sheets) != 1 || w.Sheets[0].Name != "Macro sheet" || w.Sheets[0].Type != SheetMacro {
+		t.Errorf("expected a split BOUNDSHEET record to be read whole, got %+v", w.Sheets)
+	}
+
+	// A CONTINUE record which overruns the stream is reported.
+	buf.Truncate(buf.Len() - 4)
+	biffRecord(buf, biffContinue, []byte("more"))
+	_, err = NewWorkbook(buf.Bytes()[:buf.Len()-1])
+	if err == nil || !strings.Contains(err.Error(), "0x003C") {
+		t.Errorf("expected the overrun to be reported, got %v", err)
+	}
+}
+
+func TestWorkbookMalformed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	biffRecord(buf, biffBOF, bof(0x0005))
+	biffRecord(buf, biffBoundSheet, le16(0, 0, 0), shortString("Sheet1"))
+	biffRecord(buf, biffFilePass, le16(1))
+	biffRecord(buf, biffName, nameRecord(0x0020, "\x01", 0))
+	w, err := NewWorkbook(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !w.Encrypted || len(w.Sheets) != 1 || len(w.Names) != 0 {
+		t.Errorf("expected an encrypted workbook with a sheet and no names, got %+v", w)
+	}
+
+	// BIFF5 isn't supported.
+	buf.Reset()
+	biffRecord(buf, biffBOF, le16(0x0500, 0x0005))
+	if _, err = NewWorkbook(buf.Bytes()); !errors.Is(err, ErrBIFFVersion) {
+		t.Errorf("expected ErrBIFFVersion, got %v", err)
+	}
+
+	// Records overrunning the stream stop parsing, keeping what came before.
+	buf.Reset()
+	biffRecord(buf, biffBOF, bof(0x0005))
+	biffRecord(buf, biffBoundSheet, le16(0, 0, 0x0100), shortString("Macro1"))
+	buf.Write(le16(biffName, 100))
+	w, err = NewWorkbook(buf.Bytes())
+	if err == nil || len(w.Sheets) != 1 {
+		t.Errorf("expected an error and one sheet, got %v and %+v", err, w)
+	}
+
+	// Unknown tokens leave what was decoded, and say why.
+	w = &Workbook{}
+	text, err := w.decodeFormula([]byte{0x1E, 1, 0, 0xFF})
+	if text != "1" || err == nil {
+		t.Errorf("expected 1 and an error, got %q, %v", text, err)
+	}
+}
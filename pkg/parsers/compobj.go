@@ -0,0 +1,187 @@
+package parsers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Marks the start of the Unicode half of a CompObj stream.
+const compObjUnicodeMarker uint32 = 0x71B239F4
+
+// Longest ProgID allowed in a CompObj stream. Longer ones are ignored,
+// along with anything after them.
+const maxProgIDSize = 0x28
+
+// CompObj stream reader. The "\x01CompObj" stream sits next to an
+// embedded object's data, and says which application it belongs to.
+// It is laid out like so ([MS-OLEDS] 2.3.8, integers are little-endian,
+// strings are a uint32 length including the null, followed by the
+// string):
+//
+//	[28]byte	header, Version at offset 4
+//	string	UserType, e.g. "Microsoft Word Document"
+//	format	ClipboardFormat or ClipboardFormatName
+//	string	ProgID, e.g. "Word.Document.8"
+//	uint32	0x71B239F4, if the Unicode fields follow
+//	ustring	UnicodeUserType
+//	uformat	UnicodeClipboardFormatName
+//	ustring	UnicodeProgID
+//
+// A format is a uint32 which is 0 if there is no clipboard format,
+// 0xFFFFFFFF or 0xFFFFFFFE followed by a uint32 standard clipboard
+// format, or otherwise the length of a registered clipboard format
+// name. Unicode strings are the same, with a length in UTF-16 code
+// units.
+type CompObj struct {
+	Version uint32
+
+	// Display name of the object's type.
+	UserType string
+
+	// The clipboard format the object's data is in. This is a
+	// standard format (like 3, CF_METAFILEPICT), or the name of
+	// a registered one.
+	ClipboardFormat     uint32
+	ClipboardFormatName string
+
+	// Programmatic ID of the object's class.
+	ProgID string
+
+	// The Unicode fields are optional.
+	UnicodeUserType            string
+	UnicodeClipboardFormat     uint32
+	UnicodeClipboardFormatName string
+	UnicodeProgID              string
+}
+
+// Parse a CompObj stream.
+//
+//	Args:
+//		in (io.ReaderAt):	The CompObj stream.
+//		size (int64):		Size of the stream.
+//
+//	Returns:
+//		c (*CompObj):	The parsed stream.
+//		err (error):	Non-nil if the stream can't be parsed.
+func NewCompObj(in io.ReaderAt, size int64) (c *CompObj, err error) {
+	c = &CompObj{}
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(in, 0, size)), size: size}
+	header := make([]byte, 28)
+	_, err = io.ReadFull(h, header)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the header", err)
+		return
+	}
+	c.Version = binary.LittleEndian.Uint32(header[4:])
+
+	c.UserType, err = h.lengthPrefixedString()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the user type", err)
+		return
+	}
+	c.ClipboardFormat, c.ClipboardFormatName, err = h.clipboardFormat(false)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the clipboard format", err)
+		return
+	}
+
+	// Everything from here on is optional.
+	if h.left() < 4 {
+		return
+	}
+	if progID, ok := h.progID(false); ok {
+		c.ProgID = progID
+	} else {
+		return
+	}
+	var marker uint32
+	if h.left() < 4 || binary.Read(h, binary.LittleEndian, &marker) != nil || marker != compObjUnicodeMarker {
+		return
+	}
+	c.UnicodeUserType, err = h.lengthPrefixedUnicodeString()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the Unicode user type", err)
+		return
+	}
+	c.UnicodeClipboardFormat, c.UnicodeClipboardFormatName, err = h.clipboardFormat(true)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the Unicode clipboard format", err)
+		return
+	}
+	if progID, ok := h.progID(true); ok {
+		c.UnicodeProgID = progID
+	}
+	return
+}
+
+// Read a ClipboardFormatOrAnsiString, or a ClipboardFormatOrUnicodeString.
+func (h *headerReader) clipboardFormat(unicode bool) (format uint32, name string, err error) {
+	var marker uint32
+	err = binary.Read(h, binary.LittleEndian, &marker)
+	if err != nil {
+		return
+	}
+	switch marker {
+	case 0:
+	case 0xFFFFFFFF, 0xFFFFFFFE:
+		err = binary.Read(h, binary.LittleEndian, &format)
+	default:
+		name, err = h.stringOfLength(marker, unicode)
+	}
+	return
+}
+
+// Read the ProgID. If it's missing or too long, ok is false.
+func (h *headerReader) progID(unicode bool) (progID string, ok bool) {
+	var length uint32
+	if binary.Read(h, binary.LittleEndian, &length) != nil || length > maxProgIDSize {
+		return
+	}
+	progID, err := h.stringOfLength(length, unicode)
+	ok = err == nil
+	return
+}
+
+// lengthPrefixedUnicodeString reads a UTF-16 string prefixed with its
+// uint32 length in code units, which includes the terminating null.
+func (h *headerReader) lengthPrefixedUnicodeString() (s string, err error) {
+	var length uint32
+	err = binary.Read(h, binary.LittleEndian, &length)
+	if err != nil {
+		return
+	}
+	s, err = h.stringOfLength(length, true)
+	return
+}
+
+// Read a string of length characters, including the terminating null.
+func (h *headerReader) stringOfLength(length uint32, unicode bool) (s string, err error) {
+	size := int64(length)
+	if unicode {
+		size *= 2
+	}
+	if size > h.left() || size > maxOle10StringSize {
+		err = fmt.Errorf("string length of %d overruns the stream (%d bytes left)", size, h.left())
+		return
+	}
+	buf := make([]byte, size)
+	_, err = io.ReadFull(h, buf)
+	if err != nil {
+		return
+	}
+	if unicode {
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(buf[i*2:])
+		}
+		s = string(utf16.Decode(units))
+	} else {
+		s = string(buf)
+	}
+	s = strings.TrimRight(s, "\x00")
+	return
+}
@@ -0,0 +1,69 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// Build a CompObj stream, with a registered clipboard format and Unicode copies.
+func buildCompObj(userType, format, progID string) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0x01, 0x00, 0xfe, 0xff, 0x03, 0x0a, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff})
+	buf.Write(make([]byte, 16))
+	for _, s := range []string{userType, format, progID} {
+		binary.Write(buf, binary.LittleEndian, uint32(len(s)+1))
+		buf.WriteString(s + "\x00")
+	}
+	binary.Write(buf, binary.LittleEndian, compObjUnicodeMarker)
+	for _, s := range []string{userType, format, progID} {
+		units := utf16.Encode([]rune(s + "\x00"))
+		binary.Write(buf, binary.LittleEndian, uint32(len(units)))
+		binary.Write(buf, binary.LittleEndian, units)
+	}
+	return buf.Bytes()
+}
+
+// Both halves of a CompObj stream are decoded.
+func TestCompObj(t *testing.T) {
+	data := buildCompObj("Microsoft Word Document", "MSWordDoc", "Word.Document.8")
+	c, err := NewCompObj(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 0x0a03 {
+		t.Errorf("version mismatch - expected %#x got %#x", 0x0a03, c.Version)
+	}
+	if c.UserType != "Microsoft Word Document" || c.UnicodeUserType != c.UserType {
+		t.Errorf("user type mismatch - got %q and %q", c.UserType, c.UnicodeUserType)
+	}
+	if c.ClipboardFormatName != "MSWordDoc" || c.UnicodeClipboardFormatName != c.ClipboardFormatName {
+		t.Errorf("clipboard format mismatch - got %q and %q", c.ClipboardFormatName, c.UnicodeClipboardFormatName)
+	}
+	if c.ProgID != "Word.Document.8" || c.UnicodeProgID != c.ProgID {
+		t.Errorf("ProgID mismatch - got %q and %q", c.ProgID, c.UnicodeProgID)
+	}
+}
+
+// Standard clipboard formats, and streams which stop after the ANSI fields.
+func TestCompObjShort(t *testing.T) {
+	buf := &bytes.Buffer{}
+	buf.Write(make([]byte, 28))
+	binary.Write(buf, binary.LittleEndian, uint32(12))
+	buf.WriteString("OLE Package\x00")
+	binary.Write(buf, binary.LittleEndian, []uint32{0xffffffff, 3})
+	c, err := NewCompObj(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.UserType != "OLE Package" || c.ClipboardFormat != 3 || c.ProgID != "" {
+		t.Errorf("mismatch: %+v", c)
+	}
+
+	// A user type running off the end is an error.
+	data := buf.Bytes()[:35]
+	if _, err = NewCompObj(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Errorf("expected an error for a truncated user type")
+	}
+}
@@ -0,0 +1,243 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// Kinds of moniker we know how to read.
+const (
+	MonikerFile      = "File"
+	MonikerURL       = "URL"
+	MonikerItem      = "Item"
+	MonikerAnti      = "Anti"
+	MonikerComposite = "Composite"
+	MonikerUnknown   = "Unknown"
+)
+
+// Class IDs of the monikers, as stored in a moniker stream.
+var monikerKinds = map[string]string{
+	"{00000303-0000-0000-C000-000000000046}": MonikerFile,
+	"{79EAC9E0-BAF9-11CE-8C82-00AA004BA90B}": MonikerURL,
+	"{00000304-0000-0000-C000-000000000046}": MonikerItem,
+	"{00000305-0000-0000-C000-000000000046}": MonikerAnti,
+	"{00000309-0000-0000-C000-000000000046}": MonikerComposite,
+}
+
+// A moniker names something to link to: a file, a URL, a part of
+// something else (an item), or several of those strung together (a
+// composite). Each is stored as its class ID, followed by data in a
+// layout of its own ([MS-OSHARED] 2.3.7).
+type Moniker struct {
+	// One of the Moniker constants.
+	Kind  string
+	CLSID string
+
+	// The path of a file moniker, the URL of a URL moniker,
+	// or the item name of an item moniker.
+	Target string
+
+	// The delimiter ahead of an item moniker's name, usually "!".
+	Delimiter string
+
+	// How many levels up a file or anti moniker goes.
+	Anti int
+
+	// The parts of a composite moniker.
+	Monikers []*Moniker
+}
+
+// String returns what the moniker points at. For a composite, this is
+// the concatenation of its parts, like "C:\book.xlsx!Sheet1!R1C1".
+func (m *Moniker) String() string {
+	switch m.Kind {
+	case MonikerFile:
+		return strings.Repeat(`..\`, m.Anti) + m.Target
+	case MonikerItem:
+		return m.Delimiter + m.Target
+	case MonikerAnti:
+		return strings.Repeat(`..\`, m.Anti)
+	case MonikerComposite:
+		parts := make([]string, 0, len(m.Monikers))
+		for _, part := range m.Monikers {
+			parts = append(parts, part.String())
+		}
+		return strings.Join(parts, "")
+	}
+	return m.Target
+}
+
+// ParseMoniker reads a moniker stream: a class ID, then the moniker's
+// data. Monikers we don't know come back as MonikerUnknown, with just
+// the class ID set. Since there's no telling how long those are, a
+// composite stops at the first one.
+func ParseMoniker(r *bytes.Reader) (m *Moniker, err error) {
+	clsid := make([]byte, 16)
+	_, err = io.ReadFull(r, clsid)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the moniker class ID", err)
+		return
+	}
+	m = &Moniker{CLSID: guidString(clsid), Kind: MonikerUnknown}
+	if kind, ok := monikerKinds[m.CLSID]; ok {
+		m.Kind = kind
+	}
+	switch m.Kind {
+	case MonikerFile:
+		err = m.parseFile(r)
+	case MonikerURL:
+		err = m.parseURL(r)
+	case MonikerItem:
+		err = m.parseItem(r)
+	case MonikerAnti:
+		var count uint32
+		err = binary.Read(r, binary.LittleEndian, &count)
+		m.Anti = int(count)
+	case MonikerComposite:
+		err = m.parseComposite(r)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: parsing %s moniker", err, strings.ToLower(m.Kind))
+	}
+	return
+}
+
+// File monikers hold an ANSI path, and optionally a Unicode one.
+func (m *Moniker) parseFile(r *bytes.Reader) (err error) {
+	var cAnti uint16
+	err = binary.Read(r, binary.LittleEndian, &cAnti)
+	if err != nil {
+		return
+	}
+	m.Anti = int(cAnti)
+	ansi, err := readCounted(r)
+	if err != nil {
+		return
+	}
+	m.Target = strings.TrimRight(string(ansi), "\x00")
+
+	// Everything after the ANSI path is optional.
+	if r.Len() == 0 {
+		return
+	}
+	// End server, version number and reserved fields.
+	_, err = r.Seek(2+2+16+4, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	var unicodeSize uint32
+	err = binary.Read(r, binary.LittleEndian, &unicodeSize)
+	if err != nil || unicodeSize == 0 {
+		return
+	}
+	// The Unicode path is preceded by its size in bytes, and a key
+	// value. It isn't null-terminated.
+	var unicode struct {
+		Size uint32
+		Key  uint16
+	}
+	err = binary.Read(r, binary.LittleEndian, &unicode)
+	if err != nil {
+		return
+	}
+	if int64(unicode.Size) > int64(r.Len()) {
+		err = fmt.Errorf("length of %d overruns the moniker (%d bytes left)", unicode.Size, r.Len())
+		return
+	}
+	path := make([]byte, unicode.Size)
+	_, err = io.ReadFull(r, path)
+	if err != nil {
+		return
+	}
+	m.Target = decodeUTF16(path)
+	return
+}
+
+// URL monikers hold a null-terminated UTF-16 URL, optionally
+// followed by a serialization GUID, version and flags.
+func (m *Moniker) parseURL(r *bytes.Reader) (err error) {
+	data, err := readCounted(r)
+	if err != nil {
+		return
+	}
+	m.Target = decodeUTF16(data)
+	return
+}
+
+// Item monikers hold a delimiter and an item name. Each is an ANSI
+// string, which may be followed by a Unicode copy in the same count.
+func (m *Moniker) parseItem(r *bytes.Reader) (err error) {
+	delimiter, err := readCounted(r)
+	if err != nil {
+		return
+	}
+	m.Delimiter = ansiPart(delimiter)
+	item, err := readCounted(r)
+	if err != nil {
+		return
+	}
+	m.Target = ansiPart(item)
+	return
+}
+
+// Composite monikers hold a count, then that many moniker streams.
+func (m *Moniker) parseComposite(r *bytes.Reader) (err error) {
+	var count uint32
+	err = binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return
+	}
+	for i := uint32(0); i < count; i++ {
+		var part *Moniker
+		part, err = ParseMoniker(r)
+		if err != nil {
+			return
+		}
+		m.Monikers = append(m.Monikers, part)
+		if part.Kind == MonikerUnknown {
+			return
+		}
+	}
+	return
+}
+
+// Read a uint32 count of bytes, then the bytes.
+func readCounted(r *bytes.Reader) (data []byte, err error) {
+	var count uint32
+	err = binary.Read(r, binary.LittleEndian, &count)
+	if err != nil {
+		return
+	}
+	if int64(count) > int64(r.Len()) {
+		err = fmt.Errorf("length of %d overruns the moniker (%d bytes left)", count, r.Len())
+		return
+	}
+	data = make([]byte, count)
+	_, err = io.ReadFull(r, data)
+	return
+}
+
+// Decode UTF-16 up to the first null.
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := binary.LittleEndian.Uint16(data[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// The ANSI string at the start of data, up to the first null.
+func ansiPart(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return string(data)
+}
@@ -0,0 +1,286 @@
+package parsers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// OLE 1.0 object format IDs, from [MS-OLEDS] 2.2.
+const (
+	Ole1FormatLinked       uint32 = 1
+	Ole1FormatEmbedded     uint32 = 2
+	Ole1FormatPresentation uint32 = 5
+)
+
+// The OLEVersion written by OLE 1.0 libraries. It is meant to be
+// ignored when reading, but it's a good way to spot these objects.
+const Ole1Version uint32 = 0x00000501
+
+// Class names of the standard presentation formats.
+const (
+	Ole1PresentationMetafile = "METAFILEPICT"
+	Ole1PresentationBitmap   = "BITMAP"
+	Ole1PresentationDIB      = "DIB"
+)
+
+// OLE 1.0 object reader. This reads a serialized OLE 1.0 object, as
+// found in RTF \objdata and in documents written before OLE 2.0, laid
+// out like so (integers are little-endian, strings are a uint32 length
+// including the null, followed by the string):
+//
+//	uint32	OLEVersion
+//	uint32	FormatID: Ole1FormatLinked or Ole1FormatEmbedded
+//	string	ClassName
+//	string	TopicName
+//	string	ItemName
+//
+// Embedded objects follow with the native data:
+//
+//	uint32	NativeSize
+//	[]byte	the native data
+//
+// Linked objects follow with:
+//
+//	string	NetworkName
+//	uint32	reserved
+//	uint32	LinkUpdateOption
+//
+// Either may be followed by a presentation object, see Ole1Presentation.
+type Ole1Object struct {
+	Version  uint32
+	FormatID uint32
+
+	// The OLE 1.0 class of the object, e.g. "Package" or "PBrush".
+	ClassName string
+
+	// For embedded objects, these are usually empty. For links,
+	// TopicName is the linked file, and ItemName the part of it.
+	TopicName string
+	ItemName  string
+
+	// Linked objects only.
+	NetworkName      string
+	LinkUpdateOption uint32
+
+	// Size of the native data, for embedded objects.
+	NativeSize int64
+
+	// How the object was last drawn. Nil if there is no presentation.
+	Presentation *Ole1Presentation
+
+	ra           io.ReaderAt
+	nativeOffset int64
+}
+
+// An OLE 1.0 presentation object. For the standard formats (ClassName
+// is one of the Ole1Presentation constants), Width and Height are set.
+// For anything else, ClassName is empty and the data is in the format
+// given by ClipboardFormat, or ClipboardFormatName for a registered
+// clipboard format.
+//
+//	uint32	OLEVersion
+//	uint32	FormatID: Ole1FormatPresentation, 0 if there is none
+//	string	ClassName
+//
+// Standard formats follow with:
+//
+//	uint32	Width
+//	int32	Height, negative
+//
+// Other formats follow with:
+//
+//	uint32	ClipboardFormat
+//	string	ClipboardFormatName, only if ClipboardFormat is 0
+//
+// And both end with:
+//
+//	uint32	Size
+//	[]byte	the presentation data
+type Ole1Presentation struct {
+	ClassName string
+
+	Width  uint32
+	Height int32
+
+	ClipboardFormat     uint32
+	ClipboardFormatName string
+
+	// Size of the presentation data.
+	Size int64
+
+	ra     io.ReaderAt
+	offset int64
+}
+
+// Create a new OLE 1.0 object reader. The header and presentation
+// are parsed up front, the native and presentation data are read
+// from in when asked for.
+//
+//	Args:
+//		in (io.ReaderAt):	The serialized object.
+//		size (int64):		Size of the serialized object.
+//
+//	Returns:
+//		o (*Ole1Object):	Reader for the object.
+//		err (error):	Non-nil if the object can't be parsed.
+func NewOle1Object(in io.ReaderAt, size int64) (o *Ole1Object, err error) {
+	o = &Ole1Object{ra: in}
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(in, 0, size)), size: size}
+	err = binary.Read(h, binary.LittleEndian, &o.Version)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the OLE version", err)
+		return
+	}
+	err = binary.Read(h, binary.LittleEndian, &o.FormatID)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the format ID", err)
+		return
+	}
+	if o.FormatID != Ole1FormatLinked && o.FormatID != Ole1FormatEmbedded {
+		err = fmt.Errorf("unsupported format ID %d", o.FormatID)
+		return
+	}
+	for _, field := range []struct {
+		name string
+		dest *string
+	}{
+		{"class name", &o.ClassName},
+		{"topic name", &o.TopicName},
+		{"item name", &o.ItemName},
+	} {
+		*field.dest, err = h.lengthPrefixedString()
+		if err != nil {
+			err = fmt.Errorf("%w: getting the %s", err, field.name)
+			return
+		}
+	}
+
+	if o.FormatID == Ole1FormatEmbedded {
+		var nativeSize uint32
+		err = binary.Read(h, binary.LittleEndian, &nativeSize)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the native data size", err)
+			return
+		}
+		o.NativeSize = int64(nativeSize)
+		if o.NativeSize > h.left() {
+			err = fmt.Errorf("native data size of %d overruns the stream (%d bytes left)", o.NativeSize, h.left())
+			return
+		}
+		o.nativeOffset = h.n
+		err = h.skip(o.NativeSize)
+		if err != nil {
+			err = fmt.Errorf("%w: skipping the native data", err)
+			return
+		}
+	} else {
+		o.NetworkName, err = h.lengthPrefixedString()
+		if err != nil {
+			err = fmt.Errorf("%w: getting the network name", err)
+			return
+		}
+		var reserved uint32
+		err = binary.Read(h, binary.LittleEndian, &reserved)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the reserved field", err)
+			return
+		}
+		err = binary.Read(h, binary.LittleEndian, &o.LinkUpdateOption)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the link update option", err)
+			return
+		}
+	}
+
+	// The presentation is optional.
+	if h.left() == 0 {
+		return
+	}
+	o.Presentation, err = parseOle1Presentation(h, in)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing the presentation", err)
+	}
+	return
+}
+
+// Native returns the native data of an embedded object.
+func (o *Ole1Object) Native() *io.SectionReader {
+	return io.NewSectionReader(o.ra, o.nativeOffset, o.NativeSize)
+}
+
+// Data returns the presentation data.
+func (p *Ole1Presentation) Data() *io.SectionReader {
+	return io.NewSectionReader(p.ra, p.offset, p.Size)
+}
+
+// Standard returns true if the presentation is in one of the standard formats.
+func (p *Ole1Presentation) Standard() bool {
+	switch p.ClassName {
+	case Ole1PresentationMetafile, Ole1PresentationBitmap, Ole1PresentationDIB:
+		return true
+	}
+	return false
+}
+
+func parseOle1Presentation(h *headerReader, in io.ReaderAt) (p *Ole1Presentation, err error) {
+	var version, formatID uint32
+	err = binary.Read(h, binary.LittleEndian, &version)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the OLE version", err)
+		return
+	}
+	err = binary.Read(h, binary.LittleEndian, &formatID)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the format ID", err)
+		return
+	}
+	if formatID == 0 {
+		return
+	}
+	p = &Ole1Presentation{ra: in}
+	p.ClassName, err = h.lengthPrefixedString()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the class name", err)
+		return
+	}
+	if p.Standard() {
+		err = binary.Read(h, binary.LittleEndian, &p.Width)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the width", err)
+			return
+		}
+		err = binary.Read(h, binary.LittleEndian, &p.Height)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the height", err)
+			return
+		}
+	} else {
+		err = binary.Read(h, binary.LittleEndian, &p.ClipboardFormat)
+		if err != nil {
+			err = fmt.Errorf("%w: getting the clipboard format", err)
+			return
+		}
+		if p.ClipboardFormat == 0 {
+			p.ClipboardFormatName, err = h.lengthPrefixedString()
+			if err != nil {
+				err = fmt.Errorf("%w: getting the clipboard format name", err)
+				return
+			}
+		}
+	}
+	var size uint32
+	err = binary.Read(h, binary.LittleEndian, &size)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the presentation data size", err)
+		return
+	}
+	p.Size = int64(size)
+	if p.Size > h.left() {
+		err = fmt.Errorf("presentation data size of %d overruns the stream (%d bytes left)", p.Size, h.left())
+		return
+	}
+	p.offset = h.n
+	return
+}
@@ -1,40 +1,149 @@
 package parsers
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"path"
+	"strings"
+	"unicode/utf16"
 )
 
-// OLE 1.0 reader. This implements io.Reader and looks a
-// little like other archive readers WRT representation
-// of metadata and accessing the enclosed object data.
+// Object types found in the Packager header.
+const (
+	// The object is a link to SourcePath, and carries no data.
+	Ole10Linked uint16 = 1
+
+	// The object carries a copy of the file.
+	Ole10Embedded uint16 = 3
+)
+
+// Kinds of OLE 1.0 object, named after the OLE 1.0
+// class which writes them.
+const (
+	// A file wrapped up by Packager, see Ole10.
+	Ole10KindPackage = "Package"
+
+	// A Paintbrush picture. The native data is a BMP file.
+	Ole10KindPaintbrush = "PBrush"
+
+	// An Equation Editor equation. The native data starts
+	// with an EQNOLEFILEHDR, followed by MTEF data.
+	Ole10KindEquation = "Equation.3"
+
+	// An MS Graph chart. The native data is a BIFF stream,
+	// starting with a BOF record, like an Excel chart.
+	Ole10KindGraph = "MSGraph"
+
+	// Native data we don't recognize.
+	Ole10KindUnknown = "Unknown"
+)
+
+// ErrNotSeekable is returned by ReadAt and Seek when the
+// Ole10 reader was created from a plain io.Reader.
+var ErrNotSeekable = errors.New("OLE 1.0 stream does not support random access")
+
+// Longest null-terminated string we'll read from a header. This keeps
+// a stream without nulls from being buffered whole.
+const maxOle10StringSize = 64 << 10
+
+// OLE 1.0 reader. This implements io.Reader, io.ReaderAt and
+// io.Seeker over the enclosed object, and looks a little like
+// other archive readers WRT representation of metadata and
+// accessing the enclosed object data.
+//
+// The stream holds a Packager object, laid out like so
+// (integers are little-endian):
+//
+//	uint32	TotalSize: size of the rest of the stream
+//	uint16	Flags: usually 2
+//	string	Label, null-terminated
+//	string	SourcePath, null-terminated
+//	uint16	Reserved: usually 0
+//	uint16	ObjectType: Ole10Linked or Ole10Embedded
+//	uint32	length of TempPath, including the null
+//	string	TempPath, null-terminated
+//	uint32	Size of the enclosed object
+//	[]byte	the enclosed object
+//	[]byte	trailing data, see Trailing
+//
+// Linked objects end after ObjectType: they point at SourcePath
+// rather than carrying a copy of it, so their Size is 0 and
+// anything after the header is trailing data.
+//
+// Other OLE 1.0 servers store their native data right after
+// TotalSize. For those, Kind says what the data looks like, Size
+// is TotalSize, Name comes from NativeDataName, and the enclosed
+// object is the native data.
 type Ole10 struct {
-	// Name of the enclosed file.
+	// What kind of object the stream holds, one of the Ole10Kind
+	// constants.
+	Kind string
+
+	// Size of the stream following the TotalSize field,
+	// as recorded in the header.
+	TotalSize int64
+
+	// The first word of the header. Packager writes 2 here.
+	Flags uint16
+
+	// Label shown for the object, usually the original file name.
+	Label string
+
+	// Name of the enclosed file. This is the last element of Label.
 	Name string
 
-	// Ostensibly, the temp path that this file was
-	// loaded in from by the packaging program. Docs
-	// are a little light on this part, so this could
-	// mean something different between implementations.
-	TempPath string
+	// The path the file was packaged from, on the
+	// system that created the object.
+	SourcePath string
 
-	// Ostensibly, the cache path on the original system.
-	// Docs are a little light on this part, so this could
-	// mean something different between implementations.
+	// Deprecated: CachePath is SourcePath, under the name it had before
+	// the header was fully decoded. It's set to the same value.
 	CachePath string
 
+	// Reserved. Packager writes 0 here.
+	Reserved uint16
+
+	// Ole10Linked or Ole10Embedded.
+	ObjectType uint16
+
+	// The temp path that this file was loaded in from by
+	// the packaging program.
+	TempPath string
+
 	// The size of the enclosed object.
 	Size int64
 
-	// This holds a pointer to our OLE 1.0 file buffer.
-	in *bytes.Buffer
+	// Office follows the enclosed object with Unicode copies of the
+	// temp path, label and source path. These are set by Trailing,
+	// if the trailing data has the expected layout.
+	UnicodeTempPath   string
+	UnicodeLabel      string
+	UnicodeSourcePath string
 
-	// This tracks our position reading the
-	// enclosed object from the OLE container.
+	// Number of trailing bytes left over after the Unicode fields. This
+	// is set by Trailing, and is normally zero.
+	UnknownTrailingSize int64
+
+	// When the OLE 1.0 stream supports random access, this is the
+	// stream, its size, and the enclosed object as a section of it,
+	// starting at offset.
+	ra     io.ReaderAt
+	size   int64
+	offset int64
+	object *io.SectionReader
+
+	// Otherwise, this is the rest of the stream, starting
+	// at the enclosed object.
+	in io.Reader
+
+	// This tracks our position reading the enclosed
+	// object from a stream without random access.
 	readPosition int64
 }
 
@@ -46,6 +155,9 @@ type Ole10 struct {
 // author's expectations and assumptions about how such a thing
 // is structured, an error may be returned.
 //
+// The buffer is not consumed. Use NewOle10ReaderAt to avoid reading
+// the whole stream into memory first.
+//
 //	Args:
 //		in (*bytes.Buffer):	This is the buffer containing OLE 1.0 file contents.
 //
@@ -53,14 +165,63 @@ type Ole10 struct {
 //		o (*Ole10):		Reader for the OLE 1.0 file data.
 //		err (error):	Failure to parse metadata from the file will cause this to be non-nil.
 func NewOle10(in *bytes.Buffer) (o *Ole10, err error) {
-	o = &Ole10{in: in}
-	err = o.parse()
+	o, err = NewOle10ReaderAt(bytes.NewReader(in.Bytes()), int64(in.Len()))
+	return
+}
+
+// Create a new OLE 1.0 reader over a stream with random access.
+// Only the header is read up front; the enclosed object is read
+// from the stream as it is requested.
+//
+//	Args:
+//		in (io.ReaderAt):	The OLE 1.0 file contents.
+//		size (int64):		Size of the OLE 1.0 file contents.
+//
+//	Returns:
+//		o (*Ole10):		Reader for the OLE 1.0 file data.
+//		err (error):	Failure to parse metadata from the file will cause this to be non-nil.
+func NewOle10ReaderAt(in io.ReaderAt, size int64) (o *Ole10, err error) {
+	o = &Ole10{ra: in, size: size}
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(in, 0, size)), size: size}
+	err = o.parse(h)
+	if err != nil {
+		return
+	}
+	o.offset = h.n
+	o.object = io.NewSectionReader(in, o.offset, o.Size)
+	return
+}
+
+// Create a new OLE 1.0 reader over a stream without random access.
+// The enclosed object can only be read once, in order: ReadAt and
+// Seek return ErrNotSeekable. Since the size of the stream isn't
+// known, a stream which ends early is only detected when reading
+// the enclosed object, which returns io.ErrUnexpectedEOF.
+//
+//	Args:
+//		in (io.Reader):	The OLE 1.0 file contents.
+//
+//	Returns:
+//		o (*Ole10):		Reader for the OLE 1.0 file data.
+//		err (error):	Failure to parse metadata from the file will cause this to be non-nil.
+func NewOle10Reader(in io.Reader) (o *Ole10, err error) {
+	o = &Ole10{size: -1}
+	h := &headerReader{r: bufio.NewReader(in), size: -1}
+	err = o.parse(h)
+	if err != nil {
+		return
+	}
+	o.in = h.r
 	return
 }
 
 // Read implements the io.Reader interface, taking care
 // not to overrun the body of the enclosed object.
 func (o *Ole10) Read(p []byte) (n int, err error) {
+	if o.object != nil {
+		n, err = o.object.Read(p)
+		return
+	}
 	lenRemains := o.Size - o.readPosition
 	// If len(p) is 0, return 0/nil ()
 	if len(p) == 0 {
@@ -74,107 +235,325 @@ func (o *Ole10) Read(p []byte) (n int, err error) {
 		return
 	}
 	// If p asks for more bytes than remain in the embedded
-	// file, we use a LimitReader to stop before overrunning
-	// the payload.
-	if len(p) > int(lenRemains) {
-		r := io.LimitReader(o.in, lenRemains)
-		n, err = r.Read(p)
-		o.readPosition += int64(n)
-		return
+	// file, only ask for what remains.
+	if int64(len(p)) > lenRemains {
+		p = p[:lenRemains]
 	}
-	// If p does not ask for more bytes than remain,
-	// just use Read.
 	n, err = o.in.Read(p)
 	o.readPosition += int64(n)
+	// The stream ending before the object does is an error.
+	if err == io.EOF && o.readPosition < o.Size {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// ReadAt implements the io.ReaderAt interface over the enclosed object.
+func (o *Ole10) ReadAt(p []byte, off int64) (n int, err error) {
+	if o.object == nil {
+		err = ErrNotSeekable
+		return
+	}
+	n, err = o.object.ReadAt(p, off)
+	return
+}
+
+// Seek implements the io.Seeker interface over the enclosed object.
+func (o *Ole10) Seek(offset int64, whence int) (n int64, err error) {
+	if o.object == nil {
+		err = ErrNotSeekable
+		return
+	}
+	n, err = o.object.Seek(offset, whence)
+	return
+}
+
+// Trailing returns a reader over everything in the OLE 1.0 stream after
+// the enclosed object, so it can be copied out without being held in
+// memory. The Unicode fields Office writes after the object are decoded
+// first, if they are present; only they are read up front.
+//
+// On a stream without random access, any part of the enclosed object
+// which hasn't been read yet is skipped, so call this once done reading.
+// The size of the trailing data isn't known then: size is -1, and
+// UnknownTrailingSize only counts what has been read from trailing.
+//
+//	Returns:
+//		trailing (io.Reader):	The data after the enclosed object. This is an *io.SectionReader if the stream supports random access.
+//		size (int64):		Size of the trailing data, or -1 if it isn't known.
+//		err (error):	Non-nil if the stream ends before the enclosed object does.
+func (o *Ole10) Trailing() (trailing io.Reader, size int64, err error) {
+	if o.object != nil {
+		start := o.offset + o.Size
+		size = o.size - start
+		fieldsSize := o.parseTrailing(io.NewSectionReader(o.ra, start, size), size)
+		o.UnknownTrailingSize = size - fieldsSize
+		trailing = io.NewSectionReader(o.ra, start, size)
+		return
+	}
+	if remains := o.Size - o.readPosition; remains > 0 {
+		var skipped int64
+		skipped, err = io.CopyN(io.Discard, o, remains)
+		if err != nil {
+			err = fmt.Errorf("%w: skipping the rest of the enclosed object (%d of %d bytes)", err, skipped, remains)
+			return
+		}
+	}
+	// Whatever was read looking for the Unicode fields is handed back
+	// ahead of the rest of the stream.
+	read := &bytes.Buffer{}
+	fieldsSize := o.parseTrailing(io.TeeReader(o.in, read), -1)
+	o.UnknownTrailingSize = int64(read.Len()) - fieldsSize
+	trailing = io.MultiReader(read, &unknownTrailingCounter{r: o.in, o: o})
+	size = -1
+	return
+}
+
+// unknownTrailingCounter adds whatever is read past the Unicode fields
+// to UnknownTrailingSize.
+type unknownTrailingCounter struct {
+	r io.Reader
+	o *Ole10
+}
+
+func (c *unknownTrailingCounter) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.o.UnknownTrailingSize += int64(n)
+	return
+}
+
+// Decode the Unicode temp path, label and source path from the start of
+// the trailing data. Each is a little-endian uint32 count of UTF-16 code
+// units, followed by the code units. If the data doesn't fit that layout,
+// the fields are left empty. size is the size of the trailing data, or -1
+// if it isn't known. Returns the number of bytes the fields take up.
+func (o *Ole10) parseTrailing(r io.Reader, size int64) (fieldsSize int64) {
+	if o.Kind != Ole10KindPackage {
+		return
+	}
+	fields := [3]string{}
+	for i := range fields {
+		var count uint32
+		if binary.Read(r, binary.LittleEndian, &count) != nil {
+			return 0
+		}
+		fieldsSize += 4
+		length := int64(count) * 2
+		if length > maxOle10StringSize || (size >= 0 && fieldsSize+length > size) {
+			return 0
+		}
+		units := make([]uint16, count)
+		if binary.Read(r, binary.LittleEndian, units) != nil {
+			return 0
+		}
+		fieldsSize += length
+		fields[i] = strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	}
+	o.UnicodeTempPath, o.UnicodeLabel, o.UnicodeSourcePath = fields[0], fields[1], fields[2]
 	return
 }
 
 // Parse and set the metadata from the OLE 1.0 object
-// and advance the buffer to prepare for extraction.
-func (o *Ole10) parse() (err error) {
-	// Advance past the first header
-	var firstHeader struct {
-		Whatever [6]byte
+// and advance the header reader to the enclosed object.
+// Every length is checked against what is left of the
+// stream before it is trusted, when the stream's size is known.
+func (o *Ole10) parse(h *headerReader) (err error) {
+	var totalSize uint32
+	err = binary.Read(h, binary.LittleEndian, &totalSize)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the total size", err)
+		return
 	}
-	err = binary.Read(o.in, binary.LittleEndian, &firstHeader)
+	o.TotalSize = int64(totalSize)
+	if o.TotalSize > h.left() {
+		err = fmt.Errorf("total size of %d overruns the stream (%d bytes left)", o.TotalSize, h.left())
+		return
+	}
+
+	// Only Packager objects carry a header. Anything else is the
+	// native data of some other OLE 1.0 server, stored as is.
+	peek, _ := h.r.Peek(6)
+	o.Kind = sniffOle10Kind(peek)
+	if o.Kind != Ole10KindPackage {
+		o.Size = o.TotalSize
+		o.Name = NativeDataName(o.Kind)
+		return
+	}
+
+	err = binary.Read(h, binary.LittleEndian, &o.Flags)
 	if err != nil {
-		err = fmt.Errorf("%w: taking the first header", err)
+		err = fmt.Errorf("%w: getting the flags", err)
 		return
 	}
 
-	// The next section before null is the member name.
-	name := bytes.NewBufferString("")
-	err = advanceBufferToNextNull(o.in, name)
+	o.Label, err = h.string()
 	if err != nil {
-		err = fmt.Errorf("%w: Getting the member name", err)
+		err = fmt.Errorf("%w: getting the label", err)
 		return
 	}
 	// Make sure that if any path elements are squeezed into this field,
 	// we sanitize them out.
-	o.Name = path.Base(name.String())
+	o.Name = path.Base(o.Label)
 
-	// The next section is the original object path. This is sometimes interesting,
-	// but not for this particular use case.
-	cachePath := bytes.NewBufferString("")
-	err = advanceBufferToNextNull(o.in, cachePath)
+	o.SourcePath, err = h.string()
 	if err != nil {
-		err = fmt.Errorf("%w: getting the original path of the object", err)
+		err = fmt.Errorf("%w: getting the source path", err)
 		return
 	}
-	o.CachePath = cachePath.String()
+	o.CachePath = o.SourcePath
 
-	// Advance the stream a couple of null-terminated sections
-	err = advanceBufferToNextNull(o.in, nil)
+	err = binary.Read(h, binary.LittleEndian, &o.Reserved)
 	if err != nil {
-		err = fmt.Errorf("%w: skipping to null (1)", err)
+		err = fmt.Errorf("%w: getting the reserved field", err)
 		return
 	}
-	err = advanceBufferToNextNull(o.in, nil)
+	err = binary.Read(h, binary.LittleEndian, &o.ObjectType)
 	if err != nil {
-		err = fmt.Errorf("%w: skipping to null (2)", err)
+		err = fmt.Errorf("%w: getting the object type", err)
 		return
 	}
-
-	// We find the second 6-byte header. Get it and skip it.
-	var secondHeader struct {
-		Whatever [6]byte
+	if o.ObjectType == Ole10Linked {
+		return
 	}
-	err = binary.Read(o.in, binary.LittleEndian, &secondHeader)
-	if err != nil {
-		err = fmt.Errorf("%w: taking the second header", err)
+	if o.ObjectType != Ole10Embedded {
+		err = fmt.Errorf("unsupported object type %d, expected linked (%d) or embedded (%d)", o.ObjectType, Ole10Linked, Ole10Embedded)
 		return
 	}
 
-	// The next null-terminated section is the shortened temp file name
-	tempPath := bytes.NewBufferString("")
-	err = advanceBufferToNextNull(o.in, tempPath)
+	var tempPathSize uint32
+	err = binary.Read(h, binary.LittleEndian, &tempPathSize)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the temp path size", err)
+		return
+	}
+	if int64(tempPathSize) > h.left() || tempPathSize > maxOle10StringSize {
+		err = fmt.Errorf("temp path size of %d overruns the stream (%d bytes left)", tempPathSize, h.left())
+		return
+	}
+	tempPath := make([]byte, tempPathSize)
+	_, err = io.ReadFull(h, tempPath)
 	if err != nil {
-		err = fmt.Errorf("%w: skipping to null (3 - shortened file name)", err)
+		err = fmt.Errorf("%w: getting the temp path", err)
 		return
 	}
-	o.TempPath = tempPath.String()
+	o.TempPath = strings.TrimRight(string(tempPath), "\x00")
 
-	// Get the next section ahead of the object itself out of the way.
 	var size uint32
-	err = binary.Read(o.in, binary.LittleEndian, &size)
+	err = binary.Read(h, binary.LittleEndian, &size)
 	if err != nil {
 		err = fmt.Errorf("%w: getting the enclosed object size", err)
 		slog.Error("getting object size", "error", err.Error())
 		return
 	}
 	o.Size = int64(size)
+	if o.Size > h.left() {
+		err = fmt.Errorf("enclosed object size of %d overruns the stream (%d bytes left)", o.Size, h.left())
+		return
+	}
+	return
+}
+
+// Work out what kind of object the native data at
+// the start of peek belongs to.
+func sniffOle10Kind(peek []byte) (kind string) {
+	switch {
+	case bytes.HasPrefix(peek, []byte{0x02, 0x00}):
+		kind = Ole10KindPackage
+	case bytes.HasPrefix(peek, []byte("BM")):
+		kind = Ole10KindPaintbrush
+	// cbHdr is 28, and the version 0x00020000.
+	case bytes.HasPrefix(peek, []byte{0x1c, 0x00, 0x00, 0x00, 0x02, 0x00}):
+		kind = Ole10KindEquation
+	// A BOF record of any BIFF version, 2 to 8.
+	case len(peek) >= 2 && peek[0] == 0x09 && (peek[1] == 0x00 || peek[1] == 0x02 || peek[1] == 0x04 || peek[1] == 0x08):
+		kind = Ole10KindGraph
+	default:
+		kind = Ole10KindUnknown
+	}
+	return
+}
+
+// NativeDataName returns the member name used for
+// the native data of an OLE 1.0 object of the kind.
+func NativeDataName(kind string) (name string) {
+	switch kind {
+	case Ole10KindPaintbrush:
+		name = "native.bmp"
+	case Ole10KindEquation:
+		name = "native.eqn"
+	case Ole10KindGraph:
+		name = "native.biff"
+	default:
+		name = "native.bin"
+	}
 	return
 }
 
-// Advance the buffer to the next null. If w is provided, it will receive the
-// contents of the buffer read until null was reached.
-func advanceBufferToNextNull(r, w *bytes.Buffer) (err error) {
-	result, err := r.ReadBytes('\x00')
+// headerReader reads the Packager header, keeping count of
+// the bytes read so lengths can be checked against the stream.
+type headerReader struct {
+	r *bufio.Reader
+
+	// Bytes read so far, and the size of the stream.
+	// size is -1 when it isn't known.
+	n, size int64
+}
+
+func (h *headerReader) Read(p []byte) (n int, err error) {
+	n, err = h.r.Read(p)
+	h.n += int64(n)
+	return
+}
+
+// left returns how much of the stream is left to read.
+// If the size isn't known, there is no limit.
+func (h *headerReader) left() int64 {
+	if h.size < 0 {
+		return math.MaxInt64
+	}
+	return h.size - h.n
+}
+
+// string reads a null-terminated string.
+func (h *headerReader) string() (s string, err error) {
+	buf := []byte{}
+	for {
+		var c byte
+		c, err = h.r.ReadByte()
+		if err != nil {
+			return
+		}
+		h.n++
+		if c == 0 {
+			break
+		}
+		if len(buf) >= maxOle10StringSize {
+			err = fmt.Errorf("string is longer than %d bytes", maxOle10StringSize)
+			return
+		}
+		buf = append(buf, c)
+	}
+	s = string(buf)
+	return
+}
+
+// lengthPrefixedString reads a string prefixed with its uint32
+// length, which includes the terminating null.
+func (h *headerReader) lengthPrefixedString() (s string, err error) {
+	var length uint32
+	err = binary.Read(h, binary.LittleEndian, &length)
 	if err != nil {
 		return
 	}
-	if w != nil {
-		_, err = w.Write(result[:len(result)-1])
+	s, err = h.stringOfLength(length, false)
+	return
+}
+
+// skip discards n bytes.
+func (h *headerReader) skip(n int64) (err error) {
+	skipped, err := io.CopyN(io.Discard, h, n)
+	if err == io.EOF && skipped < n {
+		err = io.ErrUnexpectedEOF
 	}
 	return
 }
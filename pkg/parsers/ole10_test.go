@@ -3,6 +3,7 @@ package parsers
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
@@ -49,6 +50,9 @@ func TestOle10E2E(t *testing.T) {
 	if o.CachePath != expectedCachePath {
 		t.Errorf("cache path mismatch - expected %s got %s", expectedCachePath, o.CachePath)
 	}
+	if o.SourcePath != o.CachePath {
+		t.Errorf("source path mismatch - expected %s got %s", o.CachePath, o.SourcePath)
+	}
 	if o.TempPath != expectedTempPath {
 		t.Errorf("temp path mismatch - expected %s got %s", expectedTempPath, o.TempPath)
 	}
@@ -73,3 +77,302 @@ func TestOle10E2E(t *testing.T) {
 		t.Errorf("mismatch between header-indicated size and actual size - got %d expected %d", len(embedded), o.Size)
 	}
 }
+
+// The Unicode fields after the payload are decoded, and nothing else is left over.
+func TestOle10Trailing(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOle10(bytes.NewBuffer(fc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Don't read the payload first, Trailing should skip it.
+	trailing, size, err := o.Trailing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := trailing.(*io.SectionReader); !ok || size != 474 {
+		t.Errorf("expected a section of 474 bytes, got %T of %d bytes", trailing, size)
+	}
+	data, err := io.ReadAll(trailing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, fc[len(fc)-474:]) {
+		t.Errorf("trailing data does not match the end of the stream")
+	}
+	if o.UnicodeLabel != o.Name {
+		t.Errorf("unicode label mismatch - expected %s got %s", o.Name, o.UnicodeLabel)
+	}
+	if o.UnicodeTempPath != o.TempPath {
+		t.Errorf("unicode temp path mismatch - expected %s got %s", o.TempPath, o.UnicodeTempPath)
+	}
+	if o.UnicodeSourcePath != o.SourcePath {
+		t.Errorf("unicode source path mismatch - expected %s got %s", o.SourcePath, o.UnicodeSourcePath)
+	}
+	if o.UnknownTrailingSize != 0 {
+		t.Errorf("expected no unknown trailing data, got %d bytes", o.UnknownTrailingSize)
+	}
+}
+
+// Trailing data is only read as it's asked for, past the Unicode fields.
+func TestOle10TrailingStreamed(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	junk := bytes.Repeat([]byte{0xff}, 4<<20)
+	stream := &countingReaderAt{r: bytes.NewReader(append(fc, junk...))}
+	o, err := NewOle10ReaderAt(stream, int64(len(fc)+len(junk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, size, err := o.Trailing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(474+len(junk)) || o.UnknownTrailingSize != int64(len(junk)) || o.UnicodeLabel != o.Name {
+		t.Errorf("trailing data mismatch - %d bytes, %d unknown, label %q", size, o.UnknownTrailingSize, o.UnicodeLabel)
+	}
+	if stream.n > 1<<20 {
+		t.Errorf("expected the trailing data not to be read up front, %d bytes were read", stream.n)
+	}
+
+	// Without random access, the unknown bytes are counted as they're read.
+	o, err = NewOle10Reader(bytes.NewReader(append(fc, junk...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trailing, size, err := o.Trailing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != -1 || o.UnknownTrailingSize != 0 {
+		t.Errorf("expected an unknown size with nothing read yet, got %d bytes, %d unknown", size, o.UnknownTrailingSize)
+	}
+	n, err := io.Copy(io.Discard, trailing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(474+len(junk)) || o.UnknownTrailingSize != int64(len(junk)) {
+		t.Errorf("trailing data mismatch - %d bytes, %d unknown", n, o.UnknownTrailingSize)
+	}
+}
+
+// countingReaderAt counts the bytes read through it.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	n, err = c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return
+}
+
+// Every field of the Packager header is decoded.
+func TestOle10Header(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOle10(bytes.NewBuffer(fc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.TotalSize != int64(len(fc))-4 {
+		t.Errorf("total size mismatch - expected %d got %d", len(fc)-4, o.TotalSize)
+	}
+	if o.Kind != Ole10KindPackage {
+		t.Errorf("kind mismatch - expected %s got %s", Ole10KindPackage, o.Kind)
+	}
+	if o.Flags != 2 {
+		t.Errorf("flags mismatch - expected 2 got %d", o.Flags)
+	}
+	if o.Label != "Untitled.msg" {
+		t.Errorf("label mismatch - expected Untitled.msg got %s", o.Label)
+	}
+	if o.Reserved != 0 {
+		t.Errorf("reserved mismatch - expected 0 got %d", o.Reserved)
+	}
+	if o.ObjectType != Ole10Embedded {
+		t.Errorf("object type mismatch - expected %d got %d", Ole10Embedded, o.ObjectType)
+	}
+	if o.Size != 24064 {
+		t.Errorf("size mismatch - expected 24064 got %d", o.Size)
+	}
+}
+
+// A linked object's header is decoded, and there's no object to read.
+func TestOle10Linked(t *testing.T) {
+	header := &bytes.Buffer{}
+	binary.Write(header, binary.LittleEndian, uint16(2))
+	header.WriteString("report.pdf\x00")
+	header.WriteString(`\\fileserver\share\report.pdf` + "\x00")
+	binary.Write(header, binary.LittleEndian, []uint16{0, Ole10Linked})
+	fc := binary.LittleEndian.AppendUint32(nil, uint32(header.Len()))
+	fc = append(fc, header.Bytes()...)
+
+	o, err := NewOle10ReaderAt(bytes.NewReader(fc), int64(len(fc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ObjectType != Ole10Linked || o.Label != "report.pdf" || o.SourcePath != `\\fileserver\share\report.pdf` || o.Size != 0 {
+		t.Errorf("header mismatch: %+v", o)
+	}
+	if data, err := io.ReadAll(o); err != nil || len(data) != 0 {
+		t.Errorf("expected no object data, got %d bytes and %v", len(data), err)
+	}
+
+	// Other object types are still rejected.
+	binary.LittleEndian.PutUint16(fc[len(fc)-2:], 2)
+	if _, err = NewOle10ReaderAt(bytes.NewReader(fc), int64(len(fc))); err == nil {
+		t.Errorf("expected an error for an unknown object type")
+	}
+}
+
+// Lengths which overrun the stream are rejected, wherever the stream ends.
+func TestOle10Truncated(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewOle10(bytes.NewBuffer(fc[:len(fc)-1])); err == nil {
+		t.Errorf("expected an error for a total size past the end of the stream")
+	}
+	// Cut in the total size, label, source path, temp path and object,
+	// fixing up the total size so the later checks are reached.
+	for _, n := range []int{2, 10, 40, 200, 1000} {
+		cut := bytes.Clone(fc[:n])
+		if n >= 4 {
+			binary.LittleEndian.PutUint32(cut, uint32(n-4))
+		}
+		if _, err = NewOle10(bytes.NewBuffer(cut)); err == nil {
+			t.Errorf("expected an error parsing the first %d bytes", n)
+		}
+	}
+	// A temp path length running off the end of the stream.
+	bad := bytes.Clone(fc)
+	bad[0x72] = 0xff
+	bad[0x74] = 0xff
+	if _, err = NewOle10(bytes.NewBuffer(bad)); err == nil {
+		t.Errorf("expected an error for an oversized temp path")
+	}
+}
+
+// A plain io.Reader gives the same object, but no random access.
+func TestOle10Reader(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	control, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOle10Reader(bytes.NewReader(fc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = o.ReadAt(make([]byte, 1), 0); err != ErrNotSeekable {
+		t.Errorf("expected ErrNotSeekable from ReadAt, got %v", err)
+	}
+	embedded, err := io.ReadAll(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(embedded, control) {
+		t.Errorf("embedded object does not match sample1.msg")
+	}
+	trailing, size, err := o.Trailing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != -1 || o.UnicodeLabel != o.Name {
+		t.Errorf("trailing data mismatch - got size %d, label %q", size, o.UnicodeLabel)
+	}
+	data, err := io.ReadAll(trailing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, fc[len(fc)-474:]) || o.UnknownTrailingSize != 0 {
+		t.Errorf("trailing data mismatch - got %d bytes, %d unknown", len(data), o.UnknownTrailingSize)
+	}
+
+	// A stream ending inside the object is reported when reading it.
+	o, err = NewOle10Reader(bytes.NewReader(fc[:1000]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.ReadAll(o); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF reading a truncated object, got %v", err)
+	}
+}
+
+// With an io.ReaderAt, the object can be read at random.
+func TestOle10ReaderAt(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	control, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOle10ReaderAt(bytes.NewReader(fc), int64(len(fc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 16)
+	if _, err = o.ReadAt(p, 512); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, control[512:528]) {
+		t.Errorf("ReadAt mismatch at offset 512")
+	}
+	end, err := o.Seek(-16, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end != o.Size-16 {
+		t.Errorf("seek mismatch - expected %d got %d", o.Size-16, end)
+	}
+	rest, err := io.ReadAll(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rest, control[len(control)-16:]) {
+		t.Errorf("read after seek mismatch")
+	}
+	if _, err = o.ReadAt(p, o.Size); err != io.EOF {
+		t.Errorf("expected io.EOF reading past the object, got %v", err)
+	}
+}
+
+// The native data of each kind of object is told apart, and named for it.
+func TestSniffOle10Kind(t *testing.T) {
+	cases := []struct {
+		peek       []byte
+		kind, name string
+	}{
+		{[]byte{0x02, 0x00, 'a', 0x00}, Ole10KindPackage, ""},
+		{[]byte("BM\x1e\x00\x00\x00"), Ole10KindPaintbrush, "native.bmp"},
+		{[]byte{0x1c, 0x00, 0x00, 0x00, 0x02, 0x00}, Ole10KindEquation, "native.eqn"},
+		{[]byte{0x09, 0x08, 0x10, 0x00, 0x00, 0x05}, Ole10KindGraph, "native.biff"},
+		{[]byte{0x09, 0x02, 0x06, 0x00, 0x00, 0x00}, Ole10KindGraph, "native.biff"},
+		{[]byte{0x09, 0x01, 0x06, 0x00}, Ole10KindUnknown, "native.bin"},
+		{nil, Ole10KindUnknown, "native.bin"},
+	}
+	for _, c := range cases {
+		kind := sniffOle10Kind(c.peek)
+		if kind != c.kind {
+			t.Errorf("sniffOle10Kind(% x) mismatch - expected %s got %s", c.peek, c.kind, kind)
+		}
+		if c.name != "" && NativeDataName(kind) != c.name {
+			t.Errorf("NativeDataName(%s) mismatch - expected %s got %s", kind, c.name, NativeDataName(kind))
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrOle10WriteTooLong is returned when more than Ole10Header.Size
+// bytes are written to an Ole10Writer.
+var ErrOle10WriteTooLong = errors.New("write past the size of the OLE 1.0 object")
+
+// Ole10Header describes a Packager object for NewOle10Writer. The
+// fields mirror those of Ole10; see there for the layout.
+type Ole10Header struct {
+	// Defaults to 2 when zero.
+	Flags uint16
+
+	Label      string
+	SourcePath string
+
+	// Written as is, Packager uses 0.
+	Reserved uint16
+
+	// Defaults to Ole10Embedded when zero. Only embedded
+	// objects can be written.
+	ObjectType uint16
+
+	TempPath string
+
+	// The size of the enclosed object. Exactly this many
+	// bytes must be written before closing the writer.
+	Size int64
+
+	// Unicode copies of the paths and label, written after the object
+	// like Office does. Empty fields default to the ANSI ones.
+	UnicodeTempPath   string
+	UnicodeLabel      string
+	UnicodeSourcePath string
+
+	// Leave out the Unicode copies, and end the stream with the object.
+	NoUnicode bool
+}
+
+// Header returns the header of a parsed OLE 1.0 object, which can be
+// handed to NewOle10Writer to package the same object again. The
+// Unicode fields are set by Trailing, so call that first to keep them.
+func (o *Ole10) Header() (hdr Ole10Header) {
+	hdr = Ole10Header{
+		Flags:             o.Flags,
+		Label:             o.Label,
+		SourcePath:        o.SourcePath,
+		Reserved:          o.Reserved,
+		ObjectType:        o.ObjectType,
+		TempPath:          o.TempPath,
+		Size:              o.Size,
+		UnicodeTempPath:   o.UnicodeTempPath,
+		UnicodeLabel:      o.UnicodeLabel,
+		UnicodeSourcePath: o.UnicodeSourcePath,
+	}
+	return
+}
+
+// OLE 1.0 writer. This writes an Ole10Native stream holding a single
+// Packager object: the header is written when the writer is created,
+// the enclosed object through Write, and the trailing Unicode fields
+// on Close.
+type Ole10Writer struct {
+	w   io.Writer
+	hdr Ole10Header
+
+	// Encoded Unicode fields, written on Close.
+	trailer []byte
+
+	// Bytes of the enclosed object written so far.
+	written int64
+	closed  bool
+}
+
+// Create a new OLE 1.0 writer, and write the header to w.
+//
+//	Args:
+//		w (io.Writer):		Receives the Ole10Native stream.
+//		hdr (Ole10Header):	Describes the object.
+//
+//	Returns:
+//		ow (*Ole10Writer):	Writer for the enclosed object.
+//		err (error):	Non-nil if the header can't be encoded, or writing it fails.
+func NewOle10Writer(w io.Writer, hdr Ole10Header) (ow *Ole10Writer, err error) {
+	if hdr.Flags == 0 {
+		hdr.Flags = 2
+	}
+	if hdr.ObjectType == 0 {
+		hdr.ObjectType = Ole10Embedded
+	}
+	if hdr.ObjectType != Ole10Embedded {
+		err = fmt.Errorf("unsupported object type %d, only embedded (%d) objects can be written", hdr.ObjectType, Ole10Embedded)
+		return
+	}
+	for _, field := range []struct{ name, value string }{
+		{"label", hdr.Label},
+		{"source path", hdr.SourcePath},
+		{"temp path", hdr.TempPath},
+	} {
+		if strings.ContainsRune(field.value, 0) {
+			err = fmt.Errorf("the %s contains a null", field.name)
+			return
+		}
+	}
+	if hdr.Size < 0 || hdr.Size > math.MaxUint32 {
+		err = fmt.Errorf("object size of %d doesn't fit in the header", hdr.Size)
+		return
+	}
+
+	ow = &Ole10Writer{w: w, hdr: hdr}
+	if !hdr.NoUnicode {
+		ow.trailer = encodeTrailing(
+			withDefault(hdr.UnicodeTempPath, hdr.TempPath),
+			withDefault(hdr.UnicodeLabel, hdr.Label),
+			withDefault(hdr.UnicodeSourcePath, hdr.SourcePath),
+		)
+	}
+
+	// Everything after the total size field.
+	header := &bytes.Buffer{}
+	binary.Write(header, binary.LittleEndian, hdr.Flags)
+	header.WriteString(hdr.Label + "\x00")
+	header.WriteString(hdr.SourcePath + "\x00")
+	binary.Write(header, binary.LittleEndian, hdr.Reserved)
+	binary.Write(header, binary.LittleEndian, hdr.ObjectType)
+	binary.Write(header, binary.LittleEndian, uint32(len(hdr.TempPath)+1))
+	header.WriteString(hdr.TempPath + "\x00")
+	binary.Write(header, binary.LittleEndian, uint32(hdr.Size))
+
+	totalSize := int64(header.Len()) + hdr.Size + int64(len(ow.trailer))
+	if totalSize > math.MaxUint32 {
+		err = fmt.Errorf("total size of %d doesn't fit in the header", totalSize)
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, uint32(totalSize))
+	if err != nil {
+		err = fmt.Errorf("%w: writing the total size", err)
+		return
+	}
+	_, err = header.WriteTo(w)
+	if err != nil {
+		err = fmt.Errorf("%w: writing the header", err)
+	}
+	return
+}
+
+// Write implements the io.Writer interface, writing the enclosed
+// object. Writing more than the header's Size returns ErrOle10WriteTooLong.
+func (ow *Ole10Writer) Write(p []byte) (n int, err error) {
+	if ow.closed {
+		err = errors.New("write to a closed OLE 1.0 writer")
+		return
+	}
+	if remains := ow.hdr.Size - ow.written; int64(len(p)) > remains {
+		p = p[:remains]
+		err = ErrOle10WriteTooLong
+	}
+	n, writeErr := ow.w.Write(p)
+	ow.written += int64(n)
+	if writeErr != nil {
+		err = writeErr
+	}
+	return
+}
+
+// Close writes the trailing Unicode fields. It fails if fewer than
+// the header's Size bytes were written. The underlying writer is
+// not closed.
+func (ow *Ole10Writer) Close() (err error) {
+	if ow.closed {
+		return
+	}
+	ow.closed = true
+	if ow.written != ow.hdr.Size {
+		err = fmt.Errorf("only %d of %d bytes of the enclosed object were written", ow.written, ow.hdr.Size)
+		return
+	}
+	_, err = ow.w.Write(ow.trailer)
+	if err != nil {
+		err = fmt.Errorf("%w: writing the trailing data", err)
+	}
+	return
+}
+
+// Encode the Unicode fields, in the layout parseTrailing reads.
+func encodeTrailing(fields ...string) (trailer []byte) {
+	buf := &bytes.Buffer{}
+	for _, field := range fields {
+		units := utf16.Encode([]rune(field))
+		binary.Write(buf, binary.LittleEndian, uint32(len(units)))
+		binary.Write(buf, binary.LittleEndian, units)
+	}
+	trailer = buf.Bytes()
+	return
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"testing"
+)
+
+// Parsing sample1.ole and writing it back gives the same bytes.
+func TestOle10WriterRoundTrip(t *testing.T) {
+	fc, err := os.ReadFile(path.Join(pathToSampleDataDir, "sample1.ole"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOle10(bytes.NewBuffer(fc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = o.Trailing(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &bytes.Buffer{}
+	w, err := NewOle10Writer(out, o.Header())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = o.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.Copy(w, o); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), fc) {
+		t.Errorf("round trip mismatch - expected %d bytes, got %d", len(fc), out.Len())
+	}
+}
+
+// A stream built from scratch parses back to what went in.
+func TestOle10Writer(t *testing.T) {
+	payload := []byte("hello, world")
+	out := &bytes.Buffer{}
+	w, err := NewOle10Writer(out, Ole10Header{
+		Label:      "hello.txt",
+		SourcePath: `C:\hello.txt`,
+		TempPath:   `C:\Temp\hello.txt`,
+		Size:       int64(len(payload)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(append(payload, '!')); err != ErrOle10WriteTooLong {
+		t.Errorf("expected ErrOle10WriteTooLong, got %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := NewOle10(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Name != "hello.txt" || o.SourcePath != `C:\hello.txt` || o.TempPath != `C:\Temp\hello.txt` || o.Flags != 2 {
+		t.Errorf("header mismatch: %+v", o)
+	}
+	embedded, err := io.ReadAll(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(embedded, payload) {
+		t.Errorf("payload mismatch - expected %q got %q", payload, embedded)
+	}
+	if _, _, err = o.Trailing(); err != nil {
+		t.Fatal(err)
+	}
+	if o.UnicodeLabel != "hello.txt" || o.UnknownTrailingSize != 0 {
+		t.Errorf("trailing data mismatch - label %q, %d unknown bytes", o.UnicodeLabel, o.UnknownTrailingSize)
+	}
+
+	// Closing before the whole object is written fails.
+	w, err = NewOle10Writer(io.Discard, Ole10Header{Label: "short", Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err == nil {
+		t.Errorf("expected an error closing a short object")
+	}
+}
@@ -0,0 +1,113 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// Build a serialized OLE 1.0 embedded object. A presentation is
+// added if presClass isn't empty.
+func buildOle1Object(class string, native []byte, presClass string, pres []byte) []byte {
+	buf := &bytes.Buffer{}
+	str := func(s string) {
+		if s == "" {
+			binary.Write(buf, binary.LittleEndian, uint32(0))
+			return
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(s)+1))
+		buf.WriteString(s + "\x00")
+	}
+	binary.Write(buf, binary.LittleEndian, Ole1Version)
+	binary.Write(buf, binary.LittleEndian, Ole1FormatEmbedded)
+	str(class)
+	str("")
+	str("")
+	binary.Write(buf, binary.LittleEndian, uint32(len(native)))
+	buf.Write(native)
+	if presClass != "" {
+		binary.Write(buf, binary.LittleEndian, Ole1Version)
+		binary.Write(buf, binary.LittleEndian, Ole1FormatPresentation)
+		str(presClass)
+		binary.Write(buf, binary.LittleEndian, uint32(320))
+		binary.Write(buf, binary.LittleEndian, int32(-240))
+		binary.Write(buf, binary.LittleEndian, uint32(len(pres)))
+		buf.Write(pres)
+	}
+	return buf.Bytes()
+}
+
+// The header, native data and presentation of an OLE 1.0 object are all found.
+func TestOle1Object(t *testing.T) {
+	native := []byte("BM not much of a bitmap")
+	pres := []byte("8 bytes!and then a metafile")
+	data := buildOle1Object("PBrush", native, Ole1PresentationMetafile, pres)
+	o, err := NewOle1Object(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.ClassName != "PBrush" || o.FormatID != Ole1FormatEmbedded || o.NativeSize != int64(len(native)) {
+		t.Errorf("header mismatch: %+v", o)
+	}
+	got, err := io.ReadAll(o.Native())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, native) {
+		t.Errorf("native data mismatch - expected %q got %q", native, got)
+	}
+	p := o.Presentation
+	if p == nil {
+		t.Fatal("expected a presentation")
+	}
+	if p.ClassName != Ole1PresentationMetafile || !p.Standard() || p.Width != 320 || p.Height != -240 {
+		t.Errorf("presentation mismatch: %+v", p)
+	}
+	got, err = io.ReadAll(p.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, pres) {
+		t.Errorf("presentation data mismatch - expected %q got %q", pres, got)
+	}
+
+	// Sizes running past the end of the object are rejected.
+	if _, err = NewOle1Object(bytes.NewReader(data[:30]), 30); err == nil {
+		t.Errorf("expected an error for truncated native data")
+	}
+	if _, err = NewOle1Object(bytes.NewReader(data[:len(data)-1]), int64(len(data)-1)); err == nil {
+		t.Errorf("expected an error for truncated presentation data")
+	}
+}
+
+// Ole10Native streams from servers other than Packager hold bare native data.
+func TestOle10NativeKinds(t *testing.T) {
+	cases := []struct {
+		native []byte
+		kind   string
+		name   string
+	}{
+		{[]byte("BM\x00\x00 a bitmap"), Ole10KindPaintbrush, "native.bmp"},
+		{[]byte{0x1c, 0x00, 0x00, 0x00, 0x02, 0x00, 0x9e, 0xc4}, Ole10KindEquation, "native.eqn"},
+		{[]byte("MS Graph data"), Ole10KindUnknown, "native.bin"},
+	}
+	for _, c := range cases {
+		stream := binary.LittleEndian.AppendUint32(nil, uint32(len(c.native)))
+		stream = append(stream, c.native...)
+		o, err := NewOle10(bytes.NewBuffer(stream))
+		if err != nil {
+			t.Fatalf("parsing %s: %s", c.kind, err)
+		}
+		if o.Kind != c.kind || o.Name != c.name {
+			t.Errorf("expected %s named %s, got %s named %s", c.kind, c.name, o.Kind, o.Name)
+		}
+		got, err := io.ReadAll(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, c.native) {
+			t.Errorf("native data mismatch for %s", c.kind)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package parsers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Standard clipboard formats presentation data is found in.
+const (
+	ClipboardFormatBitmap      uint32 = 2
+	ClipboardFormatMetafile    uint32 = 3
+	ClipboardFormatDIB         uint32 = 8
+	ClipboardFormatEnhMetafile uint32 = 14
+)
+
+// Presentation stream reader. The "\x02OlePres000" stream sits next to
+// an embedded object's data in an MS-CFB storage, and holds a picture of
+// the object, so it can be drawn without the application it belongs to.
+// This is the OLE 2.0 counterpart to Ole1Presentation. It is laid out
+// like so ([MS-OLEDS] 2.3.4, integers are little-endian):
+//
+//	format	ClipboardFormat or ClipboardFormatName, see CompObj
+//	uint32	TargetDeviceSize, 4 if there is no TargetDevice
+//	[]byte	TargetDevice, TargetDeviceSize - 4 bytes
+//	uint32	Aspect
+//	uint32	Lindex
+//	uint32	Advf
+//	uint32	reserved
+//	uint32	Width
+//	uint32	Height
+//	uint32	Size
+//	[]byte	the presentation data
+//
+// A table of contents may follow the data, which is ignored. Metafiles
+// are stored without the METAFILEPICT header OLE 1.0 gives them, and
+// DIBs without a BITMAPFILEHEADER.
+type OlePresentation struct {
+	// The clipboard format the data is in. This is a standard format
+	// (one of the ClipboardFormat constants), or the name of a
+	// registered one.
+	ClipboardFormat     uint32
+	ClipboardFormatName string
+
+	// Which view of the object this is: 1 for its content, 2 for a
+	// thumbnail, 4 for an icon and 8 for a printer rendering.
+	Aspect uint32
+
+	Width  uint32
+	Height uint32
+
+	// Size of the presentation data.
+	Size int64
+
+	ra     io.ReaderAt
+	offset int64
+}
+
+// Parse a presentation stream. The data is read from in when asked for.
+//
+//	Args:
+//		in (io.ReaderAt):	The presentation stream.
+//		size (int64):		Size of the stream.
+//
+//	Returns:
+//		p (*OlePresentation):	The parsed stream.
+//		err (error):	Non-nil if the stream can't be parsed.
+func NewOlePresentation(in io.ReaderAt, size int64) (p *OlePresentation, err error) {
+	p = &OlePresentation{ra: in}
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(in, 0, size)), size: size}
+	p.ClipboardFormat, p.ClipboardFormatName, err = h.clipboardFormat(false)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the clipboard format", err)
+		return
+	}
+	var targetDeviceSize uint32
+	err = binary.Read(h, binary.LittleEndian, &targetDeviceSize)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the target device size", err)
+		return
+	}
+	if targetDeviceSize < 4 || int64(targetDeviceSize)-4 > h.left() {
+		err = fmt.Errorf("target device size of %d is invalid (%d bytes left)", targetDeviceSize, h.left())
+		return
+	}
+	err = h.skip(int64(targetDeviceSize) - 4)
+	if err != nil {
+		err = fmt.Errorf("%w: skipping the target device", err)
+		return
+	}
+	var fields struct {
+		Aspect, Lindex, Advf, Reserved uint32
+		Width, Height, Size            uint32
+	}
+	err = binary.Read(h, binary.LittleEndian, &fields)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the presentation header", err)
+		return
+	}
+	p.Aspect, p.Width, p.Height, p.Size = fields.Aspect, fields.Width, fields.Height, int64(fields.Size)
+	if p.Size > h.left() {
+		err = fmt.Errorf("presentation data size of %d overruns the stream (%d bytes left)", p.Size, h.left())
+		return
+	}
+	p.offset = h.n
+	return
+}
+
+// Data returns the presentation data.
+func (p *OlePresentation) Data() *io.SectionReader {
+	return io.NewSectionReader(p.ra, p.offset, p.Size)
+}
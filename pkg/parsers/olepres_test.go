@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// Build a presentation stream in a standard clipboard format, with a
+// target device and a table of contents after the data.
+func buildOlePres(format uint32, data []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, []uint32{0xFFFFFFFF, format, 8, 0xAAAAAAAA})
+	binary.Write(buf, binary.LittleEndian, []uint32{1, 0xFFFFFFFF, 2, 0, 2540, 1270, uint32(len(data))})
+	buf.Write(data)
+	binary.Write(buf, binary.LittleEndian, []uint32{0x494E414E, 0})
+	return buf.Bytes()
+}
+
+func TestOlePresentation(t *testing.T) {
+	stream := buildOlePres(ClipboardFormatEnhMetafile, []byte("EMF data"))
+	p, err := NewOlePresentation(bytes.NewReader(stream), int64(len(stream)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ClipboardFormat != ClipboardFormatEnhMetafile || p.Aspect != 1 || p.Width != 2540 || p.Height != 1270 {
+		t.Errorf("header mismatch: %+v", p)
+	}
+	data, err := io.ReadAll(p.Data())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "EMF data" {
+		t.Errorf("data mismatch - expected %q got %q", "EMF data", data)
+	}
+}
+
+// Registered clipboard formats are named rather than numbered.
+func TestOlePresentationRegisteredFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint32(13))
+	buf.WriteString("Rich Text Fo\x00")
+	binary.Write(buf, binary.LittleEndian, []uint32{4, 1, 0xFFFFFFFF, 2, 0, 0, 0, 3})
+	buf.WriteString("{\\r")
+	p, err := NewOlePresentation(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ClipboardFormat != 0 || p.ClipboardFormatName != "Rich Text Fo" || p.Size != 3 {
+		t.Errorf("header mismatch: %+v", p)
+	}
+}
+
+// Sizes past the end of the stream are errors.
+func TestOlePresentationTruncated(t *testing.T) {
+	stream := buildOlePres(ClipboardFormatMetafile, []byte("WMF data"))
+	for _, size := range []int{12, 20, 40} {
+		if _, err := NewOlePresentation(bytes.NewReader(stream[:size]), int64(size)); err == nil {
+			t.Errorf("expected an error for a stream cut to %d bytes", size)
+		}
+	}
+	binary.LittleEndian.PutUint32(stream[8:], 0x10000)
+	if _, err := NewOlePresentation(bytes.NewReader(stream), int64(len(stream))); err == nil {
+		t.Errorf("expected an error for an oversized target device")
+	}
+}
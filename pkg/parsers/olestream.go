@@ -0,0 +1,150 @@
+package parsers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Set in OleStream.Flags for linked objects.
+const OleStreamLinked uint32 = 0x00000001
+
+// OLE stream reader. The "\x01Ole" stream sits next to an embedded or
+// linked object's data. For links, it holds the monikers which point
+// at the link source. It is laid out like so ([MS-OLEDS] 2.3.3,
+// integers are little-endian):
+//
+//	uint32	Version
+//	uint32	Flags: OleStreamLinked for links
+//	uint32	LinkUpdateOption
+//	uint32	reserved
+//	uint32	reserved moniker size, plus four, or 0
+//	[]byte	reserved moniker
+//
+// Linked objects follow with:
+//
+//	uint32	relative source moniker size, plus four, or 0
+//	[]byte	RelativeSource
+//	uint32	absolute source moniker size, plus four, or 0
+//	[]byte	AbsoluteSource
+//	int32	-1, if a CLSID follows
+//	[16]byte	CLSID of the link source
+//	ustring	DisplayName, uint32 length in UTF-16 code units, then the string
+//	...	reserved field and update times
+type OleStream struct {
+	Version          uint32
+	Flags            uint32
+	LinkUpdateOption uint32
+
+	// Linked objects only.
+	RelativeSource *Moniker
+	AbsoluteSource *Moniker
+	CLSID          string
+	DisplayName    string
+}
+
+// Linked returns true if the object is a link.
+func (o *OleStream) Linked() bool {
+	return o.Flags&OleStreamLinked != 0
+}
+
+// Target returns where a link points, from the absolute source
+// moniker or, failing that, the relative one.
+func (o *OleStream) Target() (target string) {
+	if o.AbsoluteSource != nil {
+		target = o.AbsoluteSource.String()
+	}
+	if target == "" && o.RelativeSource != nil {
+		target = o.RelativeSource.String()
+	}
+	return
+}
+
+// Parse an OLE stream.
+//
+//	Args:
+//		in (io.ReaderAt):	The OLE stream.
+//		size (int64):		Size of the stream.
+//
+//	Returns:
+//		o (*OleStream):	The parsed stream.
+//		err (error):	Non-nil if the stream can't be parsed.
+func NewOleStream(in io.ReaderAt, size int64) (o *OleStream, err error) {
+	o = &OleStream{}
+	h := &headerReader{r: bufio.NewReader(io.NewSectionReader(in, 0, size)), size: size}
+	var header struct {
+		Version, Flags, LinkUpdateOption, Reserved uint32
+	}
+	err = binary.Read(h, binary.LittleEndian, &header)
+	if err != nil {
+		err = fmt.Errorf("%w: getting the header", err)
+		return
+	}
+	o.Version, o.Flags, o.LinkUpdateOption = header.Version, header.Flags, header.LinkUpdateOption
+	_, err = h.monikerStream()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the reserved moniker", err)
+		return
+	}
+	if !o.Linked() {
+		return
+	}
+
+	o.RelativeSource, err = h.monikerStream()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the relative source moniker", err)
+		return
+	}
+	o.AbsoluteSource, err = h.monikerStream()
+	if err != nil {
+		err = fmt.Errorf("%w: getting the absolute source moniker", err)
+		return
+	}
+
+	// The rest is only nice to have.
+	var indicator int32
+	if binary.Read(h, binary.LittleEndian, &indicator) != nil || indicator != -1 {
+		return
+	}
+	clsid := make([]byte, 16)
+	if _, err = io.ReadFull(h, clsid); err != nil {
+		err = nil
+		return
+	}
+	o.CLSID = guidString(clsid)
+	o.DisplayName, _ = h.lengthPrefixedUnicodeString()
+	return
+}
+
+// Read a moniker stream prefixed with its size plus four. A size of 0
+// means there is no moniker.
+func (h *headerReader) monikerStream() (m *Moniker, err error) {
+	var size uint32
+	err = binary.Read(h, binary.LittleEndian, &size)
+	if err != nil || size == 0 {
+		return
+	}
+	if size < 4 || int64(size-4) > h.left() || size-4 > maxOle10StringSize {
+		err = fmt.Errorf("moniker size of %d overruns the stream (%d bytes left)", size, h.left())
+		return
+	}
+	data := make([]byte, size-4)
+	_, err = io.ReadFull(h, data)
+	if err != nil {
+		return
+	}
+	m, err = ParseMoniker(bytes.NewReader(data))
+	return
+}
+
+// Format a GUID in its usual text form. The first three
+// fields are stored little-endian, the rest as is.
+func guidString(b []byte) string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
@@ -0,0 +1,126 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+var (
+	fileMonikerCLSID      = []byte{0x03, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+	itemMonikerCLSID      = []byte{0x04, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+	compositeMonikerCLSID = []byte{0x09, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+	urlMonikerCLSID       = []byte{0xe0, 0xc9, 0xea, 0x79, 0xf9, 0xba, 0xce, 0x11, 0x8c, 0x82, 0x00, 0xaa, 0x00, 0x4b, 0xa9, 0x0b}
+)
+
+func utf16Bytes(s string) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, utf16.Encode([]rune(s)))
+	return buf.Bytes()
+}
+
+func urlMoniker(url string) []byte {
+	data := utf16Bytes(url + "\x00")
+	return append(append(append([]byte{}, urlMonikerCLSID...), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...), data...)
+}
+
+func fileMoniker(path string) []byte {
+	buf := bytes.NewBuffer(append([]byte{}, fileMonikerCLSID...))
+	binary.Write(buf, binary.LittleEndian, uint16(0))
+	binary.Write(buf, binary.LittleEndian, uint32(len(path)+1))
+	buf.WriteString(path + "\x00")
+	binary.Write(buf, binary.LittleEndian, []uint16{0xffff, 0xdead})
+	buf.Write(make([]byte, 20))
+	unicode := utf16Bytes(path)
+	binary.Write(buf, binary.LittleEndian, uint32(len(unicode)+6))
+	binary.Write(buf, binary.LittleEndian, uint32(len(unicode)))
+	binary.Write(buf, binary.LittleEndian, uint16(3))
+	buf.Write(unicode)
+	return buf.Bytes()
+}
+
+func itemMoniker(delimiter, item string) []byte {
+	buf := bytes.NewBuffer(append([]byte{}, itemMonikerCLSID...))
+	for _, s := range []string{delimiter, item} {
+		binary.Write(buf, binary.LittleEndian, uint32(len(s)+1))
+		buf.WriteString(s + "\x00")
+	}
+	return buf.Bytes()
+}
+
+// Build an Ole stream for a link, pointing at the moniker.
+func buildOleStream(moniker []byte) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, []uint32{0x02000001, OleStreamLinked, 1, 0, 0, 0})
+	binary.Write(buf, binary.LittleEndian, uint32(len(moniker)+4))
+	buf.Write(moniker)
+	binary.Write(buf, binary.LittleEndian, int32(-1))
+	buf.Write(make([]byte, 16))
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	return buf.Bytes()
+}
+
+// A link through a URL moniker, like the ones used to fetch remote content.
+func TestOleStreamURL(t *testing.T) {
+	data := buildOleStream(urlMoniker("http://example.com/template.hta"))
+	o, err := NewOleStream(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.Linked() {
+		t.Errorf("expected a linked object")
+	}
+	if o.AbsoluteSource == nil || o.AbsoluteSource.Kind != MonikerURL {
+		t.Fatalf("expected a URL moniker, got %+v", o.AbsoluteSource)
+	}
+	if target := o.Target(); target != "http://example.com/template.hta" {
+		t.Errorf("target mismatch - got %q", target)
+	}
+	if o.CLSID != "{00000000-0000-0000-0000-000000000000}" {
+		t.Errorf("CLSID mismatch - got %s", o.CLSID)
+	}
+}
+
+// A link to part of a file on a share, through a composite moniker.
+func TestOleStreamComposite(t *testing.T) {
+	composite := append([]byte{}, compositeMonikerCLSID...)
+	composite = binary.LittleEndian.AppendUint32(composite, 2)
+	composite = append(composite, fileMoniker(`\\attacker\share\book.xlsx`)...)
+	composite = append(composite, itemMoniker("!", "Sheet1!R1C1")...)
+	data := buildOleStream(composite)
+	o, err := NewOleStream(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := o.AbsoluteSource
+	if m == nil || m.Kind != MonikerComposite || len(m.Monikers) != 2 {
+		t.Fatalf("expected a composite of two monikers, got %+v", m)
+	}
+	if m.Monikers[0].Kind != MonikerFile || m.Monikers[1].Kind != MonikerItem {
+		t.Errorf("expected a file and an item moniker, got %s and %s", m.Monikers[0].Kind, m.Monikers[1].Kind)
+	}
+	if target := o.Target(); target != `\\attacker\share\book.xlsx!Sheet1!R1C1` {
+		t.Errorf("target mismatch - got %q", target)
+	}
+}
+
+// Embedded objects have an Ole stream too, with no monikers.
+func TestOleStreamEmbedded(t *testing.T) {
+	data := make([]byte, 20)
+	binary.LittleEndian.PutUint32(data, 0x02000001)
+	o, err := NewOleStream(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Linked() || o.Target() != "" {
+		t.Errorf("expected an embedded object, got %+v", o)
+	}
+
+	// A moniker running off the end is an error.
+	data = buildOleStream(urlMoniker("http://example.com/"))
+	data = data[:40]
+	if _, err = NewOleStream(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Errorf("expected an error for a truncated moniker")
+	}
+}
@@ -0,0 +1,144 @@
+package parsers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// A relationship from a part of an Open Packaging Conventions (OPC)
+// package, like an OOXML document, to another part or an external
+// resource ([ECMA-376] Part 2, 9.3).
+type Relationship struct {
+	ID   string `xml:"Id,attr"`
+	Type string `xml:"Type,attr"`
+
+	// The target, relative to the source part's folder, unless
+	// TargetMode is "External".
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// Parse a relationships part, like _rels/.rels or
+// word/_rels/document.xml.rels.
+//
+//	Args:
+//		data ([]byte):	The relationships part.
+//
+//	Returns:
+//		rels ([]*Relationship):	The relationships, in the order listed.
+//		err (error):		Non-nil if the part can't be parsed.
+func NewRelationships(data []byte) (rels []*Relationship, err error) {
+	parsed := &struct {
+		XMLName       xml.Name
+		Relationships []*Relationship `xml:"Relationship"`
+	}{}
+	err = xml.Unmarshal(data, parsed)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing relationships", err)
+		return
+	}
+	if parsed.XMLName.Local != "Relationships" {
+		err = fmt.Errorf("relationships part has a root element of %s", parsed.XMLName.Local)
+		return
+	}
+	rels = parsed.Relationships
+	return
+}
+
+// RelationshipsPart returns the name of the part holding the
+// relationships of a part. Part names are zip member names, without a
+// leading slash. The package's own relationships are those of "".
+func RelationshipsPart(part string) string {
+	dir, file := path.Split(part)
+	return dir + "_rels/" + file + ".rels"
+}
+
+// RelationshipsSource is the reverse of RelationshipsPart. It returns
+// the name of the part whose relationships are held by relsPart, with
+// ok false if relsPart isn't a relationships part.
+func RelationshipsSource(relsPart string) (source string, ok bool) {
+	dir, file := path.Split(relsPart)
+	if !strings.HasSuffix(dir, "_rels/") || path.Ext(file) != ".rels" {
+		return
+	}
+	dir = strings.TrimSuffix(dir, "_rels/")
+	if dir != "" && !strings.HasSuffix(dir, "/") {
+		return
+	}
+	return dir + strings.TrimSuffix(file, ".rels"), true
+}
+
+// ResolveTarget returns the name of the part a relationship of source
+// points at. Targets are URIs, so they're percent-decoded, unless they
+// aren't valid escapes. Those starting with a slash are relative to the
+// root of the package, and the rest to the folder of the source part.
+func ResolveTarget(source, target string) string {
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if strings.HasPrefix(target, "/") {
+		return path.Clean(target)[1:]
+	}
+	return strings.TrimPrefix(path.Join(path.Dir("/"+source), target), "/")
+}
+
+// The content types of the parts of an OPC package, from its
+// [Content_Types].xml part ([ECMA-376] Part 2, 10.1.2). Part names and
+// extensions are compared without regard to case.
+type ContentTypes struct {
+	// Content types by extension, without the dot.
+	Defaults map[string]string
+
+	// Content types of single parts, by part name.
+	Overrides map[string]string
+}
+
+// Parse the [Content_Types].xml part of a package.
+//
+//	Args:
+//		data ([]byte):	The content types part.
+//
+//	Returns:
+//		c (*ContentTypes):	The content types.
+//		err (error):		Non-nil if the part can't be parsed.
+func NewContentTypes(data []byte) (c *ContentTypes, err error) {
+	parsed := &struct {
+		XMLName  xml.Name
+		Defaults []struct {
+			Extension   string `xml:",attr"`
+			ContentType string `xml:",attr"`
+		} `xml:"Default"`
+		Overrides []struct {
+			PartName    string `xml:",attr"`
+			ContentType string `xml:",attr"`
+		} `xml:"Override"`
+	}{}
+	err = xml.Unmarshal(data, parsed)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing content types", err)
+		return
+	}
+	if parsed.XMLName.Local != "Types" {
+		err = fmt.Errorf("content types part has a root element of %s", parsed.XMLName.Local)
+		return
+	}
+	c = &ContentTypes{Defaults: map[string]string{}, Overrides: map[string]string{}}
+	for _, d := range parsed.Defaults {
+		c.Defaults[strings.ToLower(d.Extension)] = d.ContentType
+	}
+	for _, o := range parsed.Overrides {
+		c.Overrides[strings.ToLower(ResolveTarget("", o.PartName))] = o.ContentType
+	}
+	return
+}
+
+// PartType returns the content type of a part, or "" if it has none.
+func (c *ContentTypes) PartType(part string) string {
+	if t, ok := c.Overrides[strings.ToLower(part)]; ok {
+		return t
+	}
+	return c.Defaults[strings.ToLower(strings.TrimPrefix(path.Ext(part), "."))]
+}
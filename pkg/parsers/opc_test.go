@@ -0,0 +1,124 @@
+package parsers
+
+import "testing"
+
+func TestRelationships(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+  <Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com/" TargetMode="External"/>
+</Relationships>`
+	rels, err := NewRelationships([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 2 {
+		t.Fatalf("expected 2 relationships, got %d", len(rels))
+	}
+	if r := rels[0]; r.ID != "rId1" || r.Target != "xl/workbook.xml" || r.TargetMode != "" {
+		t.Errorf("relationship mismatch: %+v", r)
+	}
+	if r := rels[1]; r.ID != "rId2" || r.TargetMode != "External" {
+		t.Errorf("relationship mismatch: %+v", r)
+	}
+
+	if _, err = NewRelationships([]byte(`<Types/>`)); err == nil {
+		t.Errorf("expected an error for the wrong root element")
+	}
+}
+
+func TestResolveTarget(t *testing.T) {
+	cases := []struct{ source, target, expected string }{
+		{"", "xl/workbook.xml", "xl/workbook.xml"},
+		{"xl/workbook.xml", "macrosheets/sheet1.xml", "xl/macrosheets/sheet1.xml"},
+		{"xl/workbook.xml", "/xl/worksheets/sheet1.xml", "xl/worksheets/sheet1.xml"},
+		{"word/document.xml", "../customXml/item1.xml", "customXml/item1.xml"},
+		{"word/document.xml", "../../../evil.xml", "evil.xml"},
+		{"word/document.xml", "media/image%201.png", "word/media/image 1.png"},
+		{"word/document.xml", "%2e%2e/%2e%2e/evil.xml", "evil.xml"},
+		{"word/document.xml", "media/100%.png", "word/media/100%.png"},
+	}
+	for _, c := range cases {
+		if actual := ResolveTarget(c.source, c.target); actual != c.expected {
+			t.Errorf("ResolveTarget(%q, %q) mismatch - expected %s got %s", c.source, c.target, c.expected, actual)
+		}
+	}
+	for part, expected := range map[string]string{
+		"":                "_rels/.rels",
+		"xl/workbook.xml": "xl/_rels/workbook.xml.rels",
+	} {
+		if actual := RelationshipsPart(part); actual != expected {
+			t.Errorf("RelationshipsPart(%q) mismatch - expected %s got %s", part, expected, actual)
+		}
+		if source, ok := RelationshipsSource(expected); !ok || source != part {
+			t.Errorf("RelationshipsSource(%q) mismatch - expected %s got %s", expected, part, source)
+		}
+	}
+}
+
+func TestRelationshipsSource(t *testing.T) {
+	for _, part := range []string{"word/document.xml", "word/_rels/document.xml", "x_rels/a.xml.rels", "_rels"} {
+		if source, ok := RelationshipsSource(part); ok {
+			t.Errorf("expected %s not to be a relationships part, got %s", part, source)
+		}
+	}
+}
+
+func TestContentTypes(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+  <Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+  <Default Extension="XML" ContentType="application/xml"/>
+  <Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.document.macroEnabled.main+xml"/>
+</Types>`
+	c, err := NewContentTypes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for part, expected := range map[string]string{
+		"word/document.xml":         "application/vnd.ms-word.document.macroEnabled.main+xml",
+		"Word/Document.xml":         "application/vnd.ms-word.document.macroEnabled.main+xml",
+		"word/styles.xml":           "application/xml",
+		"_rels/.rels":               "application/vnd.openxmlformats-package.relationships+xml",
+		"word/media/image1.png":     "",
+		"[Content_Types].xml":       "application/xml",
+		"word/embeddings/oleObject": "",
+	} {
+		if actual := c.PartType(part); actual != expected {
+			t.Errorf("PartType(%q) mismatch - expected %q got %q", part, expected, actual)
+		}
+	}
+
+	if _, err = NewContentTypes([]byte(`<Relationships/>`)); err == nil {
+		t.Errorf("expected an error for the wrong root element")
+	}
+}
+
+// The relationships and content types of a real .docx file.
+func TestOPCSample(t *testing.T) {
+	rels, err := NewRelationships(sampleZipMember(t, "docx.docx", "word/_rels/document.xml.rels"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []string{}
+	for _, r := range rels {
+		targets = append(targets, ResolveTarget("word/document.xml", r.Target))
+	}
+	if len(targets) != 3 || targets[0] != "word/styles.xml" || targets[1] != "word/fontTable.xml" || targets[2] != "word/settings.xml" {
+		t.Errorf("relationships mismatch: %v", targets)
+	}
+
+	c, err := NewContentTypes(sampleZipMember(t, "docx.docx", "[Content_Types].xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for part, expected := range map[string]string{
+		"word/document.xml": "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+		"docProps/core.xml": "application/vnd.openxmlformats-package.core-properties+xml",
+		"_rels/.rels":       "application/vnd.openxmlformats-package.relationships+xml",
+	} {
+		if actual := c.PartType(part); actual != expected {
+			t.Errorf("PartType(%q) mismatch - expected %q got %q", part, expected, actual)
+		}
+	}
+}
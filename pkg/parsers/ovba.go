@@ -0,0 +1,126 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Largest output we'll decompress from a single container. A 4098-byte
+// chunk can be as small as a handful of bytes, so this guards against
+// containers built to blow up.
+const maxOVBASize = 32 << 20
+
+// Size of a decompressed chunk.
+const ovbaChunkSize = 4096
+
+var (
+	ErrOVBASignature = errors.New("not an MS-OVBA compressed container")
+	ErrOVBATooLarge  = errors.New("MS-OVBA container decompresses to more than the limit")
+)
+
+// Decompress an MS-OVBA compressed container ([MS-OVBA] 2.4.1). VBA
+// keeps the dir stream and module source code in this form. It is a
+// signature byte of 1, followed by chunks of up to 4096 decompressed
+// bytes, each with a uint16 header:
+//
+//	bits 0-11	chunk size, minus 3
+//	bits 12-14	signature, 0b011
+//	bit 15		set if the chunk is compressed
+//
+// Uncompressed chunks hold 4096 literal bytes. Compressed chunks hold
+// groups of a flag byte, followed by 8 tokens: a literal byte for each
+// clear bit, or a uint16 copy token for each set bit. A copy token
+// repeats earlier output from the same chunk, with the split between
+// its offset and length bits depending on how far into the chunk it is.
+//
+//	Args:
+//		data ([]byte):	The compressed container.
+//
+//	Returns:
+//		out ([]byte):	The decompressed data.
+//		err (error):	Non-nil if the container is malformed. out holds
+//				what was decompressed up to that point.
+func DecompressOVBA(data []byte) (out []byte, err error) {
+	if len(data) == 0 || data[0] != 0x01 {
+		err = ErrOVBASignature
+		return
+	}
+	buf := &bytes.Buffer{}
+	pos := 1
+	for pos+2 <= len(data) {
+		header := binary.LittleEndian.Uint16(data[pos:])
+		end := pos + int(header&0x0FFF) + 3
+		if end > len(data) {
+			end = len(data)
+		}
+		pos += 2
+		if buf.Len()+ovbaChunkSize > maxOVBASize {
+			err = ErrOVBATooLarge
+			break
+		}
+		if header&0x8000 == 0 {
+			raw := data[pos:end]
+			if len(raw) > ovbaChunkSize {
+				raw = raw[:ovbaChunkSize]
+			}
+			buf.Write(raw)
+			pos = end
+			continue
+		}
+		err = decompressChunk(buf, data[pos:end])
+		if err != nil {
+			err = fmt.Errorf("%w: decompressing chunk at offset %d", err, pos-2)
+			break
+		}
+		pos = end
+	}
+	out = buf.Bytes()
+	return
+}
+
+// Decompress a compressed chunk, without its header, onto the end of out.
+func decompressChunk(out *bytes.Buffer, chunk []byte) (err error) {
+	start := out.Len()
+	pos := 0
+	for pos < len(chunk) {
+		flags := chunk[pos]
+		pos++
+		for bit := 0; bit < 8 && pos < len(chunk); bit++ {
+			if flags&(1<<bit) == 0 {
+				out.WriteByte(chunk[pos])
+				pos++
+				continue
+			}
+			if pos+2 > len(chunk) {
+				err = fmt.Errorf("copy token at %d overruns the chunk", pos)
+				return
+			}
+			token := binary.LittleEndian.Uint16(chunk[pos:])
+			pos += 2
+			difference := out.Len() - start
+			bitCount := 4
+			for 1<<bitCount < difference {
+				bitCount++
+			}
+			lengthMask := uint16(0xFFFF) >> bitCount
+			length := int(token&lengthMask) + 3
+			offset := int(token>>(16-bitCount)) + 1
+			if offset > difference {
+				err = fmt.Errorf("copy token offset of %d is before the start of the chunk", offset)
+				return
+			}
+			if difference+length > ovbaChunkSize {
+				err = fmt.Errorf("copy token length of %d overruns the chunk", length)
+				return
+			}
+			// Copies may overlap what they write, so go byte by byte.
+			for i := 0; i < length; i++ {
+				b := out.Bytes()
+				out.WriteByte(b[len(b)-offset])
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,82 @@
+package parsers
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/vbatest"
+)
+
+// Examples from [MS-OVBA] 3.2.
+var ovbaExamples = []struct {
+	name         string
+	decompressed string
+	compressed   []byte
+}{
+	{
+		"no compression",
+		"abcdefghijklmnopqrstuv.",
+		[]byte{
+			0x01, 0x19, 0xB0, 0x00, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x00, 0x69, 0x6A, 0x6B,
+			0x6C, 0x6D, 0x6E, 0x6F, 0x70, 0x00, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x2E,
+		},
+	},
+	{
+		"normal compression",
+		"#aaabcdefaaaaghijaaaaaklaaamnopqaaaaaaaaaaaarstuvwxyzaaa",
+		[]byte{
+			0x01, 0x2F, 0xB0, 0x00, 0x23, 0x61, 0x61, 0x61, 0x62, 0x63, 0x64, 0x65, 0x82, 0x66, 0x00, 0x70,
+			0x61, 0x67, 0x68, 0x69, 0x6A, 0x01, 0x38, 0x08, 0x61, 0x6B, 0x6C, 0x00, 0x30, 0x6D, 0x6E, 0x6F,
+			0x70, 0x06, 0x71, 0x02, 0x70, 0x04, 0x10, 0x72, 0x73, 0x74, 0x75, 0x76, 0x10, 0x77, 0x78, 0x79,
+			0x7A, 0x00, 0x3C,
+		},
+	},
+}
+
+func TestDecompressOVBA(t *testing.T) {
+	for _, example := range ovbaExamples {
+		out, err := DecompressOVBA(example.compressed)
+		if err != nil {
+			t.Errorf("%s: %v", example.name, err)
+			continue
+		}
+		if string(out) != example.decompressed {
+			t.Errorf("%s: expected %q got %q", example.name, example.decompressed, out)
+		}
+	}
+}
+
+// Several chunks, compressible or not, survive a round trip.
+func TestOVBARoundTrip(t *testing.T) {
+	random := make([]byte, 5000)
+	seed := uint32(1)
+	for i := range random {
+		seed = seed*1664525 + 1013904223
+		random[i] = byte(seed >> 24)
+	}
+	for _, data := range [][]byte{
+		[]byte(ovbaExamples[1].decompressed),
+		[]byte(strings.Repeat("Attribute VB_Name = \"Module1\"\r\n", 400)),
+		random,
+	} {
+		out, err := DecompressOVBA(vbatest.Compress(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("round trip of %d bytes came back as %d different bytes", len(data), len(out))
+		}
+	}
+}
+
+func TestDecompressOVBAMalformed(t *testing.T) {
+	if _, err := DecompressOVBA([]byte("Attribute")); !errors.Is(err, ErrOVBASignature) {
+		t.Errorf("expected a signature error, got %v", err)
+	}
+	// A copy token before any literals points outside the chunk.
+	if _, err := DecompressOVBA([]byte{0x01, 0x02, 0xB0, 0x01, 0x00, 0x00}); err == nil {
+		t.Errorf("expected an error for a copy token with nothing to copy")
+	}
+}
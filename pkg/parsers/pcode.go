@@ -0,0 +1,929 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrPCodeVersion = errors.New("unsupported VBA version")
+	ErrNoPCode      = errors.New("no p-code found in the module stream")
+)
+
+// Versions of VBA, which decide the layout of the p-code. VBA 6 came
+// with Office 2000, VBA 7 with Office 2010.
+const (
+	vbaVersion6 = 0x6B
+	vbaVersion7 = 0x97
+)
+
+// The _VBA_PROJECT stream of a VBA storage. Only the header is documented
+// ([MS-OVBA] 2.3.4.1, integers are little-endian):
+//
+//	uint16	0x61CC
+//	uint16	Version, of the VBA that wrote the performance cache
+//	byte	0x00
+//	uint16	reserved
+//	[]byte	PerformanceCache
+//
+// Office only runs the compiled p-code in module streams when Version
+// matches its own VBA. Otherwise, it compiles the source code again.
+//
+// The performance cache isn't documented. Its identifier table is found
+// the way pcodedmp does, by skipping over the references and modules
+// ahead of it.
+type VBAProjectStream struct {
+	Version uint16
+
+	// The project's identifiers, which p-code refers to by index. This
+	// is nil if the cache can't be walked to the end of the table, or
+	// the table doesn't look right, as the indexes would be off.
+	Identifiers []string
+}
+
+// Parse a _VBA_PROJECT stream.
+//
+//	Args:
+//		data ([]byte):	The _VBA_PROJECT stream.
+//
+//	Returns:
+//		p (*VBAProjectStream):	The parsed header.
+//		err (error):		Non-nil if the stream can't be parsed.
+func NewVBAProjectStream(data []byte) (p *VBAProjectStream, err error) {
+	if len(data) < 7 {
+		err = fmt.Errorf("_VBA_PROJECT stream of %d bytes is too short", len(data))
+		return
+	}
+	if magic := binary.LittleEndian.Uint16(data); magic != 0x61CC {
+		err = fmt.Errorf("_VBA_PROJECT stream starts with %#04x, not 0x61cc", magic)
+		return
+	}
+	p = &VBAProjectStream{Version: binary.LittleEndian.Uint16(data[2:])}
+	if p.Version != 0xFFFF {
+		p.Identifiers = readIdentifiers(data, p.Version)
+	}
+	return
+}
+
+// Read the identifier table from the performance cache of a _VBA_PROJECT
+// stream. The offsets and the version checks come from pcodedmp. As they
+// are guesswork, the table is only returned if it was read to the end,
+// and every name in it could be a VBA identifier.
+func readIdentifiers(data []byte, version uint16) (identifiers []string) {
+	unicodeRef := (version >= 0x5B && version != 0x60 && version != 0x62 && version != 0x63) || version == 0x4E
+	unicodeName := (version >= 0x59 && version != 0x60 && version != 0x62 && version != 0x63) || version == 0x4E
+	nonUnicodeName := version <= 0x59 && version != 0x4E
+	r := &cacheReader{data: data, offset: 0x1E, order: binary.LittleEndian}
+	// Mac projects are big-endian.
+	if binary.LittleEndian.Uint16(data[5:]) == 0x000E {
+		r.order = binary.BigEndian
+	}
+
+	// References to other projects and type libraries.
+	refs := int(r.word())
+	r.skip(2)
+	for i := 0; i < refs && !r.failed; i++ {
+		length := int(r.word())
+		switch {
+		case length == 0:
+			r.skip(6)
+		case unicodeRef && length < 5, !unicodeRef && length < 3:
+			r.skip(length)
+		default:
+			kind := r.peek(2)
+			if unicodeRef {
+				kind = r.peek(4)
+			}
+			r.skip(length)
+			if kind == 'C' || kind == 'D' {
+				r.skipArray(false, 1)
+			}
+		}
+		r.skip(10)
+		if r.word() != 0 {
+			r.skipArray(false, 1)
+			length = int(r.word())
+			if length != 0 {
+				r.skip(2)
+			}
+			r.skip(length + 30)
+		}
+	}
+	// Class and user form table, compile-time constants, type info ID,
+	// project description and help file.
+	r.skipArray(false, 2)
+	r.skipArray(false, 4)
+	r.skip(2)
+	r.skipArray(true, 1)
+	r.skipArray(true, 1)
+	r.skipArray(true, 1)
+	r.skip(0x64)
+
+	// Module descriptors.
+	modules := int(r.word())
+	for i := 0; i < modules && !r.failed; i++ {
+		length := int(r.word())
+		if unicodeName {
+			r.skip(length)
+		}
+		if nonUnicodeName {
+			if length != 0 {
+				length = int(r.word())
+			}
+			r.skip(length)
+		}
+		r.skipArray(false, 1)
+		r.skipArray(true, 1)
+		r.word()
+		if version >= vbaVersion6 {
+			r.skipArray(true, 1)
+		}
+		r.skipArray(true, 1)
+		r.skip(2)
+		if version != 0x51 {
+			r.skip(4)
+		}
+		r.skipArray(false, 8)
+		r.skip(11)
+	}
+	r.skip(6)
+	r.skipArray(true, 1)
+	r.skip(6)
+
+	// The identifier table, after some we don't need.
+	w0, count, w1 := int(r.word()), int(r.word()), int(r.word())
+	r.skip(4)
+	for i := 0; i < count+w1-w0 && !r.failed; i++ {
+		r.skip(4)
+		idType, length := r.typeAndLength()
+		if idType > 0x7F {
+			r.skip(6)
+		}
+		r.skip(length)
+	}
+	if w0 < w1 {
+		return
+	}
+	for i := 0; i < w0-w1 && !r.failed; i++ {
+		idType, length := r.typeAndLength()
+		keyword := false
+		if idType == 0 && length == 0 {
+			r.skip(2)
+			idType, length = r.typeAndLength()
+			keyword = true
+		}
+		if idType&0x80 != 0 {
+			r.skip(6)
+		}
+		if length > 0 {
+			name := r.bytes(length)
+			if r.failed || bytes.IndexFunc(name, func(r rune) bool { return r < 0x20 || r == 0x7F }) >= 0 {
+				return nil
+			}
+			identifiers = append(identifiers, string(name))
+		}
+		if !keyword {
+			r.skip(4)
+		}
+	}
+	if r.failed {
+		identifiers = nil
+	}
+	return
+}
+
+// Reads the performance cache. Reading past the end sets failed, and
+// gives zeros.
+type cacheReader struct {
+	data   []byte
+	offset int
+	order  binary.ByteOrder
+	failed bool
+}
+
+func (r *cacheReader) skip(n int) {
+	if n < 0 || n > len(r.data)-r.offset {
+		r.failed = true
+		r.offset = len(r.data)
+		return
+	}
+	r.offset += n
+}
+
+func (r *cacheReader) bytes(n int) (b []byte) {
+	start := r.offset
+	r.skip(n)
+	if !r.failed {
+		b = r.data[start:r.offset]
+	}
+	return
+}
+
+func (r *cacheReader) word() (w uint16) {
+	if b := r.bytes(2); b != nil {
+		w = r.order.Uint16(b)
+	}
+	return
+}
+
+func (r *cacheReader) dword() (dw uint32) {
+	if b := r.bytes(4); b != nil {
+		dw = r.order.Uint32(b)
+	}
+	return
+}
+
+// The byte n bytes ahead, without moving.
+func (r *cacheReader) peek(n int) byte {
+	if r.offset+n >= len(r.data) {
+		return 0
+	}
+	return r.data[r.offset+n]
+}
+
+// Skip a count, as a uint16 or uint32, and that many elements of size
+// bytes.
+func (r *cacheReader) skipArray(dwordCount bool, size int) {
+	var count int64
+	if dwordCount {
+		count = int64(r.dword())
+	} else {
+		count = int64(r.word())
+	}
+	if count*int64(size) > int64(len(r.data)-r.offset) {
+		r.skip(-1)
+		return
+	}
+	r.skip(int(count) * size)
+}
+
+// The type and length of an identifier, a byte each. The length comes
+// first in little-endian projects.
+func (r *cacheReader) typeAndLength() (idType, length int) {
+	b := r.bytes(2)
+	if b == nil {
+		return
+	}
+	idType, length = int(b[1]), int(b[0])
+	if r.order == binary.BigEndian {
+		idType, length = int(b[0]), int(b[1])
+	}
+	return
+}
+
+// A line of disassembled p-code.
+type PCodeLine struct {
+	Instructions []*PCodeInstruction
+}
+
+// A p-code instruction. Each is a uint16, with the opcode in the low 10
+// bits, and a type in the high 6 bits, followed by its operands.
+type PCodeInstruction struct {
+	Mnemonic string
+	Operands []string
+
+	// The text, for LitStr, Rem and QuoteRem instructions.
+	Literal string
+
+	// The project's own identifiers among the operands, without any
+	// type suffix. Built-in keywords aren't listed.
+	Identifiers []string
+
+	// True if an operand refers to one of the project's identifiers,
+	// but it isn't in the Identifiers given to DisassemblePCode.
+	Unresolved bool
+}
+
+// String returns the instruction as a line of the disassembly listing,
+// like `ArgsCall (Call) id_0212 0x0001` or `LitStr 0x0008 "calc.exe"`.
+func (i *PCodeInstruction) String() string {
+	return strings.TrimSpace(i.Mnemonic + " " + strings.Join(i.Operands, " "))
+}
+
+// Disassemble the p-code of a module stream. The p-code isn't documented.
+// This follows the layout worked out by pcodedmp, for VBA 6 and 7 on
+// Windows. Module streams start with 0x01CC, and hold a line table
+// somewhere after the declarations:
+//
+//	uint16	0xCAFE
+//	uint16	unknown
+//	uint16	number of lines
+//	[]line	each 12 bytes: 4 unknown, uint16 length, 2 unknown, uint32 offset
+//	[10]byte	unknown
+//	[]byte	p-code for each line, at its offset from here
+//
+// Names of built-in keywords are resolved, and the project's own
+// identifiers are looked up in the Identifiers from _VBA_PROJECT. Any
+// which aren't there show up by ID, like "id_0212". A line with an opcode
+// we don't know is cut short, rather than failing the whole module.
+//
+//	Args:
+//		stream ([]byte):	The module stream.
+//		version (uint16):	The Version from _VBA_PROJECT.
+//		identifiers ([]string):	The Identifiers from _VBA_PROJECT.
+//		is64bit (bool):		True if the project's SysKind is VBASysKindWin64.
+//
+//	Returns:
+//		lines ([]*PCodeLine):	The p-code, line by line.
+//		err (error):		Non-nil if the p-code can't be found.
+func DisassemblePCode(stream []byte, version uint16, identifiers []string, is64bit bool) (lines []*PCodeLine, err error) {
+	if version < vbaVersion6 {
+		err = fmt.Errorf("%w: %#04x", ErrPCodeVersion, version)
+		return
+	}
+	d := &disassembler{stream: stream, vba7: version >= vbaVersion7, is64bit: is64bit, identifiers: identifiers}
+	if len(stream) < 0x1D || binary.LittleEndian.Uint16(stream) != 0x01CC {
+		err = ErrNoPCode
+		return
+	}
+	offset := int64(binary.LittleEndian.Uint32(stream[0x19:])) + 0x3C
+	if magic, ok := d.word(offset); !ok || magic != 0xCAFE {
+		err = fmt.Errorf("%w: no line table at %#x", ErrNoPCode, offset)
+		return
+	}
+	count, ok := d.word(offset + 4)
+	if !ok {
+		err = fmt.Errorf("%w: line table at %#x is truncated", ErrNoPCode, offset)
+		return
+	}
+	table := offset + 6
+	start := table + int64(count)*12 + 10
+	if start > int64(len(stream)) {
+		err = fmt.Errorf("%w: %d lines overrun the module stream", ErrNoPCode, count)
+		return
+	}
+	for i := int64(0); i < int64(count); i++ {
+		length, _ := d.word(table + i*12 + 4)
+		lineOffset, _ := d.dword(table + i*12 + 8)
+		lines = append(lines, d.line(start+int64(lineOffset), int64(length)))
+	}
+	return
+}
+
+type disassembler struct {
+	stream      []byte
+	vba7        bool
+	is64bit     bool
+	identifiers []string
+}
+
+func (d *disassembler) word(offset int64) (w uint16, ok bool) {
+	if offset < 0 || offset+2 > int64(len(d.stream)) {
+		return
+	}
+	return binary.LittleEndian.Uint16(d.stream[offset:]), true
+}
+
+func (d *disassembler) dword(offset int64) (dw uint32, ok bool) {
+	if offset < 0 || offset+4 > int64(len(d.stream)) {
+		return
+	}
+	return binary.LittleEndian.Uint32(d.stream[offset:]), true
+}
+
+// Disassemble the instructions in length bytes at offset.
+func (d *disassembler) line(offset, length int64) (line *PCodeLine) {
+	line = &PCodeLine{}
+	end := offset + length
+	if end > int64(len(d.stream)) {
+		end = int64(len(d.stream))
+	}
+	for offset < end {
+		word, _ := d.word(offset)
+		offset += 2
+		opType := int(word >> 10)
+		opcode := d.translate(word & 0x03FF)
+		if int(opcode) >= len(pcodeOpcodes) {
+			line.Instructions = append(line.Instructions, &PCodeInstruction{Mnemonic: fmt.Sprintf("Unknown_%04X", word&0x03FF)})
+			return
+		}
+		op := pcodeOpcodes[opcode]
+		instruction := &PCodeInstruction{Mnemonic: op.mnemonic}
+		line.Instructions = append(line.Instructions, instruction)
+		if prefix := opTypeOperand(op.mnemonic, opType); prefix != "" {
+			instruction.Operands = append(instruction.Operands, prefix)
+		}
+		for _, arg := range op.args {
+			switch arg {
+			case "name":
+				id, ok := d.word(offset)
+				if !ok {
+					return
+				}
+				offset += 2
+				name, identifier, resolved := d.name(id, op.mnemonic, opType)
+				instruction.Operands = append(instruction.Operands, name)
+				if identifier != "" {
+					instruction.Identifiers = append(instruction.Identifiers, identifier)
+				}
+				if !resolved {
+					instruction.Unresolved = true
+				}
+			case "0x", "imp_":
+				w, ok := d.word(offset)
+				if !ok {
+					return
+				}
+				offset += 2
+				instruction.Operands = append(instruction.Operands, fmt.Sprintf("%s%04X", arg, w))
+			default:
+				dw, ok := d.dword(offset)
+				if !ok {
+					return
+				}
+				offset += 4
+				instruction.Operands = append(instruction.Operands, fmt.Sprintf("%s%08X", arg, dw))
+				if d.is64bit && arg == "context_" {
+					dw, _ = d.dword(offset)
+					offset += 4
+					instruction.Operands = append(instruction.Operands, fmt.Sprintf("%08X", dw))
+				}
+			}
+		}
+		if op.varg {
+			size, ok := d.word(offset)
+			if !ok {
+				return
+			}
+			offset += 2
+			data := d.stream[offset:min64(offset+int64(size), int64(len(d.stream)))]
+			offset += int64(size)
+			if size&1 != 0 {
+				offset++
+			}
+			instruction.Operands = append(instruction.Operands, fmt.Sprintf("0x%04X", size))
+			switch op.mnemonic {
+			case "LitStr", "QuoteRem", "Rem", "Reparse":
+				instruction.Literal = string(data)
+				instruction.Operands = append(instruction.Operands, fmt.Sprintf("%q", data))
+			default:
+				for i := 0; i+1 < len(data); i += 2 {
+					instruction.Operands = append(instruction.Operands, fmt.Sprintf("0x%04X", binary.LittleEndian.Uint16(data[i:])))
+				}
+			}
+		}
+	}
+	return
+}
+
+// 32-bit projects don't have the 8-byte literal opcodes, so everything
+// after them shifts down.
+func (d *disassembler) translate(opcode uint16) uint16 {
+	if d.is64bit {
+		return opcode
+	}
+	switch {
+	case opcode <= 173:
+		return opcode
+	case opcode <= 175:
+		return opcode + 1
+	case opcode <= 178:
+		return opcode + 2
+	}
+	return opcode + 3
+}
+
+// Resolve a name operand. IDs are stored doubled. Below 0x100, they're
+// built-in keywords. The rest index the project's identifiers, less a
+// few which VBA 7 keeps for itself. Those are also returned as
+// identifier, bare. resolved is false if the index is past the end of
+// the project's identifiers, or is one of the ids VBA 7 keeps.
+func (d *disassembler) name(id uint16, mnemonic string, opType int) (name, identifier string, resolved bool) {
+	index := int(id >> 1)
+	resolved = true
+	switch {
+	case index >= 0x100:
+		index -= 0x100
+		if d.vba7 {
+			index -= 4
+			if d.is64bit {
+				index -= 3
+			}
+			if index > 0xBE {
+				index--
+			}
+		}
+		name = fmt.Sprintf("id_%04X", id)
+		if index >= 0 && index < len(d.identifiers) {
+			name = d.identifiers[index]
+			identifier = name
+		}
+		resolved = index >= 0 && index < len(d.identifiers)
+	case d.vba7 && index >= 0xC3:
+		index--
+		fallthrough
+	default:
+		name = fmt.Sprintf("id_%04X", id)
+		if index < len(pcodeKeywords) {
+			name = pcodeKeywords[index]
+		}
+	}
+	switch mnemonic {
+	case "OnError":
+		switch opType {
+		case 1:
+			name, identifier, resolved = "(Resume Next)", "", true
+		case 2:
+			name, identifier, resolved = "(GoTo 0)", "", true
+		}
+		return
+	case "Resume":
+		switch opType {
+		case 0:
+		case 1:
+			name, identifier, resolved = "(Next)", "", true
+		default:
+			name, identifier, resolved = "", "", true
+		}
+		return
+	}
+	if opType < len(pcodeTypeSuffixes) {
+		name += pcodeTypeSuffixes[opType]
+	} else if opType == 32 {
+		name = "[" + name + "]"
+	}
+	return
+}
+
+// Describe the type bits of instructions which use them for more than
+// a name's type suffix.
+func opTypeOperand(mnemonic string, opType int) string {
+	switch mnemonic {
+	case "Coerce", "CoerceVar", "DefType":
+		if opType < len(pcodeVarTypes) {
+			return "(" + pcodeVarTypes[opType] + ")"
+		}
+		if opType == 17 {
+			return "(Byte)"
+		}
+		return fmt.Sprintf("(%d)", opType)
+	case "Dim", "DimImplicit", "Type":
+		kinds := []string{}
+		switch {
+		case opType&0x04 != 0:
+			kinds = append(kinds, "Global")
+		case opType&0x08 != 0:
+			kinds = append(kinds, "Public")
+		case opType&0x10 != 0:
+			kinds = append(kinds, "Private")
+		case opType&0x20 != 0:
+			kinds = append(kinds, "Static")
+		}
+		if opType&0x01 != 0 && mnemonic != "Type" {
+			kinds = append(kinds, "Const")
+		}
+		if len(kinds) > 0 {
+			return "(" + strings.Join(kinds, " ") + ")"
+		}
+	case "LitVarSpecial":
+		if opType < len(pcodeSpecials) {
+			return "(" + pcodeSpecials[opType] + ")"
+		}
+	case "ArgsCall", "ArgsMemCall", "ArgsMemCallWith":
+		if opType < 16 {
+			return "(Call)"
+		}
+	case "Option":
+		if opType < len(pcodeOptions) {
+			return "(" + pcodeOptions[opType] + ")"
+		}
+	case "Redim", "RedimAs":
+		if opType&16 != 0 {
+			return "(Preserve)"
+		}
+	}
+	return ""
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+var (
+	pcodeTypeSuffixes = []string{"", "?", "%", "&", "!", "#", "@", "?", "$", "?", "?", "?", "?", "?"}
+	pcodeVarTypes     = []string{"Var", "?", "Int", "Lng", "Sng", "Dbl", "Cur", "Date", "Str", "Obj", "Err", "Bool", "Var"}
+	pcodeSpecials     = []string{"False", "True", "Null", "Empty"}
+	pcodeOptions      = []string{"Base 0", "Base 1", "Compare Text", "Compare Binary", "Explicit", "Private Module"}
+)
+
+// Names of the built-in identifiers, by ID.
+var pcodeKeywords = []string{
+	"<crash>", "0", "Abs", "Access", "AddressOf", "Alias", "And", "Any",
+	"Append", "Array", "As", "Assert", "B", "Base", "BF", "Binary",
+	"Boolean", "ByRef", "Byte", "ByVal", "Call", "Case", "CBool", "CByte",
+	"CCur", "CDate", "CDec", "CDbl", "CDecl", "ChDir", "CInt", "Circle",
+	"CLng", "Close", "Compare", "Const", "CSng", "CStr", "CurDir", "CurDir$",
+	"CVar", "CVDate", "CVErr", "Currency", "Database", "Date", "Date$", "Debug",
+	"Decimal", "Declare", "DefBool", "DefByte", "DefCur", "DefDate", "DefDec", "DefDbl",
+	"DefInt", "DefLng", "DefObj", "DefSng", "DefStr", "DefVar", "Dim", "Dir",
+	"Dir$", "Do", "DoEvents", "Double", "Each", "Else", "ElseIf", "Empty",
+	"End", "EndIf", "Enum", "Eqv", "Erase", "Error", "Error$", "Event",
+	"WithEvents", "Explicit", "F", "False", "Fix", "For", "Format",
+	"Format$", "FreeFile", "Friend", "Function", "Get", "Global", "Go", "GoSub",
+	"Goto", "If", "Imp", "Implements", "In", "Input", "Input$", "InputB",
+	"InputB", "InStr", "InputB$", "Int", "InStrB", "Is", "Integer", "Left",
+	"LBound", "LenB", "Len", "Lib", "Let", "Line", "Like", "Load",
+	"Local", "Lock", "Long", "Loop", "LSet", "Me", "Mid", "Mid$",
+	"MidB", "MidB$", "Mod", "Module", "Name", "New", "Next", "Not",
+	"Nothing", "Null", "Object", "On", "Open", "Option", "Optional", "Or",
+	"Output", "ParamArray", "Preserve", "Print", "Private", "Property", "PSet", "Public",
+	"Put", "RaiseEvent", "Random", "Randomize", "Read", "ReDim", "Rem", "Resume",
+	"Return", "RGB", "RSet", "Scale", "Seek", "Select", "Set", "Sgn",
+	"Shared", "Single", "Spc", "Static", "Step", "Stop", "StrComp", "String",
+	"String$", "Sub", "Tab", "Text", "Then", "To", "True", "Type",
+	"TypeOf", "UBound", "Unload", "Unlock", "Unknown", "Until", "Variant", "WEnd",
+	"While", "Width", "With", "Write", "Xor", "#Const", "#Else", "#ElseIf",
+	"#End", "#If", "Attribute", "VB_Base", "VB_Control", "VB_Creatable", "VB_Customizable", "VB_Description",
+	"VB_Exposed", "VB_Ext_KEY", "VB_HelpID", "VB_Invoke_Func", "VB_Invoke_Property", "VB_Invoke_PropertyPut", "VB_Invoke_PropertyPutRef", "VB_MemberFlags",
+	"VB_Name", "VB_PredeclaredId", "VB_ProcData", "VB_TemplateDerived", "VB_VarDescription", "VB_VarHelpID", "VB_VarMemberFlags", "VB_VarProcData",
+	"VB_UserMemId", "VB_VarUserMemId", "VB_GlobalNameSpace", ",", ".", "\"", "_", "!",
+	"#", "&", "'", "(", ")", "*", "+", "-",
+	" /", ":", ";", "<", "<=", "<>", "=", "=<",
+	"=>", ">", "><", ">=", "?", "\\", "^", ":=",
+}
+
+type pcodeOpcode struct {
+	mnemonic string
+
+	// Operands: "name" for an identifier ID, "0x" and "imp_" for a
+	// uint16, and "func_", "var_", "rec_", "type_" and "context_" for
+	// a uint32.
+	args []string
+
+	// Set if the operands end with a uint16 size, and that many bytes.
+	varg bool
+}
+
+// Opcodes of 64-bit VBA 7.
+var pcodeOpcodes = []pcodeOpcode{
+	{"Imp", nil, false},
+	{"Eqv", nil, false},
+	{"And", nil, false},
+	{"Xor", nil, false},
+	{"Or", nil, false},
+	{"Eq", nil, false},
+	{"Ne", nil, false},
+	{"Le", nil, false},
+	{"Ge", nil, false},
+	{"Lt", nil, false},
+	{"Gt", nil, false},
+	{"Add", nil, false},
+	{"Sub", nil, false},
+	{"Mod", nil, false},
+	{"IDv", nil, false},
+	{"Mul", nil, false},
+	{"Div", nil, false},
+	{"Concat", nil, false},
+	{"Like", nil, false},
+	{"Pwr", nil, false},
+	{"Is", nil, false},
+	{"Not", nil, false},
+	{"UMi", nil, false},
+	{"FnAbs", nil, false},
+	{"FnFix", nil, false},
+	{"FnInt", nil, false},
+	{"FnSgn", nil, false},
+	{"FnLen", nil, false},
+	{"FnLenB", nil, false},
+	{"Paren", nil, false},
+	{"Sharp", nil, false},
+	{"LdLHS", []string{"name"}, false},
+	{"Ld", []string{"name"}, false},
+	{"MemLd", []string{"name"}, false},
+	{"DictLd", []string{"name"}, false},
+	{"IndexLd", []string{"0x"}, false},
+	{"ArgsLd", []string{"name", "0x"}, false},
+	{"ArgsMemLd", []string{"name", "0x"}, false},
+	{"ArgsDictLd", []string{"name", "0x"}, false},
+	{"St", []string{"name"}, false},
+	{"MemSt", []string{"name"}, false},
+	{"DictSt", []string{"name"}, false},
+	{"IndexSt", []string{"0x"}, false},
+	{"ArgsSt", []string{"name", "0x"}, false},
+	{"ArgsMemSt", []string{"name", "0x"}, false},
+	{"ArgsDictSt", []string{"name", "0x"}, false},
+	{"Set", []string{"name"}, false},
+	{"MemSet", []string{"name"}, false},
+	{"DictSet", []string{"name"}, false},
+	{"IndexSet", []string{"0x"}, false},
+	{"ArgsSet", []string{"name", "0x"}, false},
+	{"ArgsMemSet", []string{"name", "0x"}, false},
+	{"ArgsDictSet", []string{"name", "0x"}, false},
+	{"MemLdWith", []string{"name"}, false},
+	{"DictLdWith", []string{"name"}, false},
+	{"ArgsMemLdWith", []string{"name", "0x"}, false},
+	{"ArgsDictLdWith", []string{"name", "0x"}, false},
+	{"MemStWith", []string{"name"}, false},
+	{"DictStWith", []string{"name"}, false},
+	{"ArgsMemStWith", []string{"name", "0x"}, false},
+	{"ArgsDictStWith", []string{"name", "0x"}, false},
+	{"MemSetWith", []string{"name"}, false},
+	{"DictSetWith", []string{"name"}, false},
+	{"ArgsMemSetWith", []string{"name", "0x"}, false},
+	{"ArgsDictSetWith", []string{"name", "0x"}, false},
+	{"ArgsCall", []string{"name", "0x"}, false},
+	{"ArgsMemCall", []string{"name", "0x"}, false},
+	{"ArgsMemCallWith", []string{"name", "0x"}, false},
+	{"ArgsArray", []string{"name", "0x"}, false},
+	{"Assert", nil, false},
+	{"BoS", []string{"0x"}, false},
+	{"BoSImplicit", nil, false},
+	{"BoL", nil, false},
+	{"LdAddressOf", []string{"name"}, false},
+	{"MemAddressOf", []string{"name"}, false},
+	{"Case", nil, false},
+	{"CaseTo", nil, false},
+	{"CaseGt", nil, false},
+	{"CaseLt", nil, false},
+	{"CaseGe", nil, false},
+	{"CaseLe", nil, false},
+	{"CaseNe", nil, false},
+	{"CaseEq", nil, false},
+	{"CaseElse", nil, false},
+	{"CaseDone", nil, false},
+	{"Circle", []string{"0x"}, false},
+	{"Close", []string{"0x"}, false},
+	{"CloseAll", nil, false},
+	{"Coerce", nil, false},
+	{"CoerceVar", nil, false},
+	{"Context", []string{"context_"}, false},
+	{"Debug", nil, false},
+	{"DefType", []string{"0x", "0x"}, false},
+	{"Dim", nil, false},
+	{"DimImplicit", nil, false},
+	{"Do", nil, false},
+	{"DoEvents", nil, false},
+	{"DoUnitil", nil, false},
+	{"DoWhile", nil, false},
+	{"Else", nil, false},
+	{"ElseBlock", nil, false},
+	{"ElseIfBlock", nil, false},
+	{"ElseIfTypeBlock", []string{"imp_"}, false},
+	{"End", nil, false},
+	{"EndContext", nil, false},
+	{"EndFunc", nil, false},
+	{"EndIf", nil, false},
+	{"EndIfBlock", nil, false},
+	{"EndImmediate", nil, false},
+	{"EndProp", nil, false},
+	{"EndSelect", nil, false},
+	{"EndSub", nil, false},
+	{"EndType", nil, false},
+	{"EndWith", nil, false},
+	{"Erase", []string{"0x"}, false},
+	{"Error", nil, false},
+	{"EventDecl", []string{"func_"}, false},
+	{"RaiseEvent", []string{"name", "0x"}, false},
+	{"ArgsMemRaiseEvent", []string{"name", "0x"}, false},
+	{"ArgsMemRaiseEventWith", []string{"name", "0x"}, false},
+	{"ExitDo", nil, false},
+	{"ExitFor", nil, false},
+	{"ExitFunc", nil, false},
+	{"ExitProp", nil, false},
+	{"ExitSub", nil, false},
+	{"FnCurDir", nil, false},
+	{"FnDir", nil, false},
+	{"Empty0", nil, false},
+	{"Empty1", nil, false},
+	{"FnError", nil, false},
+	{"FnFormat", nil, false},
+	{"FnFreeFile", nil, false},
+	{"FnInStr", nil, false},
+	{"FnInStr3", nil, false},
+	{"FnInStr4", nil, false},
+	{"FnInStrB", nil, false},
+	{"FnInStrB3", nil, false},
+	{"FnInStrB4", nil, false},
+	{"FnLBound", []string{"0x"}, false},
+	{"FnMid", nil, false},
+	{"FnMidB", nil, false},
+	{"FnStrComp", nil, false},
+	{"FnStrComp3", nil, false},
+	{"FnStringVar", nil, false},
+	{"FnStringStr", nil, false},
+	{"FnUBound", []string{"0x"}, false},
+	{"For", nil, false},
+	{"ForEach", nil, false},
+	{"ForEachAs", []string{"imp_"}, false},
+	{"ForStep", nil, false},
+	{"FuncDefn", []string{"func_"}, false},
+	{"FuncDefnSave", []string{"func_"}, false},
+	{"GetRec", nil, false},
+	{"GoSub", []string{"name"}, false},
+	{"GoTo", []string{"name"}, false},
+	{"If", nil, false},
+	{"IfBlock", nil, false},
+	{"TypeOf", []string{"imp_"}, false},
+	{"IfTypeBlock", []string{"imp_"}, false},
+	{"Implements", []string{"0x", "0x", "0x", "0x"}, false},
+	{"Input", nil, false},
+	{"InputDone", nil, false},
+	{"InputItem", nil, false},
+	{"Label", []string{"name"}, false},
+	{"Let", nil, false},
+	{"Line", []string{"0x"}, false},
+	{"LineCont", nil, true},
+	{"LineInput", nil, false},
+	{"LineNum", []string{"name"}, false},
+	{"LitCy", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitDate", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitDefault", nil, false},
+	{"LitDI2", []string{"0x"}, false},
+	{"LitDI4", []string{"0x", "0x"}, false},
+	{"LitDI8", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitHI2", []string{"0x"}, false},
+	{"LitHI4", []string{"0x", "0x"}, false},
+	{"LitHI8", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitNothing", nil, false},
+	{"LitOI2", []string{"0x"}, false},
+	{"LitOI4", []string{"0x", "0x"}, false},
+	{"LitOI8", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitR4", []string{"0x", "0x"}, false},
+	{"LitR8", []string{"0x", "0x", "0x", "0x"}, false},
+	{"LitSmallI2", nil, false},
+	{"LitStr", nil, true},
+	{"LitVarSpecial", nil, false},
+	{"Lock", nil, false},
+	{"Loop", nil, false},
+	{"LoopUntil", nil, false},
+	{"LoopWhile", nil, false},
+	{"LSet", nil, false},
+	{"Me", nil, false},
+	{"MeImplicit", nil, false},
+	{"MemRedim", []string{"name", "0x", "type_"}, false},
+	{"MemRedimWith", []string{"name", "0x", "type_"}, false},
+	{"MemRedimAs", []string{"name", "0x", "type_"}, false},
+	{"MemRedimAsWith", []string{"name", "0x", "type_"}, false},
+	{"Mid", nil, false},
+	{"MidB", nil, false},
+	{"Name", nil, false},
+	{"New", []string{"imp_"}, false},
+	{"Next", nil, false},
+	{"NextVar", nil, false},
+	{"OnError", []string{"name"}, false},
+	{"OnGosub", nil, true},
+	{"OnGoto", nil, true},
+	{"Open", []string{"0x"}, false},
+	{"Option", nil, false},
+	{"OptionBase", nil, false},
+	{"ParamByVal", nil, false},
+	{"ParamOmitted", nil, false},
+	{"ParamNamed", []string{"name"}, false},
+	{"PrintChan", nil, false},
+	{"PrintComma", nil, false},
+	{"PrintEoS", nil, false},
+	{"PrintItemComma", nil, false},
+	{"PrintItemNL", nil, false},
+	{"PrintItemSemi", nil, false},
+	{"PrintNL", nil, false},
+	{"PrintObj", nil, false},
+	{"PrintSemi", nil, false},
+	{"PrintSpc", nil, false},
+	{"PrintTab", nil, false},
+	{"PrintTabComma", nil, false},
+	{"PSet", []string{"0x"}, false},
+	{"PutRec", nil, false},
+	{"QuoteRem", []string{"0x"}, true},
+	{"Redim", []string{"name", "0x", "type_"}, false},
+	{"RedimAs", []string{"name", "0x", "type_"}, false},
+	{"Reparse", nil, true},
+	{"Rem", nil, true},
+	{"Resume", []string{"name"}, false},
+	{"Return", nil, false},
+	{"RSet", nil, false},
+	{"Scale", []string{"0x"}, false},
+	{"Seek", nil, false},
+	{"SelectCase", nil, false},
+	{"SelectIs", []string{"imp_"}, false},
+	{"SelectType", nil, false},
+	{"SetStmt", nil, false},
+	{"Stack", []string{"0x", "0x"}, false},
+	{"Stop", nil, false},
+	{"Type", []string{"rec_"}, false},
+	{"Unlock", nil, false},
+	{"VarDefn", []string{"var_"}, false},
+	{"Wend", nil, false},
+	{"While", nil, false},
+	{"With", nil, false},
+	{"WriteChan", nil, false},
+	{"ConstFuncExpr", nil, false},
+	{"LbConst", []string{"name"}, false},
+	{"LbIf", nil, false},
+	{"LbElse", nil, false},
+	{"LbElseIf", nil, false},
+	{"LbEndIf", nil, false},
+	{"LbMark", nil, false},
+	{"EndForVariable", nil, false},
+	{"StartForVariable", nil, false},
+	{"NewRedim", nil, false},
+	{"StartWithExpr", nil, false},
+	{"SetOrSt", []string{"name"}, false},
+	{"EndEnum", nil, false},
+	{"Illegal", nil, false},
+}
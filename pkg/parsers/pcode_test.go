@@ -0,0 +1,210 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/vbatest"
+)
+
+// Encode an instruction: an opcode and type, then its operands.
+func pcodeOp(opcode, opType uint16, operands ...any) []byte {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, opType<<10|opcode)
+	for _, operand := range operands {
+		switch v := operand.(type) {
+		case string:
+			binary.Write(buf, binary.LittleEndian, uint16(len(v)))
+			buf.WriteString(v)
+			if len(v)%2 != 0 {
+				buf.WriteByte(0)
+			}
+		default:
+			binary.Write(buf, binary.LittleEndian, v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestNewVBAProjectStream(t *testing.T) {
+	p, err := NewVBAProjectStream([]byte{0xCC, 0x61, 0xB2, 0x00, 0x00, 0x03, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version != 0xB2 || p.Identifiers != nil {
+		t.Errorf("got version %#x and %q, want 0xb2 and no identifiers", p.Version, p.Identifiers)
+	}
+
+	identifiers := []string{"AutoOpen", "Shell", "Tr\xe8s"}
+	stream := vbatest.Project(0xB2, identifiers)
+	if p, err = NewVBAProjectStream(stream); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Identifiers) != len(identifiers) {
+		t.Fatalf("got identifiers %q, want %q", p.Identifiers, identifiers)
+	}
+	for i := range identifiers {
+		if p.Identifiers[i] != identifiers[i] {
+			t.Errorf("got identifiers %q, want %q", p.Identifiers, identifiers)
+			break
+		}
+	}
+	// A cut-short cache, or one with names which can't be identifiers,
+	// has its table thrown away, but isn't an error.
+	if p, err = NewVBAProjectStream(stream[:len(stream)-10]); err != nil || p.Identifiers != nil {
+		t.Errorf("truncated: got %q and %v, want no identifiers", p.Identifiers, err)
+	}
+	garbled := vbatest.Project(0xB2, []string{"AutoOpen", "Sh\x01ll"})
+	if p, err = NewVBAProjectStream(garbled); err != nil || p.Identifiers != nil {
+		t.Errorf("garbled: got %q and %v, want no identifiers", p.Identifiers, err)
+	}
+	if p, err = NewVBAProjectStream(stream[:0x40]); err != nil || p.Identifiers != nil {
+		t.Errorf("no identifier table: got %q and %v", p.Identifiers, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"short":     {0xCC, 0x61, 0xB2},
+		"signature": {0xCD, 0x61, 0xB2, 0x00, 0x00, 0x03, 0x00},
+	} {
+		if _, err := NewVBAProjectStream(data); err == nil {
+			t.Errorf("%s: got no error", name)
+		}
+	}
+}
+
+func TestDisassemblePCode(t *testing.T) {
+	cases := []struct {
+		name    string
+		version uint16
+		is64bit bool
+		litStr  uint16
+
+		// ID of the project's second identifier, after those VBA 7
+		// keeps for itself.
+		shell uint16
+	}{
+		// LitStr comes after the three 8-byte literals, which 32-bit
+		// projects don't have.
+		{"64-bit", 0xB2, true, 185, 0x0210},
+		{"32-bit", 0xB2, false, 182, 0x020A},
+		{"VBA 6", 0x6B, false, 182, 0x0202},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			stream := vbatest.PCode([][]byte{
+				pcodeOp(150, 0, uint32(0x10)),
+				bytes.Join([][]byte{
+					pcodeOp(c.litStr, 0, "calc.exe"),
+					// Abs is built-in, the rest are the project's, and
+					// 0x0400 is past the end of its identifiers.
+					pcodeOp(32, 2, uint16(2<<1)),
+					pcodeOp(65, 0, c.shell, uint16(2)),
+					pcodeOp(65, 0, uint16(0x0400), uint16(0)),
+				}, nil),
+				nil,
+				pcodeOp(111, 0),
+			})
+			lines, err := DisassemblePCode(stream, c.version, []string{"AutoOpen", "Shell"}, c.is64bit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{
+				{"FuncDefn func_00000010"},
+				{`LitStr 0x0008 "calc.exe"`, "Ld Abs%", "ArgsCall (Call) Shell 0x0002", "ArgsCall (Call) id_0400 0x0000"},
+				nil,
+				{"EndSub"},
+			}
+			if len(lines) != len(want) {
+				t.Fatalf("got %d lines, want %d", len(lines), len(want))
+			}
+			for i, line := range lines {
+				got := []string{}
+				for _, instruction := range line.Instructions {
+					got = append(got, instruction.String())
+				}
+				if len(got) != len(want[i]) {
+					t.Errorf("line %d: got %q, want %q", i, got, want[i])
+					continue
+				}
+				for j := range got {
+					if got[j] != want[i][j] {
+						t.Errorf("line %d: got %q, want %q", i, got, want[i])
+						break
+					}
+				}
+			}
+			if lit := lines[1].Instructions[0].Literal; lit != "calc.exe" {
+				t.Errorf("got literal %q, want calc.exe", lit)
+			}
+			// Only resolved identifiers of the project's are listed.
+			for i, want := range [][]string{nil, nil, {"Shell"}, nil} {
+				if got := lines[1].Instructions[i].Identifiers; len(got) != len(want) || (len(want) > 0 && got[0] != want[0]) {
+					t.Errorf("instruction %d: got identifiers %q, want %q", i, got, want)
+				}
+			}
+			for i, want := range []bool{false, false, false, true} {
+				if got := lines[1].Instructions[i].Unresolved; got != want {
+					t.Errorf("instruction %d: got unresolved %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+// IDs VBA 7 keeps for itself come before the project's identifiers, and
+// aren't taken for them.
+func TestDisassemblePCodeReservedID(t *testing.T) {
+	cases := []struct {
+		name    string
+		is64bit bool
+		id      uint16
+	}{
+		{"64-bit", true, 0x020C},
+		{"32-bit", false, 0x0206},
+	}
+	for _, c := range cases {
+		stream := vbatest.PCode([][]byte{pcodeOp(65, 0, c.id, uint16(0))})
+		lines, err := DisassemblePCode(stream, 0xB2, []string{"AutoOpen", "Shell"}, c.is64bit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || len(lines[0].Instructions) != 1 {
+			t.Fatalf("%s: got %+v", c.name, lines)
+		}
+		i := lines[0].Instructions[0]
+		if !i.Unresolved || len(i.Identifiers) != 0 {
+			t.Errorf("%s: expected id %04X to be unresolved, got %+v", c.name, c.id, i)
+		}
+	}
+}
+
+func TestDisassemblePCodeMalformed(t *testing.T) {
+	stream := vbatest.PCode([][]byte{pcodeOp(150, 0, uint32(0x10))})
+	if _, err := DisassemblePCode(stream, 0x5E, nil, false); !errors.Is(err, ErrPCodeVersion) {
+		t.Errorf("old version: got %v, want ErrPCodeVersion", err)
+	}
+	if _, err := DisassemblePCode(stream[:0x30], 0xB2, nil, false); !errors.Is(err, ErrNoPCode) {
+		t.Errorf("truncated: got %v, want ErrNoPCode", err)
+	}
+	if _, err := DisassemblePCode(bytes.Repeat([]byte{0x01, 0x16, 0x03, 0x00}, 16), 0xB2, nil, false); !errors.Is(err, ErrNoPCode) {
+		t.Errorf("no p-code: got %v, want ErrNoPCode", err)
+	}
+
+	// Truncated instructions and unknown opcodes cut their line short.
+	stream = vbatest.PCode([][]byte{
+		pcodeOp(150, 0),
+		append(pcodeOp(1000, 0), pcodeOp(111, 0)...),
+	})
+	lines, err := DisassemblePCode(stream, 0xB2, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || len(lines[0].Instructions) != 1 || len(lines[1].Instructions) != 1 {
+		t.Fatalf("got %+v", lines)
+	}
+	if got := lines[1].Instructions[0].Mnemonic; got != "Unknown_03E8" {
+		t.Errorf("got %s, want Unknown_03E8", got)
+	}
+}
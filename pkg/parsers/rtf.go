@@ -0,0 +1,225 @@
+package parsers
+
+import (
+	"bufio"
+	"io"
+)
+
+// Longest control word and parameter we read, from [MS-RTF] 2.1.
+const (
+	rtfMaxControlWord = 32
+	rtfMaxParameter   = 10
+)
+
+// RTF object reader. Objects embedded in an RTF document are stored in
+// an \objdata destination, inside their \object group:
+//
+//	{\object\objemb{\*\objclass Word.Picture.8}{\*\objdata 01050000...}}
+//
+// The data is hex-encoded, and holds a serialized OLE 1.0 object (see
+// Ole1Object). Only as much of RTF is understood as it takes to find
+// the objects: everything outside \objdata is skipped. Inside it, hex
+// digits are decoded wherever they are, including nested groups, \bin
+// data is taken as is, and control words and anything else are skipped.
+type RTFReader struct {
+	r   *bufio.Reader
+	obj *rtfObject
+}
+
+// Create a reader for the objects in an RTF document.
+//
+//	Args:
+//		in (io.Reader):	The RTF document.
+//
+//	Returns:
+//		r (*RTFReader):	The reader.
+func NewRTFReader(in io.Reader) (r *RTFReader) {
+	r = &RTFReader{r: bufio.NewReader(in)}
+	return
+}
+
+// Next finds the next object in the document.
+//
+//	Returns:
+//		obj (io.Reader):	The object's data, decoded. It can only be
+//			read until Next is called again.
+//		err (error):	io.EOF if there are no more objects.
+func (r *RTFReader) Next() (obj io.Reader, err error) {
+	if r.obj != nil {
+		_, err = io.Copy(io.Discard, r.obj)
+		r.obj = nil
+		if err != nil {
+			return
+		}
+	}
+	for {
+		var c byte
+		c, err = r.r.ReadByte()
+		if err != nil {
+			return
+		}
+		if c != '\\' {
+			continue
+		}
+		var word string
+		var param int64
+		word, param, err = readRTFControl(r.r)
+		if err != nil {
+			return
+		}
+		switch word {
+		case "objdata":
+			r.obj = &rtfObject{r: r.r, high: -1}
+			obj = r.obj
+			return
+		case "bin":
+			_, err = r.r.Discard(int(param))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// rtfObject decodes the data in an \objdata destination, up to the
+// brace which closes it.
+type rtfObject struct {
+	r *bufio.Reader
+
+	// Groups opened inside the destination.
+	depth int
+
+	// The first digit of a byte, or -1 when we're between bytes.
+	high int
+
+	// Bytes of \bin data left to copy.
+	bin int64
+
+	done bool
+}
+
+func (o *rtfObject) Read(p []byte) (n int, err error) {
+	for n < len(p) && !o.done {
+		if o.bin > 0 {
+			var c byte
+			c, err = o.r.ReadByte()
+			if err != nil {
+				break
+			}
+			p[n] = c
+			n++
+			o.bin--
+			continue
+		}
+		var c byte
+		c, err = o.r.ReadByte()
+		if err != nil {
+			break
+		}
+		switch {
+		case c == '{':
+			o.depth++
+		case c == '}':
+			if o.depth == 0 {
+				o.done = true
+			}
+			o.depth--
+		case c == '\\':
+			var word string
+			var param int64
+			word, param, err = readRTFControl(o.r)
+			if word == "bin" {
+				o.bin = param
+				o.high = -1
+			}
+		case hexDigit(c) >= 0:
+			if o.high < 0 {
+				o.high = hexDigit(c)
+				continue
+			}
+			p[n] = byte(o.high<<4 | hexDigit(c))
+			n++
+			o.high = -1
+		}
+		if err != nil {
+			break
+		}
+	}
+	// A document which ends inside the destination still gives us
+	// what it has.
+	if err == io.EOF {
+		o.done = true
+	}
+	if err == nil && o.done {
+		err = io.EOF
+	}
+	return
+}
+
+// Read a control word or control symbol, after its backslash. Control
+// symbols come back with an empty word, and the \'hh symbol with the
+// two hex digits of the character it stands for. The parameter is 0 if
+// the word has none, or if it's negative.
+func readRTFControl(r *bufio.Reader) (word string, param int64, err error) {
+	if !nextIs(r, isASCIILetter) {
+		var c byte
+		c, err = r.ReadByte()
+		if err == nil && c == '\'' {
+			readRun(r, func(c byte) bool { return hexDigit(c) >= 0 }, 2)
+		}
+		return
+	}
+	word = readRun(r, isASCIILetter, rtfMaxControlWord)
+	negative := nextIs(r, func(c byte) bool { return c == '-' })
+	if negative {
+		r.ReadByte()
+	}
+	for _, c := range []byte(readRun(r, isDigit, rtfMaxParameter)) {
+		param = param*10 + int64(c-'0')
+	}
+	if negative {
+		param = 0
+	}
+	// A space ends the control word, anything else is the next character.
+	if nextIs(r, func(c byte) bool { return c == ' ' }) {
+		r.ReadByte()
+	}
+	return
+}
+
+// Whether the next byte satisfies f, without reading it.
+func nextIs(r *bufio.Reader, f func(byte) bool) bool {
+	next, err := r.Peek(1)
+	return err == nil && f(next[0])
+}
+
+// Read up to max bytes, for as long as they satisfy f.
+func readRun(r *bufio.Reader, f func(byte) bool, max int) string {
+	run := []byte{}
+	for len(run) < max && nextIs(r, f) {
+		c, _ := r.ReadByte()
+		run = append(run, c)
+	}
+	return string(run)
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// The value of a hex digit, or -1 if c isn't one.
+func hexDigit(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
@@ -0,0 +1,91 @@
+package parsers
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Read every object in an RTF document.
+func rtfObjects(t *testing.T, in io.Reader) (objects [][]byte) {
+	t.Helper()
+	r := NewRTFReader(in)
+	for {
+		obj, err := r.Next()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, data)
+	}
+}
+
+// Both pictures in the sample document are OLE 1.0 objects, carrying a
+// Word picture as native data, and a metafile of it.
+func TestRTFReaderSample(t *testing.T) {
+	f, err := os.Open(path.Join(pathToSampleDataDir, "rtf.rtf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	objects := rtfObjects(t, f)
+	if len(objects) != 2 {
+		t.Fatalf("object count mismatch - expected 2 got %d", len(objects))
+	}
+	for i, data := range objects {
+		o, err := NewOle1Object(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("object %d: %v", i, err)
+		}
+		if o.ClassName != "Word.Picture.8" || o.FormatID != Ole1FormatEmbedded || o.NativeSize != 20480 {
+			t.Errorf("object %d: header mismatch: %+v", i, o)
+		}
+		native := make([]byte, 8)
+		if _, err = o.Native().Read(native); err != nil || !bytes.Equal(native, []byte{0xd0, 0xcf, 0x11, 0xe0, 0xa1, 0xb1, 0x1a, 0xe1}) {
+			t.Errorf("object %d: expected an MS-CFB file as native data, got % x", i, native)
+		}
+		if o.Presentation == nil || o.Presentation.ClassName != Ole1PresentationMetafile {
+			t.Errorf("object %d: expected a metafile presentation, got %+v", i, o.Presentation)
+		}
+	}
+}
+
+// Line breaks, control words and \bin data in an object are dealt
+// with, and \bin data elsewhere isn't taken for one. Digits right
+// after \objdata are its parameter, as they are for any control word.
+func TestRTFReader(t *testing.T) {
+	cases := []struct {
+		name     string
+		doc      string
+		expected []string
+	}{
+		{"plain", `{\rtf1{\object\objemb{\*\objdata 4142` + "\r\n" + `4344}}}`, []string{"ABCD"}},
+		{"parameter", `{\rtf1{\*\objdata4142}{\*\objdata 43}}`, []string{"", "C"}},
+		{"control words", `{\rtf1{\*\objdata 41\par 42\'ff{\*\x 43}}}`, []string{"ABC"}},
+		{"bin", `{\rtf1{\*\objdata 41\bin3 }{\42}`, []string{"A}{\\B"}},
+		{"bin outside", `{\rtf1\bin10 \objdata}{\*\objdata 41}`, []string{"A"}},
+		{"escaped backslash", `{\rtf1 \\objdata {\*\objdata 41}}`, []string{"A"}},
+		{"odd digits", `{\rtf1{\*\objdata 414}}`, []string{"A"}},
+		{"unterminated", `{\rtf1{\*\objdata 4142`, []string{"AB"}},
+		{"none", `{\rtf1 objdata 4142}`, nil},
+	}
+	for _, c := range cases {
+		objects := rtfObjects(t, strings.NewReader(c.doc))
+		got := []string{}
+		for _, o := range objects {
+			got = append(got, string(o))
+		}
+		if strings.Join(got, "|") != strings.Join(c.expected, "|") || len(got) != len(c.expected) {
+			t.Errorf("%s: objects mismatch - expected %q got %q", c.name, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Module types, from the dir stream.
+const (
+	// Standard modules, saved as .bas files.
+	VBAModuleProcedural = "Procedural"
+
+	// Document, class and designer (form) modules, saved as .cls files.
+	VBAModuleClass = "Class"
+)
+
+// Platforms a project can be saved on.
+const (
+	VBASysKindWin16 = 0
+	VBASysKindWin32 = 1
+	VBASysKindMac   = 2
+	VBASysKindWin64 = 3
+)
+
+// Record IDs in the dir stream ([MS-OVBA] 2.3.4.2).
+const (
+	vbaProjectSysKind    = 0x0001
+	vbaProjectCodePage   = 0x0003
+	vbaProjectName       = 0x0004
+	vbaProjectVersion    = 0x0009
+	vbaDirTerminator     = 0x0010
+	vbaModuleName        = 0x0019
+	vbaModuleStreamName  = 0x001A
+	vbaModuleProcedural  = 0x0021
+	vbaModuleClass       = 0x0022
+	vbaModuleReadOnly    = 0x0025
+	vbaModulePrivate     = 0x0028
+	vbaModuleTerminator  = 0x002B
+	vbaModuleOffset      = 0x0031
+	vbaModuleStreamNameU = 0x0032
+	vbaModuleNameU       = 0x0047
+)
+
+// VBA project information, from the dir stream of a VBA storage. The
+// stream is an MS-OVBA compressed container, which decompresses to a
+// series of records ([MS-OVBA] 2.3.4.2, integers are little-endian):
+//
+//	uint16	Id
+//	uint32	Size
+//	[]byte	Data, Size bytes
+//
+// PROJECTVERSION is the exception: its Size is reserved, and always
+// followed by 6 bytes. Project records come first, then references,
+// then a record for each module's properties, ended by a terminator.
+type VBADir struct {
+	Name     string
+	CodePage uint16
+
+	// The platform the project was last saved on. One of the
+	// VBASysKind constants.
+	SysKind uint32
+
+	Modules []*VBAModule
+}
+
+// A module of a VBA project.
+type VBAModule struct {
+	Name string
+
+	// Name of the stream holding the module, in the VBA storage.
+	StreamName string
+
+	// One of the VBAModule constants.
+	Type string
+
+	// Offset of the compressed source code in the module stream.
+	// Ahead of it is the module's compiled p-code.
+	TextOffset uint32
+
+	ReadOnly bool
+	Private  bool
+}
+
+// Extension returns the file extension used for the module's source.
+func (m *VBAModule) Extension() string {
+	if m.Type == VBAModuleProcedural {
+		return ".bas"
+	}
+	return ".cls"
+}
+
+// Parse a dir stream.
+//
+//	Args:
+//		data ([]byte):	The dir stream, still compressed.
+//
+//	Returns:
+//		d (*VBADir):	The project information.
+//		err (error):	Non-nil if the stream can't be parsed. Modules
+//				read up to that point are kept.
+func NewVBADir(data []byte) (d *VBADir, err error) {
+	d = &VBADir{}
+	decompressed, err := DecompressOVBA(data)
+	if err != nil {
+		err = fmt.Errorf("%w: decompressing the dir stream", err)
+		return
+	}
+	r := bytes.NewReader(decompressed)
+	var module *VBAModule
+	for r.Len() > 0 {
+		var header struct {
+			ID   uint16
+			Size uint32
+		}
+		err = binary.Read(r, binary.LittleEndian, &header)
+		if err != nil {
+			err = fmt.Errorf("%w: getting a record header", err)
+			return
+		}
+		size := int64(header.Size)
+		if header.ID == vbaProjectVersion {
+			size = 6
+		}
+		if size > int64(r.Len()) {
+			err = fmt.Errorf("record 0x%04X of %d bytes overruns the dir stream (%d bytes left)", header.ID, size, r.Len())
+			return
+		}
+		record := make([]byte, size)
+		_, err = io.ReadFull(r, record)
+		if err != nil {
+			return
+		}
+
+		switch header.ID {
+		case vbaProjectSysKind:
+			if len(record) >= 4 {
+				d.SysKind = binary.LittleEndian.Uint32(record)
+			}
+		case vbaProjectCodePage:
+			if len(record) >= 2 {
+				d.CodePage = binary.LittleEndian.Uint16(record)
+			}
+		case vbaProjectName:
+			d.Name = string(record)
+		case vbaDirTerminator:
+			return
+		case vbaModuleName:
+			module = &VBAModule{Name: string(record), Type: VBAModuleProcedural}
+			d.Modules = append(d.Modules, module)
+		case vbaModuleTerminator:
+			module = nil
+		}
+		if module == nil {
+			continue
+		}
+		switch header.ID {
+		case vbaModuleNameU:
+			module.Name = decodeUTF16(record)
+		case vbaModuleStreamName:
+			module.StreamName = string(record)
+		case vbaModuleStreamNameU:
+			module.StreamName = decodeUTF16(record)
+		case vbaModuleOffset:
+			if len(record) >= 4 {
+				module.TextOffset = binary.LittleEndian.Uint32(record)
+			}
+		case vbaModuleProcedural:
+			module.Type = VBAModuleProcedural
+		case vbaModuleClass:
+			module.Type = VBAModuleClass
+		case vbaModuleReadOnly:
+			module.ReadOnly = true
+		case vbaModulePrivate:
+			module.Private = true
+		}
+	}
+	return
+}
+
+// Source decompresses a module's source code.
+//
+//	Args:
+//		stream ([]byte):	The module stream.
+//
+//	Returns:
+//		source ([]byte):	The source code, in the project's code page.
+//		err (error):		Non-nil if the source can't be decompressed.
+func (m *VBAModule) Source(stream []byte) (source []byte, err error) {
+	if int64(m.TextOffset) > int64(len(stream)) {
+		err = fmt.Errorf("source offset of %d is past the end of the %d byte module stream", m.TextOffset, len(stream))
+		return
+	}
+	source, err = DecompressOVBA(stream[m.TextOffset:])
+	return
+}
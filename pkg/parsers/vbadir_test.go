@@ -0,0 +1,102 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/vbatest"
+)
+
+// Append a dir stream record.
+func dirRecord(buf *bytes.Buffer, id uint16, data []byte) {
+	binary.Write(buf, binary.LittleEndian, id)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+}
+
+// Build a dir stream for a project with the given modules.
+func buildVBADir(project string, modules []*VBAModule) []byte {
+	buf := &bytes.Buffer{}
+	dirRecord(buf, vbaProjectSysKind, []byte{3, 0, 0, 0})
+	dirRecord(buf, 0x0003, []byte{0xE4, 0x04})
+	dirRecord(buf, vbaProjectName, []byte(project))
+	// PROJECTVERSION's size is reserved, and doesn't count its data.
+	binary.Write(buf, binary.LittleEndian, uint16(vbaProjectVersion))
+	binary.Write(buf, binary.LittleEndian, uint32(4))
+	buf.Write([]byte{0xA5, 0x1B, 0x6B, 0x49, 0x03, 0x00})
+	dirRecord(buf, 0x000F, []byte{byte(len(modules)), 0})
+	for _, m := range modules {
+		dirRecord(buf, vbaModuleName, []byte(m.Name))
+		dirRecord(buf, vbaModuleNameU, utf16Bytes(m.Name))
+		dirRecord(buf, vbaModuleStreamName, []byte(m.StreamName))
+		dirRecord(buf, vbaModuleStreamNameU, utf16Bytes(m.StreamName))
+		offset := make([]byte, 4)
+		binary.LittleEndian.PutUint32(offset, m.TextOffset)
+		dirRecord(buf, vbaModuleOffset, offset)
+		if m.Type == VBAModuleProcedural {
+			dirRecord(buf, vbaModuleProcedural, nil)
+		} else {
+			dirRecord(buf, vbaModuleClass, nil)
+		}
+		if m.Private {
+			dirRecord(buf, vbaModulePrivate, nil)
+		}
+		dirRecord(buf, vbaModuleTerminator, nil)
+	}
+	dirRecord(buf, vbaDirTerminator, nil)
+	return vbatest.Compress(buf.Bytes())
+}
+
+func TestVBADir(t *testing.T) {
+	modules := []*VBAModule{
+		{Name: "ThisDocument", StreamName: "ThisDocument", Type: VBAModuleClass, TextOffset: 0x3E1},
+		{Name: "Módulo1", StreamName: "Module1", Type: VBAModuleProcedural, TextOffset: 0x22F, Private: true},
+	}
+	d, err := NewVBADir(buildVBADir("Project", modules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "Project" || d.CodePage != 1252 || d.SysKind != VBASysKindWin64 {
+		t.Errorf("project mismatch: %+v", d)
+	}
+	if len(d.Modules) != len(modules) {
+		t.Fatalf("expected %d modules, got %d", len(modules), len(d.Modules))
+	}
+	for i, m := range d.Modules {
+		if *m != *modules[i] {
+			t.Errorf("module %d mismatch - expected %+v got %+v", i, modules[i], m)
+		}
+	}
+	if d.Modules[0].Extension() != ".cls" || d.Modules[1].Extension() != ".bas" {
+		t.Errorf("extension mismatch")
+	}
+}
+
+func TestVBAModuleSource(t *testing.T) {
+	source := "Attribute VB_Name = \"Module1\"\r\nSub AutoOpen()\r\nEnd Sub\r\n"
+	stream := append(bytes.Repeat([]byte{0xCC}, 0x20), vbatest.Compress([]byte(source))...)
+	m := &VBAModule{TextOffset: 0x20}
+	out, err := m.Source(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != source {
+		t.Errorf("expected %q got %q", source, out)
+	}
+	m.TextOffset = 0x1000
+	if _, err = m.Source(stream); err == nil {
+		t.Errorf("expected an error for an offset past the end of the stream")
+	}
+}
+
+// Truncated records are errors, not panics.
+func TestVBADirTruncated(t *testing.T) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, uint16(vbaModuleName))
+	binary.Write(buf, binary.LittleEndian, uint32(100))
+	buf.WriteString("Module1")
+	if _, err := NewVBADir(vbatest.Compress(buf.Bytes())); err == nil {
+		t.Errorf("expected an error for a truncated record")
+	}
+}
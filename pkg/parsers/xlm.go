@@ -0,0 +1,430 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Binary operators, by Ptg ([MS-XLS] 2.5.198.25).
+var ptgOperators = map[byte]string{
+	0x03: "+", 0x04: "-", 0x05: "*", 0x06: "/", 0x07: "^", 0x08: "&",
+	0x09: "<", 0x0A: "<=", 0x0B: "=", 0x0C: ">=", 0x0D: ">", 0x0E: "<>",
+	0x0F: " ", 0x10: ",", 0x11: ":",
+}
+
+var ptgErrors = map[byte]string{
+	0x00: "#NULL!", 0x07: "#DIV/0!", 0x0F: "#VALUE!", 0x17: "#REF!",
+	0x1D: "#NAME?", 0x24: "#NUM!", 0x2A: "#N/A",
+}
+
+// Decode a parsed expression into formula text, without the leading "=".
+// BIFF8 and BIFF12 (.xlsb) expressions are the same, apart from the sizes
+// of rows and strings. Expressions are in reverse Polish notation: a series of Ptg tokens,
+// each a byte of type followed by its data ([MS-XLS] 2.5.198). Operands
+// are pushed on a stack, and operators and functions pop their
+// arguments off it. Array constants keep their values after the
+// expression, and are shown as {...}.
+//
+// If the expression can't be decoded, text is what was decoded up to
+// that point.
+func (w *Workbook) decodeFormula(rgce []byte) (text string, err error) {
+	stack := []string{}
+	pop := func(n int) (args []string) {
+		if n > len(stack) {
+			n = len(stack)
+		}
+		args = append(args, stack[len(stack)-n:]...)
+		stack = stack[:len(stack)-n]
+		return
+	}
+	pos := 0
+	need := func(n int) bool {
+		if pos+n > len(rgce) {
+			err = fmt.Errorf("Ptg at %d overruns the expression", pos-1)
+			return false
+		}
+		return true
+	}
+	for pos < len(rgce) && err == nil {
+		ptg := rgce[pos]
+		pos++
+		if op, ok := ptgOperators[ptg]; ok {
+			args := pop(2)
+			stack = append(stack, strings.Join(args, op))
+			continue
+		}
+		// Operands come in reference, value and array classes, in
+		// bits 5 and 6. They're decoded the same.
+		base := ptg
+		if ptg >= 0x20 {
+			base = ptg&0x1F | 0x20
+		}
+		switch base {
+		case 0x01, 0x02: // PtgExp, PtgTbl
+			if w.biff12 {
+				// Only the row is given, the column is the formula's own.
+				if !need(4) {
+					break
+				}
+				stack = append(stack, fmt.Sprintf("(shared formula at row %d)", binary.LittleEndian.Uint32(rgce[pos:])+1))
+				pos += 4
+				break
+			}
+			if !need(4) {
+				break
+			}
+			row, col := binary.LittleEndian.Uint16(rgce[pos:]), binary.LittleEndian.Uint16(rgce[pos+2:])
+			pos += 4
+			stack = append(stack, fmt.Sprintf("(shared formula at %s)", cellName(uint32(row), col, false, false)))
+		case 0x12: // PtgUplus
+			stack = append(stack, "+"+strings.Join(pop(1), ""))
+		case 0x13: // PtgUminus
+			stack = append(stack, "-"+strings.Join(pop(1), ""))
+		case 0x14: // PtgPercent
+			stack = append(stack, strings.Join(pop(1), "")+"%")
+		case 0x15: // PtgParen
+			stack = append(stack, "("+strings.Join(pop(1), "")+")")
+		case 0x16: // PtgMissArg
+			stack = append(stack, "")
+		case 0x17: // PtgStr
+			if !need(2) {
+				break
+			}
+			var s string
+			var n int
+			if w.biff12 {
+				s, n = wideString(rgce[pos:], 2)
+			} else {
+				s, n = shortXLString(rgce[pos:])
+			}
+			pos += n
+			stack = append(stack, `"`+strings.ReplaceAll(s, `"`, `""`)+`"`)
+		case 0x19: // PtgAttr
+			if !need(3) {
+				break
+			}
+			flags, count := rgce[pos], int(binary.LittleEndian.Uint16(rgce[pos+1:]))
+			pos += 3
+			switch {
+			case flags&0x04 != 0: // tAttrChoose, with a jump table
+				pos += (count + 1) * 2
+			case flags&0x10 != 0: // tAttrSum
+				stack = append(stack, "SUM("+strings.Join(pop(1), "")+")")
+			}
+		case 0x1C: // PtgErr
+			if !need(1) {
+				break
+			}
+			e, ok := ptgErrors[rgce[pos]]
+			if !ok {
+				e = fmt.Sprintf("#ERR%d!", rgce[pos])
+			}
+			pos++
+			stack = append(stack, e)
+		case 0x1D: // PtgBool
+			if !need(1) {
+				break
+			}
+			stack = append(stack, map[bool]string{true: "TRUE", false: "FALSE"}[rgce[pos] != 0])
+			pos++
+		case 0x1E: // PtgInt
+			if !need(2) {
+				break
+			}
+			stack = append(stack, fmt.Sprint(binary.LittleEndian.Uint16(rgce[pos:])))
+			pos += 2
+		case 0x1F: // PtgNum
+			if !need(8) {
+				break
+			}
+			f := math.Float64frombits(binary.LittleEndian.Uint64(rgce[pos:]))
+			stack = append(stack, strconv.FormatFloat(f, 'g', -1, 64))
+			pos += 8
+		case 0x20: // PtgArray
+			if w.biff12 {
+				err = fmt.Errorf("BIFF12 array constants aren't supported")
+				break
+			}
+			if !need(7) {
+				break
+			}
+			pos += 7
+			stack = append(stack, "{...}")
+		case 0x21: // PtgFunc
+			if !need(2) {
+				break
+			}
+			index := binary.LittleEndian.Uint16(rgce[pos:])
+			pos += 2
+			count, ok := xlmFixedArgs[index]
+			if !ok {
+				err = fmt.Errorf("argument count of %s is unknown", xlmFunctionName(index))
+				break
+			}
+			stack = append(stack, xlmFunctionName(index)+"("+strings.Join(pop(count), ",")+")")
+		case 0x22: // PtgFuncVar
+			if !need(3) {
+				break
+			}
+			count := int(rgce[pos] & 0x7F)
+			index := binary.LittleEndian.Uint16(rgce[pos+1:])
+			pos += 3
+			args := pop(count)
+			var name string
+			switch {
+			case index&0x8000 != 0:
+				name = xlmCommandName(index & 0x7FFF)
+			case index == 0x00FF && len(args) > 0:
+				// User-defined functions are named by their first argument.
+				name, args = args[0], args[1:]
+			default:
+				name = xlmFunctionName(index)
+			}
+			stack = append(stack, name+"("+strings.Join(args, ",")+")")
+		case 0x23: // PtgName
+			if !need(4) {
+				break
+			}
+			index := int(binary.LittleEndian.Uint32(rgce[pos:]))
+			pos += 4
+			if index > 0 && index <= len(w.Names) && w.Names[index-1].Name != "" {
+				stack = append(stack, w.Names[index-1].Name)
+			} else {
+				stack = append(stack, fmt.Sprintf("NAME%d", index))
+			}
+		case 0x24, 0x2A, 0x2C: // PtgRef, PtgRefErr, PtgRefN
+			if !need(w.refSize()) {
+				break
+			}
+			stack = append(stack, w.ref(base, rgce[pos:]))
+			pos += w.refSize()
+		case 0x25, 0x2B, 0x2D: // PtgArea, PtgAreaErr, PtgAreaN
+			if !need(w.refSize() * 2) {
+				break
+			}
+			stack = append(stack, w.area(base, rgce[pos:]))
+			pos += w.refSize() * 2
+		case 0x26, 0x27, 0x28: // PtgMemArea, PtgMemErr, PtgMemNoMem
+			// The subexpression that follows is decoded as usual.
+			pos += 6
+		case 0x29, 0x2E: // PtgMemFunc, PtgMemAreaN
+			pos += 2
+		case 0x39: // PtgNameX
+			if !need(6) {
+				break
+			}
+			xti := binary.LittleEndian.Uint16(rgce[pos:])
+			index := int(binary.LittleEndian.Uint32(rgce[pos+2:]))
+			pos += 6
+			stack = append(stack, w.externName(xti, index))
+		case 0x3A, 0x3C: // PtgRef3d, PtgRefErr3d
+			if !need(2 + w.refSize()) {
+				break
+			}
+			stack = append(stack, w.sheetName(binary.LittleEndian.Uint16(rgce[pos:]))+"!"+w.ref(base, rgce[pos+2:]))
+			pos += 2 + w.refSize()
+		case 0x3B, 0x3D: // PtgArea3d, PtgAreaErr3d
+			if !need(2 + w.refSize()*2) {
+				break
+			}
+			stack = append(stack, w.sheetName(binary.LittleEndian.Uint16(rgce[pos:]))+"!"+w.area(base, rgce[pos+2:]))
+			pos += 2 + w.refSize()*2
+		default:
+			err = fmt.Errorf("unknown Ptg 0x%02X", ptg)
+		}
+	}
+	if pos > len(rgce) && err == nil {
+		err = fmt.Errorf("Ptg overruns the expression")
+	}
+	text = strings.Join(stack, " ")
+	return
+}
+
+// Sizes of references in BIFF8 and BIFF12. Rows are uint16 in BIFF8,
+// and uint32 in BIFF12. Columns are a uint16, with bit 14 set if the
+// column is relative, and bit 15 if the row is.
+func (w *Workbook) refSize() int {
+	if w.biff12 {
+		return 6
+	}
+	return 4
+}
+
+// Format a reference. Error references point at deleted cells.
+func (w *Workbook) ref(ptg byte, data []byte) string {
+	if ptg == 0x2A || ptg == 0x3C {
+		return "#REF!"
+	}
+	var row uint32
+	var col uint16
+	if w.biff12 {
+		row, col = binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint16(data[4:])
+	} else {
+		row, col = uint32(binary.LittleEndian.Uint16(data)), binary.LittleEndian.Uint16(data[2:])
+	}
+	if ptg == 0x2C {
+		return w.relativeRef(row, col)
+	}
+	return cellName(row, col&0x3FFF, col&0x8000 == 0, col&0x4000 == 0)
+}
+
+// Format an area: its first and last rows, then its first and last columns.
+func (w *Workbook) area(ptg byte, data []byte) string {
+	if ptg == 0x2B || ptg == 0x3D {
+		return "#REF!"
+	}
+	var rows []uint32
+	var cols []uint16
+	if w.biff12 {
+		rows = []uint32{binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint32(data[4:])}
+		cols = []uint16{binary.LittleEndian.Uint16(data[8:]), binary.LittleEndian.Uint16(data[10:])}
+	} else {
+		rows = []uint32{uint32(binary.LittleEndian.Uint16(data)), uint32(binary.LittleEndian.Uint16(data[2:]))}
+		cols = []uint16{binary.LittleEndian.Uint16(data[4:]), binary.LittleEndian.Uint16(data[6:])}
+	}
+	corners := make([]string, 2)
+	for i := range corners {
+		if ptg == 0x2D {
+			corners[i] = w.relativeRef(rows[i], cols[i])
+		} else {
+			corners[i] = cellName(rows[i], cols[i]&0x3FFF, cols[i]&0x8000 == 0, cols[i]&0x4000 == 0)
+		}
+	}
+	return corners[0] + ":" + corners[1]
+}
+
+// Format a reference from a shared formula or name, where relative parts
+// are signed offsets from the cell the formula is in.
+func (w *Workbook) relativeRef(row uint32, col uint16) string {
+	r := fmt.Sprintf("R%d", row+1)
+	if col&0x8000 != 0 {
+		if w.biff12 {
+			r = fmt.Sprintf("R[%d]", int32(row))
+		} else {
+			r = fmt.Sprintf("R[%d]", int16(row))
+		}
+	}
+	c := fmt.Sprintf("C%d", col&0x3FFF+1)
+	if col&0x4000 != 0 {
+		if w.biff12 {
+			// Sign-extend the 14-bit offset.
+			c = fmt.Sprintf("C[%d]", int16(col<<2)>>2)
+		} else {
+			c = fmt.Sprintf("C[%d]", int8(col))
+		}
+	}
+	return r + c
+}
+
+// externName returns the name of an external name, like an add-in
+// function.
+func (w *Workbook) externName(xti uint16, index int) string {
+	if int(xti) < len(w.externSheets) {
+		if sb := int(w.externSheets[xti].supBook); sb < len(w.supBooks) {
+			if names := w.supBooks[sb].names; index > 0 && index <= len(names) {
+				return names[index-1]
+			}
+		}
+	}
+	return fmt.Sprintf("EXTERNNAME%d", index)
+}
+
+func xlmFunctionName(index uint16) string {
+	if int(index) < len(xlmFunctions) && xlmFunctions[index] != "" {
+		return xlmFunctions[index]
+	}
+	return fmt.Sprintf("FUNC%d", index)
+}
+
+func xlmCommandName(index uint16) string {
+	if int(index) < len(xlmCommands) && xlmCommands[index] != "" {
+		return xlmCommands[index]
+	}
+	return fmt.Sprintf("CMD%d", index)
+}
+
+// Names of built-in functions, by Ftab index ([MS-XLS] 2.5.198.17).
+var xlmFunctions = []string{
+	"COUNT", "IF", "ISNA", "ISERROR", "SUM", "AVERAGE", "MIN", "MAX",
+	"ROW", "COLUMN", "NA", "NPV", "STDEV", "DOLLAR", "FIXED", "SIN",
+	"COS", "TAN", "ATAN", "PI", "SQRT", "EXP", "LN", "LOG10",
+	"ABS", "INT", "SIGN", "ROUND", "LOOKUP", "INDEX", "REPT", "MID",
+	"LEN", "VALUE", "TRUE", "FALSE", "AND", "OR", "NOT", "MOD",
+	"DCOUNT", "DSUM", "DAVERAGE", "DMIN", "DMAX", "DSTDEV", "VAR", "DVAR",
+	"TEXT", "LINEST", "TREND", "LOGEST", "GROWTH", "GOTO", "HALT", "RETURN",
+	"PV", "FV", "NPER", "PMT", "RATE", "MIRR", "IRR", "RAND",
+	"MATCH", "DATE", "TIME", "DAY", "MONTH", "YEAR", "WEEKDAY", "HOUR",
+	"MINUTE", "SECOND", "NOW", "AREAS", "ROWS", "COLUMNS", "OFFSET", "ABSREF",
+	"RELREF", "ARGUMENT", "SEARCH", "TRANSPOSE", "ERROR", "STEP", "TYPE", "ECHO",
+	"SET.NAME", "CALLER", "DEREF", "WINDOWS", "SERIES", "DOCUMENTS", "ACTIVE.CELL", "SELECTION",
+	"RESULT", "ATAN2", "ASIN", "ACOS", "CHOOSE", "HLOOKUP", "VLOOKUP", "LINKS",
+	"INPUT", "ISREF", "GET.FORMULA", "GET.NAME", "SET.VALUE", "LOG", "EXEC", "CHAR",
+	"LOWER", "UPPER", "PROPER", "LEFT", "RIGHT", "EXACT", "TRIM", "REPLACE",
+	"SUBSTITUTE", "CODE", "NAMES", "DIRECTORY", "FIND", "CELL", "ISERR", "ISTEXT",
+	"ISNUMBER", "ISBLANK", "T", "N", "FOPEN", "FCLOSE", "FSIZE", "FREADLN",
+	"FREAD", "FWRITELN", "FWRITE", "FPOS", "DATEVALUE", "TIMEVALUE", "SLN", "SYD",
+	"DDB", "GET.DEF", "REFTEXT", "TEXTREF", "INDIRECT", "REGISTER", "CALL", "ADD.BAR",
+	"ADD.MENU", "ADD.COMMAND", "ENABLE.COMMAND", "CHECK.COMMAND", "RENAME.COMMAND", "SHOW.BAR", "DELETE.MENU", "DELETE.COMMAND",
+	"GET.CHART.ITEM", "DIALOG.BOX", "CLEAN", "MDETERM", "MINVERSE", "MMULT", "FILES", "IPMT",
+	"PPMT", "COUNTA", "CANCEL.KEY", "FOR", "WHILE", "BREAK", "NEXT", "INITIATE",
+	"REQUEST", "POKE", "EXECUTE", "TERMINATE", "RESTART", "HELP", "GET.BAR", "PRODUCT",
+	"FACT", "GET.CELL", "GET.WORKSPACE", "GET.WINDOW", "GET.DOCUMENT", "DPRODUCT", "ISNONTEXT", "GET.NOTE",
+	"NOTE", "STDEVP", "VARP", "DSTDEVP", "DVARP", "TRUNC", "ISLOGICAL", "DCOUNTA",
+	"DELETE.BAR", "UNREGISTER", "", "", "USDOLLAR", "FINDB", "SEARCHB", "REPLACEB",
+	"LEFTB", "RIGHTB", "MIDB", "LENB", "ROUNDUP", "ROUNDDOWN", "ASC", "DBCS",
+	"RANK", "", "", "ADDRESS", "DAYS360", "TODAY", "VDB", "",
+	"", "", "", "MEDIAN", "SUMPRODUCT", "SINH", "COSH", "TANH",
+	"ASINH", "ACOSH", "ATANH", "DGET", "CREATE.OBJECT", "VOLATILE", "LAST.ERROR", "CUSTOM.UNDO",
+	"CUSTOM.REPEAT", "FORMULA.CONVERT", "GET.LINK.INFO", "TEXT.BOX", "INFO", "GROUP", "GET.OBJECT", "DB",
+	"PAUSE", "", "", "RESUME", "FREQUENCY", "ADD.TOOLBAR", "DELETE.TOOLBAR", "",
+	"RESET.TOOLBAR", "EVALUATE", "GET.TOOLBAR", "GET.TOOL", "SPELLING.CHECK", "ERROR.TYPE", "APP.TITLE", "WINDOW.TITLE",
+	"SAVE.TOOLBAR", "ENABLE.TOOL", "PRESS.TOOL", "REGISTER.ID", "GET.WORKBOOK", "AVEDEV", "BETADIST", "GAMMALN",
+	"BETAINV", "BINOMDIST", "CHIDIST", "CHIINV", "COMBIN", "CONFIDENCE", "CRITBINOM", "EVEN",
+	"EXPONDIST", "FDIST", "FINV", "FISHER", "FISHERINV", "FLOOR", "GAMMADIST", "GAMMAINV",
+	"CEILING", "HYPGEOMDIST", "LOGNORMDIST", "LOGINV", "NEGBINOMDIST", "NORMDIST", "NORMSDIST", "NORMINV",
+	"NORMSINV", "STANDARDIZE", "ODD", "PERMUT", "POISSON", "TDIST", "WEIBULL", "SUMXMY2",
+	"SUMX2MY2", "SUMX2PY2", "CHITEST", "CORREL", "COVAR", "FORECAST", "FTEST", "INTERCEPT",
+	"PEARSON", "RSQ", "STEYX", "SLOPE", "TTEST", "PROB", "DEVSQ", "GEOMEAN",
+	"HARMEAN", "SUMSQ", "KURT", "SKEW", "ZTEST", "LARGE", "SMALL", "QUARTILE",
+	"PERCENTILE", "PERCENTRANK", "MODE", "TRIMMEAN", "TINV", "", "MOVIE.COMMAND", "GET.MOVIE",
+	"CONCATENATE", "POWER", "PIVOT.ADD.DATA", "GET.PIVOT.TABLE", "GET.PIVOT.FIELD", "GET.PIVOT.ITEM", "RADIANS", "DEGREES",
+	"SUBTOTAL", "SUMIF", "COUNTIF", "COUNTBLANK", "SCENARIO.GET", "OPTIONS.LISTS.GET", "ISPMT", "DATEDIF",
+	"DATESTRING", "NUMBERSTRING", "ROMAN",
+}
+
+// Argument counts of the functions PtgFunc can call, which take a fixed
+// number of arguments. The rest are called with PtgFuncVar, which gives
+// the count.
+var xlmFixedArgs = map[uint16]int{
+	2: 1, 3: 1, 10: 0, 15: 1, 16: 1, 17: 1, 18: 1, 19: 0, 20: 1,
+	21: 1, 22: 1, 23: 1, 24: 1, 25: 1, 26: 1, 27: 2, 30: 2, 31: 3, 32: 1,
+	33: 1, 34: 0, 35: 0, 38: 1, 39: 2, 40: 3, 41: 3, 42: 3, 43: 3, 44: 3,
+	45: 3, 47: 3, 48: 2, 53: 1, 61: 3, 63: 0, 65: 3, 66: 3, 67: 1, 68: 1,
+	69: 1, 71: 1, 72: 1, 73: 1, 74: 0, 75: 1, 76: 1, 77: 1, 79: 2, 80: 2,
+	83: 1, 86: 1, 89: 0, 90: 1, 94: 0, 95: 0, 97: 2, 98: 1, 99: 1,
+	105: 1, 106: 1, 108: 2, 111: 1, 112: 1, 113: 1, 114: 1, 117: 2,
+	118: 1, 119: 4, 121: 1, 126: 1, 127: 1, 128: 1, 129: 1, 130: 1, 131: 1,
+	133: 1, 134: 1, 135: 1, 136: 2, 137: 2, 138: 2, 140: 1, 141: 1, 142: 3,
+	143: 4, 162: 1, 163: 1, 164: 1, 165: 2, 176: 2, 177: 3, 178: 2, 179: 1,
+	184: 1, 186: 1, 189: 3, 190: 1, 195: 3, 196: 3, 198: 1, 199: 3, 201: 1,
+	207: 4, 210: 3, 211: 1, 212: 2, 213: 2, 214: 1, 215: 1, 221: 0, 229: 1,
+	230: 1, 231: 1, 232: 1, 233: 1, 234: 1, 235: 3, 244: 1, 252: 2, 257: 1,
+	261: 1, 271: 1, 273: 4, 274: 2, 275: 2, 276: 2, 277: 3, 278: 3, 279: 1,
+	280: 3, 281: 3, 282: 3, 283: 1, 284: 1, 285: 2, 286: 4, 287: 3, 288: 2,
+	289: 4, 290: 3, 291: 3, 292: 3, 293: 4, 294: 1, 295: 3, 296: 1, 297: 3,
+	298: 1, 299: 2, 300: 3, 301: 3, 302: 4, 303: 2, 304: 2, 305: 2, 306: 2,
+	307: 2, 308: 2, 309: 3, 310: 2, 311: 2, 312: 2, 313: 2, 314: 2, 315: 2,
+	316: 4, 325: 2, 326: 2, 327: 2, 328: 2, 331: 2, 332: 2, 337: 2, 342: 1,
+	343: 1, 345: 2, 346: 2, 347: 1, 350: 4, 351: 3,
+}
+
+// Names of macro commands, by Cetab index ([MS-XLS] 2.5.198.18), for the
+// ones malicious macros lean on. The rest are listed by number.
+var xlmCommands = []string{
+	"BEEP", "OPEN", "OPEN.LINKS", "CLOSE.ALL", "SAVE", "SAVE.AS", "FILE.DELETE", "PAGE.SETUP",
+	"PRINT", "PRINTER.SETUP", "QUIT", "NEW.WINDOW", "ARRANGE.ALL", "WINDOW.SIZE", "WINDOW.MOVE", "FULL",
+	"CLOSE", "RUN",
+}
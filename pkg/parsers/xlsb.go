@@ -0,0 +1,282 @@
+package parsers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	ErrNotXLSBWorkbook = errors.New("not an .xlsb workbook part")
+)
+
+// Record types in the BIFF12 parts of an .xlsb file ([MS-XLSB] 2.3).
+const (
+	brtRowHdr      = 0
+	brtFmlaString  = 8
+	brtFmlaNum     = 9
+	brtFmlaBool    = 10
+	brtFmlaError   = 11
+	brtName        = 39
+	brtBeginBook   = 131
+	brtBundleSh    = 156
+	brtSupSelf     = 357
+	brtSupSame     = 358
+	brtSupBookSrc  = 360
+	brtExternSheet = 362
+	brtSupAddin    = 667
+)
+
+// Largest BIFF12 record we'll accept.
+const maxBIFF12Record = 1 << 24
+
+// A BIFF12 record.
+type biff12Record struct {
+	recordType int
+	data       []byte
+}
+
+// Read the records of a BIFF12 part. Each starts with its type and size,
+// as variable-length integers: 7 bits to a byte, with the high bit set
+// if another byte follows. Types take up to 2 bytes, and sizes up to 4.
+func biff12Records(data []byte) (records []biff12Record, err error) {
+	pos := 0
+	varint := func(maxBytes int) (v int, ok bool) {
+		for i := 0; i < maxBytes; i++ {
+			if pos >= len(data) {
+				return
+			}
+			b := data[pos]
+			pos++
+			v |= int(b&0x7F) << (7 * i)
+			if b&0x80 == 0 {
+				return v, true
+			}
+		}
+		return v, true
+	}
+	for pos < len(data) {
+		start := pos
+		recordType, ok := varint(2)
+		if !ok {
+			err = fmt.Errorf("record header at offset %d is truncated", start)
+			return
+		}
+		size, ok := varint(4)
+		if !ok || size > maxBIFF12Record || pos+size > len(data) {
+			err = fmt.Errorf("record %d at offset %d overruns the part", recordType, start)
+			return
+		}
+		records = append(records, biff12Record{recordType, data[pos : pos+size]})
+		pos += size
+	}
+	return
+}
+
+// Parse the workbook part of an .xlsb file, xl/workbook.bin. Sheets are
+// listed with the relationship IDs of their parts. Their types come from
+// the relationships, given by relTypes.
+//
+//	Args:
+//		data ([]byte):			The workbook part.
+//		relTypes (map[string]string):	Relationship types, by ID, from the
+//						part's relationships.
+//
+//	Returns:
+//		w (*Workbook):	The workbook.
+//		err (error):	Non-nil if the part can't be parsed.
+func NewXLSBWorkbook(data []byte, relTypes map[string]string) (w *Workbook, err error) {
+	w = &Workbook{biff12: true}
+	records, err := biff12Records(data)
+	if len(records) == 0 || records[0].recordType != brtBeginBook {
+		err = ErrNotXLSBWorkbook
+		return
+	}
+	var names [][]byte
+	for _, r := range records {
+		switch r.recordType {
+		case brtBundleSh:
+			// uint32 state, uint32 tab ID, then the relationship ID
+			// and name, as wide strings.
+			if len(r.data) < 8 {
+				continue
+			}
+			sheet := &Sheet{Visibility: sheetVisibility(binary.LittleEndian.Uint32(r.data))}
+			relID, n := wideString(r.data[8:], 4)
+			sheet.Name, _ = wideString(r.data[8+n:], 4)
+			sheet.RelID = relID
+			sheet.Type = SheetType(relTypes[relID])
+			w.Sheets = append(w.Sheets, sheet)
+		case brtName:
+			names = append(names, r.data)
+		case brtSupSelf, brtSupSame:
+			w.supBooks = append(w.supBooks, &supBook{self: true})
+		case brtSupBookSrc, brtSupAddin:
+			w.supBooks = append(w.supBooks, &supBook{})
+		case brtExternSheet:
+			// uint32 count, then for each, uint32 SUPBOOK index, and
+			// int32 first and last sheet.
+			if len(r.data) < 4 {
+				continue
+			}
+			count := int(binary.LittleEndian.Uint32(r.data))
+			for i := 0; i < count && 4+i*12+12 <= len(r.data); i++ {
+				xti := r.data[4+i*12:]
+				w.externSheets = append(w.externSheets, externSheet{
+					supBook: uint16(binary.LittleEndian.Uint32(xti)),
+					first:   int16(int32(binary.LittleEndian.Uint32(xti[4:]))),
+					last:    int16(int32(binary.LittleEndian.Uint32(xti[8:]))),
+				})
+			}
+		}
+	}
+	w.parseXLSBNames(names)
+	return
+}
+
+// Decode BrtName records ([MS-XLSB] 2.4.647):
+//
+//	uint32	flags
+//	byte	keyboard shortcut
+//	uint32	sheet index, or 0xFFFFFFFF for the workbook
+//	[]byte	name, as a wide string
+//	uint32	size of the parsed expression
+//	[]byte	parsed expression
+func (w *Workbook) parseXLSBNames(records [][]byte) {
+	expressions := make([][]byte, len(records))
+	for i, record := range records {
+		w.Names = append(w.Names, &DefinedName{})
+		if len(record) < 13 {
+			continue
+		}
+		name := w.Names[i]
+		flags := binary.LittleEndian.Uint32(record)
+		name.Hidden = flags&0x0001 != 0
+		name.Macro = flags&0x0008 != 0
+		name.Builtin = flags&0x0020 != 0
+		if index := binary.LittleEndian.Uint32(record[5:]); int64(index) < int64(len(w.Sheets)) {
+			name.Sheet = w.Sheets[index].Name
+		}
+		var n int
+		name.Name, n = wideString(record[9:], 4)
+		name.Name, name.Builtin = builtinName(name.Name, name.Builtin)
+		expressions[i] = record[9+n:]
+	}
+	for i, name := range w.Names {
+		if e := expressions[i]; len(e) >= 4 {
+			name.Formula = "=" + w.formulaText(e[4:], int(binary.LittleEndian.Uint32(e)))
+		}
+	}
+}
+
+// Parse a macro sheet part of an .xlsb file, for the sheet's formulas.
+// Cells come after the BrtRowHdr record of their row. Each formula cell
+// record is ([MS-XLSB] 2.4.653):
+//
+//	uint32	column
+//	uint32	style and flags
+//	[]byte	cached value: a wide string, an 8-byte number, or a byte
+//	uint16	flags
+//	uint32	size of the parsed expression
+//	[]byte	parsed expression
+//
+//	Args:
+//		sheet (*Sheet):	The sheet, from the workbook.
+//		data ([]byte):	The sheet's part.
+//
+//	Returns:
+//		err (error):	Non-nil if the part can't be parsed. Formulas up
+//				to that point are kept.
+func (w *Workbook) ParseXLSBSheet(sheet *Sheet, data []byte) (err error) {
+	records, err := biff12Records(data)
+	var row uint32
+	for _, r := range records {
+		var size int
+		switch r.recordType {
+		case brtRowHdr:
+			if len(r.data) >= 4 {
+				row = binary.LittleEndian.Uint32(r.data)
+			}
+			continue
+		case brtFmlaString:
+			if len(r.data) >= 12 {
+				_, size = wideString(r.data[8:], 4)
+			}
+		case brtFmlaNum:
+			size = 8
+		case brtFmlaBool, brtFmlaError:
+			size = 1
+		default:
+			continue
+		}
+		offset := 8 + size + 2
+		if offset+4 > len(r.data) {
+			continue
+		}
+		f := &CellFormula{Row: row, Col: uint16(binary.LittleEndian.Uint32(r.data))}
+		f.Formula = "=" + w.formulaText(r.data[offset+4:], int(binary.LittleEndian.Uint32(r.data[offset:])))
+		sheet.Formulas = append(sheet.Formulas, f)
+	}
+	return
+}
+
+// SheetType returns the type of sheet a workbook relationship points at,
+// one of the Sheet constants.
+func SheetType(relType string) string {
+	switch relType[strings.LastIndex(relType, "/")+1:] {
+	case "xlMacrosheet", "xlIntlMacrosheet":
+		return SheetMacro
+	case "chartsheet":
+		return SheetChart
+	}
+	return SheetWorksheet
+}
+
+func sheetVisibility(state uint32) string {
+	switch state {
+	case 0:
+		return SheetVisible
+	case 1:
+		return SheetHidden
+	}
+	return SheetVeryHidden
+}
+
+// Built-in names are written with a _xlnm. prefix in OOXML workbooks.
+func builtinName(name string, builtin bool) (string, bool) {
+	if strings.HasPrefix(name, "_xlnm.") {
+		return strings.TrimPrefix(name, "_xlnm."), true
+	}
+	return name, builtin
+}
+
+// Read a UTF-16 string, prefixed by its length in characters, as an
+// integer of lengthSize bytes. A length of 0xFFFFFFFF is a null string.
+func wideString(data []byte, lengthSize int) (s string, n int) {
+	if len(data) < lengthSize {
+		return
+	}
+	var length uint32
+	if lengthSize == 2 {
+		length = uint32(binary.LittleEndian.Uint16(data))
+	} else {
+		length = binary.LittleEndian.Uint32(data)
+	}
+	n = lengthSize
+	if length == math.MaxUint32 {
+		return
+	}
+	if int64(length)*2 > int64(len(data)-n) {
+		length = uint32((len(data) - n) / 2)
+	}
+	chars := make([]uint16, length)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(data[n+i*2:])
+	}
+	s = string(utf16.Decode(chars))
+	n += int(length) * 2
+	return
+}
@@ -0,0 +1,97 @@
+package parsers
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/ashdwilson/ole/internal/xlsbtest"
+)
+
+// A formula cell record's data, for the expression rgce at col, after
+// a cached value.
+func fmlaRecord(col uint32, value []byte, rgce ...[]byte) []byte {
+	expression := bytes.Join(rgce, nil)
+	return bytes.Join([][]byte{xlsbtest.U32(col, 0), value, le16(0), xlsbtest.U32(uint32(len(expression))), expression}, nil)
+}
+
+func TestXLSBWorkbook(t *testing.T) {
+	buf := &bytes.Buffer{}
+	xlsbtest.Record(buf, brtBeginBook)
+	xlsbtest.Record(buf, brtBundleSh, xlsbtest.U32(0, 1), xlsbtest.Wide("rId1"), xlsbtest.Wide("Sheet1"))
+	xlsbtest.Record(buf, brtBundleSh, xlsbtest.U32(1, 2), xlsbtest.Wide("rId2"), xlsbtest.Wide("Macro1"))
+	xlsbtest.Record(buf, brtSupSelf)
+	xlsbtest.Record(buf, brtExternSheet, xlsbtest.U32(1, 0, 1, 1))
+	xlsbtest.Record(buf, brtName, xlsbtest.U32(0x0020), []byte{0}, xlsbtest.U32(0xFFFFFFFF), xlsbtest.Wide("_xlnm.Auto_Open"), xlsbtest.U32(9), []byte{0x3A}, le16(0), xlsbtest.U32(0), le16(0))
+	relTypes := map[string]string{
+		"rId1": "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet",
+		"rId2": "http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet",
+	}
+
+	w, err := NewXLSBWorkbook(buf.Bytes(), relTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []Sheet{
+		{Name: "Sheet1", RelID: "rId1", Type: SheetWorksheet, Visibility: SheetVisible},
+		{Name: "Macro1", RelID: "rId2", Type: SheetMacro, Visibility: SheetHidden},
+	} {
+		if i >= len(w.Sheets) {
+			t.Fatalf("expected 2 sheets, got %d", len(w.Sheets))
+		}
+		s := w.Sheets[i]
+		if s.Name != expected.Name || s.RelID != expected.RelID || s.Type != expected.Type || s.Visibility != expected.Visibility {
+			t.Errorf("sheet %d mismatch - expected %+v got %+v", i, expected, s)
+		}
+	}
+	if len(w.Names) != 1 {
+		t.Fatalf("expected 1 name, got %d", len(w.Names))
+	}
+	if n := w.Names[0]; n.Name != "Auto_Open" || !n.Builtin || n.Formula != "=Macro1!$A$1" || !n.AutoRun() {
+		t.Errorf("name mismatch: %+v", n)
+	}
+
+	buf.Reset()
+	xlsbtest.Record(buf, brtRowHdr, xlsbtest.U32(0), make([]byte, 13))
+	exec := append(append([]byte{0x17}, le16(8)...), le16(utf16.Encode([]rune("calc.exe"))...)...)
+	xlsbtest.Record(buf, brtFmlaString, fmlaRecord(0, xlsbtest.Wide(""), exec, []byte{0x42, 1}, le16(110)))
+	xlsbtest.Record(buf, brtRowHdr, xlsbtest.U32(1), make([]byte, 13))
+	// A relative reference, one row up, in the same column.
+	xlsbtest.Record(buf, brtFmlaBool, fmlaRecord(2, []byte{1}, []byte{0x2C}, xlsbtest.U32(0xFFFFFFFF), le16(0xC000), []byte{0x42, 1}, le16(0x8000|17)))
+	xlsbtest.Record(buf, brtFmlaNum, fmlaRecord(3, make([]byte, 8), []byte{0x42, 0}, le16(54)))
+	if err = w.ParseXLSBSheet(w.Sheets[1], buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []struct{ cell, formula string }{
+		{"A1", `=EXEC("calc.exe")`},
+		{"C2", `=RUN(R[-1]C[0])`},
+		{"D2", `=HALT()`},
+	} {
+		if i >= len(w.Sheets[1].Formulas) {
+			t.Fatalf("expected 3 formulas, got %d", len(w.Sheets[1].Formulas))
+		}
+		f := w.Sheets[1].Formulas[i]
+		if f.Cell() != expected.cell || f.Formula != expected.formula {
+			t.Errorf("formula %d mismatch - expected %s %s got %s %s", i, expected.cell, expected.formula, f.Cell(), f.Formula)
+		}
+	}
+}
+
+func TestXLSBWorkbookMalformed(t *testing.T) {
+	buf := &bytes.Buffer{}
+	xlsbtest.Record(buf, brtBundleSh, xlsbtest.U32(0, 1), xlsbtest.Wide("rId1"), xlsbtest.Wide("Sheet1"))
+	if _, err := NewXLSBWorkbook(buf.Bytes(), nil); !errors.Is(err, ErrNotXLSBWorkbook) {
+		t.Errorf("expected ErrNotXLSBWorkbook, got %v", err)
+	}
+
+	// Records overrunning the part stop parsing, keeping what came before.
+	buf.Reset()
+	xlsbtest.Record(buf, brtBeginBook)
+	xlsbtest.Record(buf, brtBundleSh, xlsbtest.U32(0, 1), xlsbtest.Wide("rId1"), xlsbtest.Wide("Sheet1"))
+	buf.Write([]byte{brtName, 100})
+	w, err := NewXLSBWorkbook(buf.Bytes(), nil)
+	if err == nil || len(w.Sheets) != 1 {
+		t.Errorf("expected an error and one sheet, got %v and %+v", err, w)
+	}
+}
@@ -0,0 +1,181 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotXLSXWorkbook = errors.New("not an .xlsx workbook part")
+)
+
+// The parts of xl/workbook.xml we need ([ECMA-376] Part 1, 18.2).
+type xlsxWorkbook struct {
+	XMLName xml.Name
+	Sheets  []struct {
+		Name  string `xml:"name,attr"`
+		State string `xml:"state,attr"`
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+	DefinedNames []struct {
+		Name         string `xml:"name,attr"`
+		LocalSheetID *int   `xml:"localSheetId,attr"`
+		Hidden       bool   `xml:"hidden,attr"`
+		XLM          bool   `xml:"xlm,attr"`
+		VBProcedure  bool   `xml:"vbProcedure,attr"`
+		Formula      string `xml:",chardata"`
+	} `xml:"definedNames>definedName"`
+}
+
+// Parse the workbook part of an .xlsx or .xlsm file, xl/workbook.xml.
+// Sheets are listed with the relationship IDs of their parts. Their types
+// come from the relationships, given by relTypes.
+//
+//	Args:
+//		data ([]byte):			The workbook part.
+//		relTypes (map[string]string):	Relationship types, by ID, from the
+//						part's relationships.
+//
+//	Returns:
+//		w (*Workbook):	The workbook.
+//		err (error):	Non-nil if the part can't be parsed.
+func NewXLSXWorkbook(data []byte, relTypes map[string]string) (w *Workbook, err error) {
+	parsed := &xlsxWorkbook{}
+	err = xml.Unmarshal(data, parsed)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrNotXLSXWorkbook, err)
+		return
+	}
+	if parsed.XMLName.Local != "workbook" {
+		err = fmt.Errorf("%w: root element is %s", ErrNotXLSXWorkbook, parsed.XMLName.Local)
+		return
+	}
+	w = &Workbook{}
+	for _, s := range parsed.Sheets {
+		sheet := &Sheet{Name: s.Name, RelID: s.RelID, Type: SheetType(relTypes[s.RelID])}
+		switch s.State {
+		case "hidden":
+			sheet.Visibility = SheetHidden
+		case "veryHidden":
+			sheet.Visibility = SheetVeryHidden
+		default:
+			sheet.Visibility = SheetVisible
+		}
+		w.Sheets = append(w.Sheets, sheet)
+	}
+	for _, n := range parsed.DefinedNames {
+		name := &DefinedName{
+			Formula: "=" + strings.TrimSpace(n.Formula),
+			Hidden:  n.Hidden,
+			Macro:   n.XLM || n.VBProcedure,
+		}
+		name.Name, name.Builtin = builtinName(n.Name, false)
+		if n.LocalSheetID != nil && *n.LocalSheetID >= 0 && *n.LocalSheetID < len(w.Sheets) {
+			name.Sheet = w.Sheets[*n.LocalSheetID].Name
+		}
+		w.Names = append(w.Names, name)
+	}
+	return
+}
+
+// Parse a macro sheet part of an .xlsx or .xlsm file, for the sheet's
+// formulas. Formulas are kept in the f element of each cell, as text:
+//
+//	<row r="1"><c r="A1"><f>EXEC("calc.exe")</f></c></row>
+//
+// Cells sharing a formula with the one before them have an empty f
+// element, and are skipped.
+//
+//	Args:
+//		sheet (*Sheet):	The sheet, from the workbook.
+//		data ([]byte):	The sheet's part.
+//
+//	Returns:
+//		err (error):	Non-nil if the part can't be parsed. Formulas up
+//				to that point are kept.
+func (w *Workbook) ParseXLSXSheet(sheet *Sheet, data []byte) (err error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var row uint32
+	var col uint16
+	for {
+		var token xml.Token
+		token, err = decoder.Token()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			return
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			// Rows and cells may leave out their position, in which
+			// case they follow on from the one before.
+			row++
+			col = 0
+			if r, ok := attr(start, "r"); ok {
+				fmt.Sscan(r, &row)
+			}
+		case "c":
+			col++
+			if r, ok := attr(start, "r"); ok {
+				if cellRow, cellCol, ok := parseCellName(r); ok {
+					row, col = cellRow+1, cellCol+1
+				}
+			}
+		case "f":
+			var text string
+			err = decoder.DecodeElement(&text, &start)
+			if err != nil {
+				return
+			}
+			if text = strings.TrimSpace(text); text != "" && row > 0 && col > 0 {
+				sheet.Formulas = append(sheet.Formulas, &CellFormula{Row: row - 1, Col: col - 1, Formula: "=" + text})
+			}
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) (value string, ok bool) {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return
+}
+
+// parseCellName reads an A1 notation cell address, without $ signs,
+// into a zero-based row and column.
+func parseCellName(name string) (row uint32, col uint16, ok bool) {
+	i := 0
+	c := 0
+	for ; i < len(name) && name[i] >= 'A' && name[i] <= 'Z'; i++ {
+		c = c*26 + int(name[i]-'A'+1)
+		if c > 0x4000 {
+			return
+		}
+	}
+	r := 0
+	for _, ch := range name[i:] {
+		if ch < '0' || ch > '9' {
+			return
+		}
+		r = r*10 + int(ch-'0')
+		if r > 1<<20 {
+			return
+		}
+	}
+	if i == 0 || i == len(name) || c == 0 || r == 0 {
+		return
+	}
+	return uint32(r - 1), uint16(c - 1), true
+}
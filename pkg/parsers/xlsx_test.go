@@ -0,0 +1,158 @@
+package parsers
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"path"
+	"testing"
+)
+
+// Read the member called name out of a zip archive in the test data.
+func sampleZipMember(t *testing.T, sample, name string) (data []byte) {
+	t.Helper()
+	rdr, err := zip.OpenReader(path.Join(pathToSampleDataDir, sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	f, err := rdr.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err = io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestXLSXWorkbook(t *testing.T) {
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets>
+    <sheet name="Sheet1" sheetId="1" r:id="rId1"/>
+    <sheet name="Macro1" sheetId="2" state="veryHidden" r:id="rId2"/>
+  </sheets>
+  <definedNames>
+    <definedName name="_xlnm.Auto_Open" hidden="1">Macro1!$A$1</definedName>
+    <definedName name="Payload" localSheetId="1" xlm="1">Macro1!$A$2</definedName>
+  </definedNames>
+</workbook>`
+	relTypes := map[string]string{
+		"rId1": "http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet",
+		"rId2": "http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet",
+	}
+	w, err := NewXLSXWorkbook([]byte(workbook), relTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []Sheet{
+		{Name: "Sheet1", RelID: "rId1", Type: SheetWorksheet, Visibility: SheetVisible},
+		{Name: "Macro1", RelID: "rId2", Type: SheetMacro, Visibility: SheetVeryHidden},
+	} {
+		if i >= len(w.Sheets) {
+			t.Fatalf("expected 2 sheets, got %d", len(w.Sheets))
+		}
+		s := w.Sheets[i]
+		if s.Name != expected.Name || s.RelID != expected.RelID || s.Type != expected.Type || s.Visibility != expected.Visibility {
+			t.Errorf("sheet %d mismatch - expected %+v got %+v", i, expected, s)
+		}
+	}
+	if len(w.Names) != 2 {
+		t.Fatalf("expected 2 names, got %d", len(w.Names))
+	}
+	if n := w.Names[0]; n.Name != "Auto_Open" || !n.Builtin || !n.Hidden || n.Formula != "=Macro1!$A$1" || !n.AutoRun() {
+		t.Errorf("built-in name mismatch: %+v", n)
+	}
+	if n := w.Names[1]; n.Name != "Payload" || n.Sheet != "Macro1" || !n.Macro || n.Builtin || n.AutoRun() {
+		t.Errorf("name mismatch: %+v", n)
+	}
+
+	sheet := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1"><f>EXEC("calc.exe")</f><v>0</v></c></row>
+    <row r="2"><c r="A2"><f t="shared" ref="A2:B2" si="0">CHAR(65)</f></c><c r="B2"><f t="shared" si="0"/></c></row>
+    <row><c><v>1</v></c><c><f>HALT()</f></c></row>
+  </sheetData>
+</xm:macrosheet>`
+	if err = w.ParseXLSXSheet(w.Sheets[1], []byte(sheet)); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []struct{ cell, formula string }{
+		{"A1", `=EXEC("calc.exe")`},
+		{"A2", `=CHAR(65)`},
+		{"B3", `=HALT()`},
+	} {
+		if i >= len(w.Sheets[1].Formulas) {
+			t.Fatalf("expected 3 formulas, got %d", len(w.Sheets[1].Formulas))
+		}
+		f := w.Sheets[1].Formulas[i]
+		if f.Cell() != expected.cell || f.Formula != expected.formula {
+			t.Errorf("formula %d mismatch - expected %s %s got %s %s", i, expected.cell, expected.formula, f.Cell(), f.Formula)
+		}
+	}
+}
+
+// The workbook part of a real .xlsx file, with one worksheet.
+func TestXLSXWorkbookSample(t *testing.T) {
+	rels, err := NewRelationships(sampleZipMember(t, "xlsx.xlsx", "xl/_rels/workbook.xml.rels"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relTypes := map[string]string{}
+	for _, rel := range rels {
+		relTypes[rel.ID] = rel.Type
+	}
+	w, err := NewXLSXWorkbook(sampleZipMember(t, "xlsx.xlsx", "xl/workbook.xml"), relTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.Sheets) != 1 || len(w.Names) != 0 {
+		t.Fatalf("expected one sheet and no names, got %+v", w)
+	}
+	expected := Sheet{Name: "Foaie1", RelID: "rId3", Type: SheetWorksheet, Visibility: SheetVisible}
+	if s := w.Sheets[0]; s.Name != expected.Name || s.RelID != expected.RelID || s.Type != expected.Type || s.Visibility != expected.Visibility {
+		t.Errorf("sheet mismatch - expected %+v got %+v", expected, s)
+	}
+}
+
+func TestXLSXWorkbookMalformed(t *testing.T) {
+	document := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"/>`
+	if _, err := NewXLSXWorkbook([]byte(document), nil); !errors.Is(err, ErrNotXLSXWorkbook) {
+		t.Errorf("expected ErrNotXLSXWorkbook, got %v", err)
+	}
+
+	// Formulas before a syntax error are kept.
+	w := &Workbook{}
+	sheet := &Sheet{}
+	err := w.ParseXLSXSheet(sheet, []byte(`<macrosheet><sheetData><row r="1"><c r="A1"><f>HALT()</f></c></row><row`))
+	if err == nil || len(sheet.Formulas) != 1 {
+		t.Errorf("expected an error and one formula, got %v and %d", err, len(sheet.Formulas))
+	}
+}
+
+func TestParseCellName(t *testing.T) {
+	cases := []struct {
+		name string
+		row  uint32
+		col  uint16
+		ok   bool
+	}{
+		{"A1", 0, 0, true},
+		{"AB5", 4, 27, true},
+		{"XFD1048576", 1048575, 16383, true},
+		{"A", 0, 0, false},
+		{"12", 0, 0, false},
+		{"A0", 0, 0, false},
+		{"$A$1", 0, 0, false},
+	}
+	for _, c := range cases {
+		row, col, ok := parseCellName(c.name)
+		if row != c.row || col != c.col || ok != c.ok {
+			t.Errorf("parseCellName(%q) mismatch - expected %d %d %v got %d %d %v", c.name, c.row, c.col, c.ok, row, col, ok)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sinks
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Dir writes files under a directory on the local filesystem.
+type Dir struct {
+	// The directory all files are written under.
+	Root string
+}
+
+// Create a new Dir sink. The root directory must already exist.
+func NewDir(root string) (d *Dir) {
+	d = &Dir{Root: root}
+	return
+}
+
+// Open opens a file under the root directory. The returned
+// fs.File is an *os.File.
+func (d *Dir) Open(name string) (f fs.File, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		return
+	}
+	f, err = os.Open(d.path(name))
+	return
+}
+
+// Create creates a file under the root directory, and any missing parents.
+func (d *Dir) Create(name string) (w io.WriteCloser, err error) {
+	if err = checkName("create", name); err != nil {
+		return
+	}
+	full := d.path(name)
+	if err = os.MkdirAll(filepath.Dir(full), 0770); err != nil {
+		return
+	}
+	w, err = os.Create(full)
+	return
+}
+
+// Mkdir creates a directory under the root directory.
+func (d *Dir) Mkdir(name string) (err error) {
+	if err = checkName("mkdir", name); err != nil {
+		return
+	}
+	err = os.MkdirAll(d.path(name), 0770)
+	return
+}
+
+// Close is a no-op, files are written through as they are closed.
+func (d *Dir) Close() (err error) {
+	return
+}
+
+func (d *Dir) path(name string) string {
+	return filepath.Join(d.Root, filepath.FromSlash(name))
+}
@@ -0,0 +1,232 @@
+package sinks
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps all files in memory. It implements fs.FS, fs.ReadDirFS
+// and fs.StatFS, so the extracted tree can be browsed with the io/fs
+// helpers once unpacking is done.
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+// Create a new, empty Memory sink.
+func NewMemory() (m *Memory) {
+	m = &Memory{
+		files: map[string][]byte{},
+		dirs:  map[string]bool{".": true},
+	}
+	return
+}
+
+// Create returns a writer which stores the file when closed.
+func (m *Memory) Create(name string) (w io.WriteCloser, err error) {
+	if err = checkName("create", name); err != nil {
+		return
+	}
+	w = &memWriter{name: name, onClose: m.store}
+	return
+}
+
+// Mkdir records a directory, along with any missing parents.
+func (m *Memory) Mkdir(name string) (err error) {
+	if err = checkName("mkdir", name); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addDirs(name)
+	return
+}
+
+// Close is a no-op, the files stay available until the sink is discarded.
+func (m *Memory) Close() (err error) {
+	return
+}
+
+// Open opens a file or directory. Files implement io.ReaderAt and io.Seeker,
+// and directories implement fs.ReadDirFile.
+func (m *Memory) Open(name string) (f fs.File, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if data, ok := m.files[name]; ok {
+		f = &memFile{info: memInfo{name: path.Base(name), size: int64(len(data))}, Reader: bytes.NewReader(data)}
+		return
+	}
+	if m.dirs[name] {
+		var entries []fs.DirEntry
+		entries, err = m.readDir(name)
+		f = &memDir{info: memInfo{name: path.Base(name), dir: true}, entries: entries}
+		return
+	}
+	err = &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	return
+}
+
+// ReadDir lists the named directory, sorted by name.
+func (m *Memory) ReadDir(name string) (entries []fs.DirEntry, err error) {
+	if !fs.ValidPath(name) {
+		err = &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.dirs[name] {
+		err = &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		return
+	}
+	entries, err = m.readDir(name)
+	return
+}
+
+// Stat describes the named file or directory.
+func (m *Memory) Stat(name string) (info fs.FileInfo, err error) {
+	f, err := m.Open(name)
+	if err != nil {
+		return
+	}
+	info, err = f.Stat()
+	return
+}
+
+// store saves the contents of a closed memWriter.
+func (m *Memory) store(name string, data []byte) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dirs[name] {
+		err = &fs.PathError{Op: "create", Path: name, Err: fs.ErrExist}
+		return
+	}
+	m.files[name] = data
+	m.addDirs(path.Dir(name))
+	return
+}
+
+// addDirs records dir and all of its parents. The caller holds the lock.
+func (m *Memory) addDirs(dir string) {
+	for ; dir != "."; dir = path.Dir(dir) {
+		m.dirs[dir] = true
+	}
+}
+
+// readDir lists a directory. The caller holds the lock.
+func (m *Memory) readDir(dir string) (entries []fs.DirEntry, err error) {
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	for name, data := range m.files {
+		if child, ok := directChild(prefix, name); ok {
+			entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: child, size: int64(len(data))}))
+		}
+	}
+	for name := range m.dirs {
+		if child, ok := directChild(prefix, name); ok {
+			entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: child, dir: true}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return
+}
+
+// directChild returns the base name of name if it sits directly under prefix.
+func directChild(prefix, name string) (child string, ok bool) {
+	if name == "." || !strings.HasPrefix(name, prefix) {
+		return
+	}
+	child = name[len(prefix):]
+	ok = child != "" && !strings.Contains(child, "/")
+	return
+}
+
+// memWriter buffers a file until it is closed.
+type memWriter struct {
+	bytes.Buffer
+	name    string
+	onClose func(name string, data []byte) error
+	closed  bool
+}
+
+func (w *memWriter) Close() (err error) {
+	if w.closed {
+		return
+	}
+	w.closed = true
+	err = w.onClose(w.name, w.Bytes())
+	return
+}
+
+// memFile is an open file in a Memory sink.
+type memFile struct {
+	*bytes.Reader
+	info memInfo
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// memDir is an open directory in a Memory sink.
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: fs.ErrInvalid}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) (entries []fs.DirEntry, err error) {
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		entries = remaining
+		d.offset = len(d.entries)
+		return
+	}
+	if len(remaining) == 0 {
+		err = io.EOF
+		return
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	entries = remaining[:n]
+	d.offset += n
+	return
+}
+
+// memInfo implements fs.FileInfo for files and directories in a Memory sink.
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() any           { return nil }
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
@@ -0,0 +1,37 @@
+// Package sinks provides destinations for the files produced while
+// unpacking: a local directory, an in-memory tree, a tar stream and a zip file.
+package sinks
+
+import (
+	"io"
+	"io/fs"
+)
+
+// A Sink receives extracted files, and hands them back for further unpacking.
+//
+// Names are slash-separated paths relative to the root of the sink, and must
+// satisfy fs.ValidPath. Parent directories are created implicitly.
+type Sink interface {
+	// Open opens a file which was previously written to the sink. Files
+	// returned by sinks in this package also implement io.ReaderAt.
+	fs.FS
+
+	// Create opens a new file for writing. The file is complete once the
+	// returned io.WriteCloser is closed.
+	Create(name string) (io.WriteCloser, error)
+
+	// Mkdir creates a directory, along with any missing parents.
+	Mkdir(name string) error
+
+	// Close flushes anything the sink has buffered. Files can't be
+	// created once the sink has been closed.
+	Close() error
+}
+
+// checkName returns an *fs.PathError if name is not a valid sink path.
+func checkName(op, name string) (err error) {
+	if !fs.ValidPath(name) || name == "." {
+		err = &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return
+}
@@ -0,0 +1,163 @@
+package sinks
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+var sampleFiles = map[string]string{
+	"sample.docx":                     "PK",
+	"sample.docx-members/Ole10Native": "ole",
+	"sample.docx-members/Ole10Native-members/Untitled.msg": "msg",
+}
+
+func writeSampleFiles(t *testing.T, s Sink) {
+	t.Helper()
+	for name, body := range sampleFiles {
+		w, err := s.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, body); err != nil {
+			t.Fatal(err)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Mkdir("sample.docx-members/empty"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// The memory sink should behave like any other fs.FS.
+func TestMemoryFS(t *testing.T) {
+	m := NewMemory()
+	writeSampleFiles(t, m)
+	err := fstest.TestFS(m,
+		"sample.docx",
+		"sample.docx-members/Ole10Native",
+		"sample.docx-members/Ole10Native-members/Untitled.msg",
+		"sample.docx-members/empty",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := m.Open("sample.docx-members/Ole10Native")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.(io.ReaderAt); !ok {
+		t.Errorf("memory files should implement io.ReaderAt")
+	}
+	if _, err = m.Create("../escape"); err == nil {
+		t.Errorf("expected an error creating a file outside the sink")
+	}
+}
+
+// Files written to a tar sink land in the stream, and can be read back.
+func TestTar(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewTar(buf)
+	writeSampleFiles(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]string{}
+	tr := tar.NewReader(buf)
+	for hdr, err := tr.Next(); err != io.EOF; hdr, err = tr.Next() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[hdr.Name] = string(body)
+	}
+	checkFiles(t, found)
+	if _, err := fs.ReadFile(s, "sample.docx-members/Ole10Native"); err != nil {
+		t.Errorf("reading back from the tar sink: %s", err)
+	}
+}
+
+// Files written to a zip sink land in the archive, and can be read back.
+func TestZip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewZip(buf)
+	writeSampleFiles(t, s)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]string{}
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		body, err := fs.ReadFile(zr, f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[f.Name] = string(body)
+	}
+	checkFiles(t, found)
+	if _, err := fs.ReadFile(s, "sample.docx-members/Ole10Native"); err != nil {
+		t.Errorf("reading back from the zip sink: %s", err)
+	}
+}
+
+// Archive sinks write each file out as soon as it's closed, rather than
+// holding everything back until the sink is closed.
+func TestArchiveStreaming(t *testing.T) {
+	for name, newSink := range map[string]func(io.Writer) Sink{
+		"tar": func(w io.Writer) Sink { return NewTar(w) },
+		"zip": func(w io.Writer) Sink { return NewZip(w) },
+	} {
+		buf := &bytes.Buffer{}
+		s := newSink(buf)
+		w, err := s.Create("a/b/file.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, "streamed")
+		if buf.Len() != 0 {
+			t.Errorf("%s: expected nothing to be written before the file is closed", name)
+		}
+		if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("a/b/file.txt")) {
+			t.Errorf("%s: expected the file to be written once closed", name)
+		}
+		if err = s.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err = s.Mkdir("late"); err == nil {
+			t.Errorf("%s: expected an error making a directory once closed", name)
+		}
+	}
+}
+
+func checkFiles(t *testing.T, found map[string]string) {
+	t.Helper()
+	if len(found) != len(sampleFiles) {
+		t.Errorf("file count mismatch - expected %d got %d", len(sampleFiles), len(found))
+	}
+	for name, body := range sampleFiles {
+		if found[name] != body {
+			t.Errorf("content mismatch for %s - expected %q got %q", name, body, found[name])
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package sinks
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// Tar writes files to a tar stream. Each file is written to the stream as
+// soon as it's closed, and directories as they're made. Files are also
+// kept in memory, so they can be opened for further unpacking, and are
+// only released when the sink is garbage collected. Unlike Dir, a Tar
+// sink holds the whole unpacked tree in memory: bound it with
+// MaxTotalBytes (see ole.Options) when the input isn't trusted.
+type Tar struct {
+	*Memory
+
+	mu      sync.Mutex
+	tw      *tar.Writer
+	dirs    map[string]bool
+	modTime time.Time
+	closed  bool
+}
+
+// Create a new Tar sink which writes to w. Close must be called to
+// finish the stream.
+func NewTar(w io.Writer) (t *Tar) {
+	t = &Tar{Memory: NewMemory(), tw: tar.NewWriter(w), dirs: map[string]bool{".": true}, modTime: time.Now()}
+	return
+}
+
+// Create returns a writer which adds the file to the stream when closed.
+func (t *Tar) Create(name string) (w io.WriteCloser, err error) {
+	if err = checkName("create", name); err != nil {
+		return
+	}
+	w = &memWriter{name: name, onClose: t.store}
+	return
+}
+
+// Mkdir adds a directory to the stream, along with any missing parents.
+func (t *Tar) Mkdir(name string) (err error) {
+	if err = t.Memory.Mkdir(name); err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	err = t.writeDirs(name)
+	return
+}
+
+// Close finishes the tar stream. It does not close the underlying writer.
+func (t *Tar) Close() (err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	err = t.tw.Close()
+	return
+}
+
+// store keeps the contents of a closed file, and writes it to the stream.
+func (t *Tar) store(name string, data []byte) (err error) {
+	if err = t.Memory.store(name, data); err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err = t.writeDirs(path.Dir(name)); err != nil {
+		return
+	}
+	err = t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0640, Size: int64(len(data)), ModTime: t.modTime})
+	if err != nil {
+		return
+	}
+	if _, err = t.tw.Write(data); err != nil {
+		return
+	}
+	err = t.tw.Flush()
+	return
+}
+
+// writeDirs writes dir and any of its parents which aren't in the stream
+// yet, parents first. The caller holds the lock.
+func (t *Tar) writeDirs(dir string) (err error) {
+	if t.closed {
+		err = errors.New("tar sink is closed")
+		return
+	}
+	if t.dirs[dir] {
+		return
+	}
+	if err = t.writeDirs(path.Dir(dir)); err != nil {
+		return
+	}
+	err = t.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0750, ModTime: t.modTime})
+	if err == nil {
+		t.dirs[dir] = true
+	}
+	return
+}
@@ -0,0 +1,106 @@
+package sinks
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// Zip writes files to a zip archive. Each file is written to the archive
+// as soon as it's closed, and directories as they're made. Files are also
+// kept in memory, so they can be opened for further unpacking, and are
+// only released when the sink is garbage collected. Unlike Dir, a Zip
+// sink holds the whole unpacked tree in memory: bound it with
+// MaxTotalBytes (see ole.Options) when the input isn't trusted.
+type Zip struct {
+	*Memory
+
+	mu      sync.Mutex
+	zw      *zip.Writer
+	dirs    map[string]bool
+	modTime time.Time
+	closed  bool
+}
+
+// Create a new Zip sink which writes to w. Close must be called to
+// write the archive's central directory.
+func NewZip(w io.Writer) (z *Zip) {
+	z = &Zip{Memory: NewMemory(), zw: zip.NewWriter(w), dirs: map[string]bool{".": true}, modTime: time.Now()}
+	return
+}
+
+// Create returns a writer which adds the file to the archive when closed.
+func (z *Zip) Create(name string) (w io.WriteCloser, err error) {
+	if err = checkName("create", name); err != nil {
+		return
+	}
+	w = &memWriter{name: name, onClose: z.store}
+	return
+}
+
+// Mkdir adds a directory to the archive, along with any missing parents.
+func (z *Zip) Mkdir(name string) (err error) {
+	if err = z.Memory.Mkdir(name); err != nil {
+		return
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	err = z.writeDirs(name)
+	return
+}
+
+// Close finishes the zip archive. It does not close the underlying writer.
+func (z *Zip) Close() (err error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.closed {
+		return
+	}
+	z.closed = true
+	err = z.zw.Close()
+	return
+}
+
+// store keeps the contents of a closed file, and writes it to the archive.
+func (z *Zip) store(name string, data []byte) (err error) {
+	if err = z.Memory.store(name, data); err != nil {
+		return
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if err = z.writeDirs(path.Dir(name)); err != nil {
+		return
+	}
+	w, err := z.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: z.modTime})
+	if err != nil {
+		return
+	}
+	if _, err = w.Write(data); err != nil {
+		return
+	}
+	err = z.zw.Flush()
+	return
+}
+
+// writeDirs writes dir and any of its parents which aren't in the archive
+// yet, parents first. The caller holds the lock.
+func (z *Zip) writeDirs(dir string) (err error) {
+	if z.closed {
+		err = errors.New("zip sink is closed")
+		return
+	}
+	if z.dirs[dir] {
+		return
+	}
+	if err = z.writeDirs(path.Dir(dir)); err != nil {
+		return
+	}
+	_, err = z.zw.CreateHeader(&zip.FileHeader{Name: dir + "/", Modified: z.modTime})
+	if err == nil {
+		z.dirs[dir] = true
+	}
+	return
+}
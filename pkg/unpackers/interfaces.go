@@ -1,10 +1,10 @@
 package unpackers
 
 import (
-	"container/list"
 	"io"
 
 	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
 )
 
 // An Unpacker is a component that reads an archive and extracts
@@ -12,21 +12,63 @@ import (
 //
 // This interface describes how the unpackers in this software project should work.
 // Generally, an unpacker works like this:
-//   - Create a new directory for all member files to be extracted to (${inpath}-members/)
 //   - Load the abstraction specific to the data stream we are trying to unpack (archive/zip, for instance)
-//   - For each enclosed file, write the contents to a new file under the ${inpath}-members/ directory.
+//   - For each enclosed file, create a new file in the sink and write the contents to it.
 //   - The naming of the file is left up to the implementation, and should take care to avoid collisions.
+//
+// The sink handed to an Unpacker is scoped to the archive being unpacked (by
+// default, everything lands under ${inpath}-members/), and every file created
+// in it is queued up for recursive processing once it is closed.
 type Unpacker interface {
 	// UnpackStream extracts members from the archive stream:
 	//
 	//	Args:
-	//		inpath (string):		Path to original file on disk. Used to construct the output directory.
+	//		inpath (string):		Path to original file in the sink. Used as the key in results.
 	//		stream (io.ReaderAt):	This is the data stream we will extract archive members from.
 	//		size (int64):		This is the total size of the archive file conveyed over `stream`.
 	//		results (*models.Results)	This is the object we use to track overall results of the extraction.
-	//		queue (*list.List)		The implementation adds extracted files to the queue for recursive processing.
+	//		sink (sinks.Sink)		The implementation creates a file in the sink for each extracted member.
 	//
 	//	Returns:
 	//		err (error)
-	UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, queue *list.List) (err error)
+	UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error)
+}
+
+// A Typer is implemented by sinks which let unpackers declare the MIME
+// type of a member. This is for members the unpacker has already made
+// sense of, but which MIME type detection wouldn't recognize.
+type Typer interface {
+	// CreateTyped works like Create, but the member is dispatched as
+	// mimeType, rather than as whatever detection makes of it.
+	CreateTyped(name, mimeType string) (io.WriteCloser, error)
+}
+
+// Create a member, with a MIME type if the sink lets us set one.
+func createTyped(sink sinks.Sink, name, mimeType string) (w io.WriteCloser, err error) {
+	if typer, ok := sink.(Typer); ok && mimeType != "" {
+		w, err = typer.CreateTyped(name, mimeType)
+		return
+	}
+	w, err = sink.Create(name)
+	return
+}
+
+// A Describer is implemented by sinks which let unpackers fill in a
+// member's result up front, with what they know of it from the archive.
+type Describer interface {
+	// CreateDescribed works like Create, but the member's result starts
+	// out as result. A FileType on it works like CreateTyped's mimeType.
+	// The sink fills in the rest of the result, and keeps it.
+	CreateDescribed(name string, result *models.Result) (io.WriteCloser, error)
+}
+
+// Create a member, with a partly filled in result if the sink lets us
+// give one, or else with its MIME type.
+func createDescribed(sink sinks.Sink, name string, result *models.Result) (w io.WriteCloser, err error) {
+	if describer, ok := sink.(Describer); ok {
+		w, err = describer.CreateDescribed(name, result)
+		return
+	}
+	w, err = createTyped(sink, name, result.FileType)
+	return
 }
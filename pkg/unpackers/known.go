@@ -0,0 +1,63 @@
+package unpackers
+
+// These registrations cover files we recognize, but don't unpack. They have
+// no Unpacker, so the dispatcher marks matching files as supported and moves on.
+func init() {
+	// Skip the files which aren't archives (or if they are archives, we have
+	// reasons for not wanting to unpack them).
+	Register(Registration{
+		Name: "passive-types",
+		Match: Matcher{
+			MIMETypes: []string{
+				"image/png",
+				"image/bmp",
+				"image/jpeg",
+				"image/jxr",
+				"application/pdf",
+				"application/vnd.ms-outlook", // We don't try to unpack Outlook .msg files
+				"text/xml; charset=utf-8",
+				"text/plain; charset=utf-8",
+				"text/plain; charset=utf-16be",
+			},
+		},
+	})
+
+	// These streams are either not currently parseable, or not
+	// particularly interesting. Supported, but not unpacked. The
+	// CompObj, Ole and summary property set streams are parsed by
+	// the MSCFB unpacker, since they describe their storage.
+	Register(Registration{
+		Name: "passive-streams",
+		Match: Matcher{
+			MIMETypes: []string{"application/octet-stream"},
+			FileNames: []string{
+				"Ole",
+				"CompObj",
+				"SummaryInformation",
+				"DocumentSummaryInformation",
+				"ObjInfo",
+				"PROJECTwm",
+				"PROJECTlk",
+				"PRINT",
+				"EPRINT",
+				"1Table",
+				"0Table",
+				"Data",
+				"WordDocument",
+				"VisioDocument",
+				"Contents",
+				"attachedToolbars.bin",
+			},
+		},
+	})
+
+	// Maybe someday we'll do format conversion. But for now,
+	// we just recognize and skip further parsing.
+	Register(Registration{
+		Name: "passive-metafiles",
+		Match: Matcher{
+			MIMETypes:  []string{"application/octet-stream"},
+			Extensions: []string{".emf", ".wmf"},
+		},
+	})
+}
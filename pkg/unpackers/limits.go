@@ -0,0 +1,19 @@
+package unpackers
+
+import "errors"
+
+// ErrLimitExceeded is returned by a sink when writing a member would exceed
+// one of the configured unpacking limits. Unpackers should stop extracting
+// from the archive when they see it, and return without error; the limit
+// has already been recorded on the archive's result.
+var ErrLimitExceeded = errors.New("unpacking limit exceeded")
+
+// A Limiter is implemented by sinks which enforce unpacking limits.
+// Unpackers for compressed formats use it to vet each member before
+// extracting it.
+type Limiter interface {
+	// CheckRatio returns an error wrapping ErrLimitExceeded if the member's
+	// compression ratio is higher than allowed. Only the member should be
+	// skipped, the rest of the archive can still be extracted.
+	CheckRatio(name string, compressedSize, uncompressedSize int64) error
+}
@@ -1,73 +1,223 @@
 package unpackers
 
 import (
-	"container/list"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path"
 
 	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
 	"github.com/richardlehane/mscfb"
 )
 
 // The MSCFB implementation of Unpacker uses a 3rd-party library
-// to parse MS-CFB (OLE v2) files.
+// to parse MS-CFB (OLE v2) files. Along the way, it records the
+// class ID, CompObj and Ole streams, and the document summary
+// property sets, of each storage on the file's result. The source
+// code of VBA projects is decompressed, and extracted alongside
+// the module streams.
 type MSCFB struct{}
 
-// This unpacker extracts all enclosed objects, and enqueues them for further examination.
-func (m *MSCFB) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, queue *list.List) (err error) {
-	// Base path is inpath-members/
-	basePath := fmt.Sprintf("%s-members", inpath)
-	err = os.MkdirAll(basePath, 0770)
-	if err != nil {
-		return
-	}
+// Class ID of storages which don't have one.
+const nullCLSID = "{00000000-0000-0000-0000-000000000000}"
+
+// Streams which describe their storage are a few hundred bytes,
+// or a few kilobytes for property sets holding a thumbnail. Larger
+// ones are extracted, but not parsed.
+const maxStorageStreamSize = 1 << 20
+
+// Parsers for the streams which describe their storage,
+// by stream name.
+var storageStreams = map[string]func(data []byte, s *models.Storage) error{
+	"CompObj":                    parseCompObj,
+	"Ole":                        parseOleStream,
+	"SummaryInformation":         parseSummaryInformation,
+	"DocumentSummaryInformation": parseDocumentSummaryInformation,
+}
+
+// mimetype names MS-CFB files after the class ID of their root
+// storage, when it's one it knows.
+var cfbMIMETypes = []string{
+	"application/x-ole-storage",
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.ms-powerpoint",
+}
+
+func init() {
+	Register(Registration{
+		Name:     "mscfb",
+		Match:    Matcher{MIMETypes: cfbMIMETypes},
+		Unpacker: &MSCFB{},
+	})
+}
+
+// This unpacker extracts all enclosed objects, and writes them to the sink.
+func (m *MSCFB) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
 	errs := []error{}
 
-	// Open as a zip archive
+	// Open as a compound file
 	var rdr *mscfb.Reader
 	rdr, err = mscfb.New(stream)
 	if err != nil {
 		return
 	}
 
+	// Storage metadata, keyed by path in the file. The root is "".
+	storages := map[string]*models.Storage{"": {}}
+	if id := rdr.ID(); id != nullCLSID {
+		storages[""].CLSID = id
+	}
+	storage := func(path string) *models.Storage {
+		if storages[path] == nil {
+			storages[path] = &models.Storage{}
+		}
+		return storages[path]
+	}
+
+	// VBA projects are parsed up front, so their streams can be
+	// typed as they're extracted.
+	projects, vbaErrs := findVBAProjects(rdr)
+	errs = append(errs, vbaErrs...)
+
 	// Iterate through members
 	for entry, err := rdr.Next(); err == nil; entry, err = rdr.Next() {
+		pathElements := append([]string{}, entry.Path...)
+		pathElements = append(pathElements, entry.Name)
+		newFilePath := path.Join(pathElements...)
+		storagePath := path.Join(entry.Path...)
 
 		if entry.FileInfo().IsDir() {
+			if id := entry.ID(); id != nullCLSID {
+				storage(newFilePath).CLSID = id
+			}
 			// Create a dir
-			dName := path.Join(basePath, entry.Name)
-			err = os.MkdirAll(dName, 0770)
+			err = sink.Mkdir(newFilePath)
 			if err != nil {
-				err = fmt.Errorf("%w: creating directory %s", err, dName)
+				err = fmt.Errorf("%w: creating directory %s", err, newFilePath)
 				errs = append(errs, err)
 			}
 			continue
 		}
-		pathElements := []string{basePath}
-		pathElements = append(pathElements, entry.Path...)
-		pathElements = append(pathElements, entry.Name)
-		newFilePath := path.Join(pathElements...)
 
-		var newFile *os.File
-		newFile, err = os.Create(newFilePath)
+		// Keep a copy of streams which describe their storage,
+		// to parse once extracted.
+		var src io.Reader = entry
+		var described *bytes.Buffer
+		parse, describes := storageStreams[entry.Name]
+		if describes && entry.Size <= maxStorageStreamSize {
+			described = &bytes.Buffer{}
+			src = io.TeeReader(entry, described)
+		}
+
+		fileType := ""
+		if p := projects[storagePath]; p != nil {
+			fileType = p.streamType(entry.Name)
+		}
+
+		var newFile io.WriteCloser
+		newFile, err = createTyped(sink, newFilePath, fileType)
+		if errors.Is(err, ErrLimitExceeded) {
+			break
+		}
 		if err != nil {
 			err = fmt.Errorf("%w: creating file %s", err, newFilePath)
 			errs = append(errs, err)
 			continue
 		}
 
-		_, err = io.Copy(newFile, entry)
+		_, err = io.Copy(newFile, src)
+		if errors.Is(err, ErrLimitExceeded) {
+			newFile.Close()
+			break
+		}
 		if err != nil {
+			newFile.Close()
 			err = fmt.Errorf("%w: extracting member %s to %s", err, entry.Name, newFilePath)
 			errs = append(errs, err)
 			continue
 		}
-		queue.PushBack(newFilePath)
+		err = newFile.Close()
+		if err != nil {
+			err = fmt.Errorf("%w: closing file %s", err, newFilePath)
+			errs = append(errs, err)
+		}
+
+		if described != nil {
+			err = parse(described.Bytes(), storage(storagePath))
+			if err != nil {
+				err = fmt.Errorf("%w: parsing %s", err, newFilePath)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	result := results.Get(inpath)
+	for _, p := range sortedProjects(projects) {
+		storage(p.storage).VBA = p.model
+		err = p.extract(sink)
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	results.ParsedFiles[inpath].Expanded = true
+
+	if root := storages[""]; *root != (models.Storage{}) {
+		result.Storage = root
+	}
+	delete(storages, "")
+	if len(storages) > 0 {
+		result.Storages = storages
+	}
+	result.Expanded = true
 	err = errors.Join(errs...)
 	return
 }
+
+func parseCompObj(data []byte, s *models.Storage) (err error) {
+	c, err := parsers.NewCompObj(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return
+	}
+	s.CompObj = &models.CompObj{
+		UserType:                   c.UserType,
+		ClipboardFormat:            c.ClipboardFormat,
+		ClipboardFormatName:        c.ClipboardFormatName,
+		ProgID:                     c.ProgID,
+		UnicodeUserType:            c.UnicodeUserType,
+		UnicodeClipboardFormatName: c.UnicodeClipboardFormatName,
+		UnicodeProgID:              c.UnicodeProgID,
+	}
+	return
+}
+
+func parseOleStream(data []byte, s *models.Storage) (err error) {
+	o, err := parsers.NewOleStream(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return
+	}
+	s.Ole = &models.OleStream{
+		Flags:            o.Flags,
+		LinkUpdateOption: o.LinkUpdateOption,
+		Linked:           o.Linked(),
+		Target:           o.Target(),
+		RelativeSource:   monikerModel(o.RelativeSource),
+		AbsoluteSource:   monikerModel(o.AbsoluteSource),
+		CLSID:            o.CLSID,
+		DisplayName:      o.DisplayName,
+	}
+	return
+}
+
+func monikerModel(m *parsers.Moniker) (model *models.Moniker) {
+	if m == nil {
+		return
+	}
+	model = &models.Moniker{Kind: m.Kind, CLSID: m.CLSID, Target: m.String()}
+	for _, part := range m.Monikers {
+		model.Monikers = append(model.Monikers, monikerModel(part))
+	}
+	return
+}
@@ -0,0 +1,171 @@
+package unpackers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+var pathToSampleDataDir = "../../test/data/"
+
+// Run an unpacker over data, and return the result it leaves, with the
+// members it extracted.
+func unpack(t *testing.T, u Unpacker, data []byte) (r *models.Result, sink *sinks.Memory, err error) {
+	t.Helper()
+	sink = sinks.NewMemory()
+	r, err = unpackTo(t, u, data, sink)
+	return
+}
+
+// Run an unpacker over data, extracting members to sink.
+func unpackTo(t *testing.T, u Unpacker, data []byte, sink sinks.Sink) (r *models.Result, err error) {
+	t.Helper()
+	results := models.NewResults()
+	results.Set("in", &models.Result{})
+	err = u.UnpackStream("in", bytes.NewReader(data), int64(len(data)), results, sink)
+	r = results.Get("in")
+	return
+}
+
+// Run an unpacker over a file in the test data, which it must unpack
+// without error.
+func unpackSample(t *testing.T, u Unpacker, name string) (r *models.Result, sink *sinks.Memory) {
+	t.Helper()
+	data, err := os.ReadFile(path.Join(pathToSampleDataDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, sink, err = unpack(t, u, data)
+	if err != nil {
+		t.Fatalf("unpacking %s: %v", name, err)
+	}
+	return
+}
+
+// Build a CompObj stream holding just the ANSI fields.
+func compObj(userType, progID string) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0x01, 0x00, 0xfe, 0xff, 0x03, 0x0a, 0x00, 0x00})
+	buf.Write(make([]byte, 20))
+	binary.Write(buf, binary.LittleEndian, uint32(len(userType)+1))
+	buf.WriteString(userType + "\x00")
+	binary.Write(buf, binary.LittleEndian, uint32(0))
+	binary.Write(buf, binary.LittleEndian, uint32(len(progID)+1))
+	buf.WriteString(progID + "\x00")
+	return buf.Bytes()
+}
+
+// The class ID and CompObj stream of Office documents end up on the
+// root storage.
+func TestMSCFBCompObj(t *testing.T) {
+	cases := []struct {
+		sample   string
+		clsid    string
+		userType string
+		format   string
+		progID   string
+	}{
+		{"novpapplan.doc", "{00020906-0000-0000-C000-000000000046}", "Microsoft Word Document", "MSWordDoc", "Word.Document.8"},
+		{"test.xls", "{00020810-0000-0000-C000-000000000046}", "Microsoft Excel 97-Tabelle", "Biff8", ""},
+	}
+	for _, c := range cases {
+		r, _ := unpackSample(t, &MSCFB{}, c.sample)
+		if !r.Expanded {
+			t.Errorf("%s: expected the file to be marked expanded", c.sample)
+		}
+		s := r.Storage
+		if s == nil || s.CompObj == nil {
+			t.Errorf("%s: expected a CompObj on the root storage, got %+v", c.sample, s)
+			continue
+		}
+		if s.CLSID != c.clsid {
+			t.Errorf("%s: CLSID mismatch - expected %s got %s", c.sample, c.clsid, s.CLSID)
+		}
+		if s.CompObj.UserType != c.userType || s.CompObj.ClipboardFormatName != c.format || s.CompObj.ProgID != c.progID {
+			t.Errorf("%s: CompObj mismatch: %+v", c.sample, s.CompObj)
+		}
+	}
+}
+
+// Each storage with a class ID or CompObj stream gets its own entry,
+// keyed by its path in the file.
+func TestMSCFBStorages(t *testing.T) {
+	packageCLSID := "{0003000C-0000-0000-C000-000000000046}"
+	cfb := cfbtest.New()
+	cfb.SetCLSID("", cfbtest.CLSID(packageCLSID))
+	cfb.Stream("\x01CompObj", compObj("OLE Package", "Package"))
+	cfb.SetCLSID("ObjectPool/_1", cfbtest.CLSID(packageCLSID))
+	cfb.Stream("ObjectPool/_1/\x01CompObj", compObj("Paintbrush Picture", "PBrush"))
+	cfb.Stream("ObjectPool/_2/data", []byte("no metadata here"))
+
+	r, sink, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storage == nil || r.Storage.CompObj == nil {
+		t.Fatalf("expected metadata on the root storage, got %+v", r.Storage)
+	}
+	if r.Storage.CLSID != packageCLSID || r.Storage.CompObj.ProgID != "Package" || r.Storage.CompObj.UserType != "OLE Package" {
+		t.Errorf("root storage mismatch: %+v, %+v", r.Storage, r.Storage.CompObj)
+	}
+	nested := r.Storages["ObjectPool/_1"]
+	if nested == nil || nested.CLSID != packageCLSID || nested.CompObj == nil || nested.CompObj.ProgID != "PBrush" {
+		t.Errorf("nested storage mismatch: %+v", nested)
+	}
+	for _, p := range []string{"ObjectPool", "ObjectPool/_2"} {
+		if _, ok := r.Storages[p]; ok {
+			t.Errorf("storage %s has no metadata, and should be left out", p)
+		}
+	}
+	if _, err = sink.Open("ObjectPool/_1/CompObj"); err != nil {
+		t.Errorf("expected the CompObj stream to be extracted: %v", err)
+	}
+}
+
+// An embedded object's Ole stream ends up on its storage, with whether
+// it's linked.
+func TestMSCFBOleStream(t *testing.T) {
+	r, _ := unpackSample(t, &MSCFB{}, "test.xls")
+	if r.Storage == nil || r.Storage.Ole == nil {
+		t.Fatalf("expected Ole stream metadata on the root storage, got %+v", r.Storage)
+	}
+	if r.Storage.Ole.Linked || r.Storage.Ole.Target != "" {
+		t.Errorf("expected the workbook not to be linked, got %+v", r.Storage.Ole)
+	}
+}
+
+// A linked object's Ole stream is flagged, with the link target, on the storage.
+func TestMSCFBLinkedObject(t *testing.T) {
+	url := utf16.Encode([]rune("\\\\198.51.100.7\\share\\doc.rtf\x00"))
+	urlMonikerCLSID := cfbtest.CLSID("{79EAC9E0-BAF9-11CE-8C82-00AA004BA90B}")
+	moniker := bytes.NewBuffer(urlMonikerCLSID[:])
+	binary.Write(moniker, binary.LittleEndian, uint32(len(url)*2))
+	binary.Write(moniker, binary.LittleEndian, url)
+	stream := &bytes.Buffer{}
+	binary.Write(stream, binary.LittleEndian, []uint32{0x02000001, 1, 1, 0, 0, 0, uint32(moniker.Len() + 4)})
+	moniker.WriteTo(stream)
+	cfb := cfbtest.New()
+	cfb.Stream("\x01Ole", stream.Bytes())
+
+	r, _, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storage == nil || r.Storage.Ole == nil {
+		t.Fatalf("expected Ole stream metadata on the root storage, got %+v", r.Storage)
+	}
+	ole := r.Storage.Ole
+	if !ole.Linked || ole.Target != `\\198.51.100.7\share\doc.rtf` {
+		t.Errorf("expected a link to the share, got %+v", ole)
+	}
+	if ole.AbsoluteSource == nil || ole.AbsoluteSource.Kind != "URL" {
+		t.Errorf("expected a URL moniker, got %+v", ole.AbsoluteSource)
+	}
+}
@@ -0,0 +1,167 @@
+package unpackers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the longest path element, in bytes, SanitizeName will
+// produce. Most filesystems top out at 255.
+const MaxNameLength = 255
+
+// Device names Windows reserves in every directory, with or without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM0": true, "COM1": true, "COM2": true, "COM3": true, "COM4": true,
+	"COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT0": true, "LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true,
+	"LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeName turns an archive member name into a relative, slash-separated
+// path which is safe to write on any common filesystem. Member names come from
+// untrusted input, so every unpacker's output goes through here:
+//   - Both slashes and backslashes separate path elements.
+//   - Absolute paths become relative, and ".." elements are neutralized rather
+//     than resolved, so the result can't escape the directory it is joined to.
+//   - Leading control characters (like the \x01 and \x05 prefixes on CFB stream
+//     names) are dropped, and other control characters are replaced.
+//   - Characters Windows doesn't allow in file names are replaced.
+//   - Windows device names (CON, NUL, COM1...) are prefixed with an underscore.
+//   - Elements longer than MaxNameLength are shortened, keeping the extension
+//     and adding a hash of the original so shortened names stay distinct.
+//
+// The result is never empty, and always satisfies fs.ValidPath.
+func SanitizeName(name string) (safe string) {
+	elements := strings.FieldsFunc(name, func(r rune) bool { return r == '/' || r == '\\' })
+	safeElements := make([]string, 0, len(elements))
+	for _, element := range elements {
+		if element == "." {
+			continue
+		}
+		safeElements = append(safeElements, sanitizeElement(element))
+	}
+	if len(safeElements) == 0 {
+		safeElements = append(safeElements, "_")
+	}
+	safe = path.Join(safeElements...)
+	return
+}
+
+// sanitizeElement makes a single path element safe.
+func sanitizeElement(element string) (safe string) {
+	element = strings.ToValidUTF8(element, "_")
+	element = strings.TrimLeftFunc(element, unicode.IsControl)
+	safe = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return '_'
+		}
+		return r
+	}, element)
+
+	// Windows quietly drops trailing dots and spaces, which would let
+	// "a." collide with "a". This also takes care of "..".
+	if trimmed := strings.TrimRight(safe, ". "); trimmed != safe {
+		safe = trimmed + strings.Repeat("_", len(safe)-len(trimmed))
+	}
+	if safe == "" {
+		safe = "_"
+	}
+
+	base := strings.ToUpper(strings.TrimSpace(strings.SplitN(safe, ".", 2)[0]))
+	if windowsReservedNames[base] {
+		safe = "_" + safe
+	}
+
+	if len(safe) > MaxNameLength {
+		sum := sha256.Sum256([]byte(element))
+		suffix := "~" + hex.EncodeToString(sum[:4])
+		ext := path.Ext(safe)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		keep := MaxNameLength - len(suffix) - len(ext)
+		for keep > 0 && !utf8.RuneStart(safe[keep]) {
+			keep--
+		}
+		safe = safe[:keep] + suffix + ext
+	}
+	return
+}
+
+// A Namer hands out unique, sanitized names for the members of a single
+// container. Names are compared case-insensitively, since the output may
+// land on a case-insensitive filesystem. When a name is already taken, a
+// numeric suffix is added before the extension ("invoice~2.pdf"), so the
+// result only depends on the order members are named in.
+//
+// Naming a file also reserves "${name}-members", the directory its own
+// members will be extracted to, so an archive can't smuggle files into
+// another member's output.
+type Namer struct {
+	// Case-folded names which are already in use.
+	taken map[string]bool
+
+	// Maps case-folded, sanitized directory names to the names handed out.
+	dirs map[string]string
+}
+
+// Create a new Namer for a container.
+func NewNamer() (n *Namer) {
+	n = &Namer{taken: map[string]bool{}, dirs: map[string]string{}}
+	return
+}
+
+// File returns a unique name for a file member.
+func (n *Namer) File(member string) (name string) {
+	dir, base := path.Split(SanitizeName(member))
+	dir = n.dir(strings.TrimSuffix(dir, "/"))
+	name = path.Join(dir, base)
+	for i := 2; n.taken[fold(name)] || n.taken[fold(name+"-members")]; i++ {
+		name = path.Join(dir, withSuffix(base, i))
+	}
+	n.taken[fold(name)] = true
+	n.taken[fold(name+"-members")] = true
+	return
+}
+
+// Dir returns the name for a directory member. Directories with the
+// same name (ignoring case) share a name.
+func (n *Namer) Dir(member string) (name string) {
+	name = n.dir(SanitizeName(member))
+	return
+}
+
+func (n *Namer) dir(safe string) (name string) {
+	if safe == "" || safe == "." {
+		return
+	}
+	if existing, ok := n.dirs[fold(safe)]; ok {
+		name = existing
+		return
+	}
+	parent, base := path.Split(safe)
+	parent = n.dir(strings.TrimSuffix(parent, "/"))
+	name = path.Join(parent, base)
+	for i := 2; n.taken[fold(name)]; i++ {
+		name = path.Join(parent, withSuffix(base, i))
+	}
+	n.taken[fold(name)] = true
+	n.dirs[fold(safe)] = name
+	return
+}
+
+// withSuffix adds ~i to the name, ahead of the extension.
+func withSuffix(base string, i int) string {
+	ext := path.Ext(base)
+	return fmt.Sprintf("%s~%d%s", strings.TrimSuffix(base, ext), i, ext)
+}
+
+func fold(name string) string {
+	return strings.ToLower(name)
+}
@@ -0,0 +1,88 @@
+package unpackers
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
+	}{
+		{"word/document.xml", "word/document.xml"},
+		{"../../etc/passwd", "__/__/etc/passwd"},
+		{"/etc/passwd", "etc/passwd"},
+		{`C:\Windows\System32\evil.dll`, "C_/Windows/System32/evil.dll"},
+		{"a/./b", "a/b"},
+		{"\x01Ole10Native", "Ole10Native"},
+		{"\x05SummaryInformation", "SummaryInformation"},
+		{"bad\x00name", "bad_name"},
+		{"what?.txt", "what_.txt"},
+		{"trailing. ", "trailing__"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"console.txt", "console.txt"},
+		{"", "_"},
+		{"/", "_"},
+	}
+	for _, c := range cases {
+		safe := SanitizeName(c.name)
+		if safe != c.expected {
+			t.Errorf("sanitizing %q - expected %q got %q", c.name, c.expected, safe)
+		}
+		if !fs.ValidPath(safe) {
+			t.Errorf("sanitizing %q produced an invalid path: %q", c.name, safe)
+		}
+	}
+}
+
+// Long names get shortened, but stay distinct and keep their extension.
+func TestSanitizeNameLength(t *testing.T) {
+	a := SanitizeName(strings.Repeat("a", 300) + "1.pdf")
+	b := SanitizeName(strings.Repeat("a", 300) + "2.pdf")
+	if len(a) > MaxNameLength || len(b) > MaxNameLength {
+		t.Errorf("expected names of at most %d bytes, got %d and %d", MaxNameLength, len(a), len(b))
+	}
+	if a == b {
+		t.Errorf("shortened names collide: %s", a)
+	}
+	if !strings.HasSuffix(a, ".pdf") {
+		t.Errorf("expected the extension to survive, got %s", a)
+	}
+}
+
+// Colliding names get suffixed, in a predictable way.
+func TestNamer(t *testing.T) {
+	n := NewNamer()
+	cases := []struct {
+		file     bool
+		member   string
+		expected string
+	}{
+		{true, "invoice.pdf", "invoice.pdf"},
+		{true, "invoice.pdf", "invoice~2.pdf"},
+		{true, "INVOICE.pdf", "INVOICE~3.pdf"},
+		{true, "word/document.xml", "word/document.xml"},
+		{true, "Word/Document.xml", "word/Document~2.xml"},
+		{false, "WORD", "word"},
+		{true, "data", "data"},
+		{true, "data/nested.bin", "data~2/nested.bin"},
+		{true, "data/other.bin", "data~2/other.bin"},
+		{true, "data-members/planted.txt", "data-members~2/planted.txt"},
+		{true, "Makefile", "Makefile"},
+		{true, "makefile", "makefile~2"},
+	}
+	for _, c := range cases {
+		var name string
+		if c.file {
+			name = n.File(c.member)
+		} else {
+			name = n.Dir(c.member)
+		}
+		if name != c.expected {
+			t.Errorf("naming %q - expected %q got %q", c.member, c.expected, name)
+		}
+	}
+}
@@ -2,70 +2,198 @@ package unpackers
 
 import (
 	"archive/zip"
-	"container/list"
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path"
+	"sort"
 
 	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
 )
 
 // This implementation of the Unpacker interface uses archive/zip
 // to extract all members from the Office document.
 type OfficeZip struct{}
 
-// Unpack all the archive members and queue them up for parsing.
-func (o *OfficeZip) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, queue *list.List) (err error) {
-	// Base path is inpath-members/
-	basePath := fmt.Sprintf("%s-members", inpath)
-	err = os.MkdirAll(basePath, 0770)
-	if err != nil {
-		return
-	}
+func init() {
+	Register(Registration{
+		Name: "officezip",
+		// Every kind of Office Open XML document, and other OPC
+		// packages, as told apart by OfficeType.
+		Match: Matcher{
+			MIMETypes: append(officeMIMETypes(), OPCPackageType),
+		},
+		Unpacker: &OfficeZip{},
+	})
+}
+
+// Unpack all the archive members and write them to the sink.
+func (o *OfficeZip) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
 	errs := []error{}
 
 	// Open as a zip archive
 	var rdr *zip.Reader
 	rdr, err = zip.NewReader(stream, size)
+	if err != nil {
+		err = fmt.Errorf("%w: opening zip archive", err)
+		return
+	}
+
+	pkg := newZipPackage(rdr)
+	result := results.Get(inpath)
+	result.Office = officeDocument(pkg)
+
+	parts, err := pkg.parts()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// Parse the workbook of spreadsheets first, so macro sheets can be
+	// typed as they're extracted.
+	wb, err := findOfficeWorkbook(pkg, result.Office)
+	if err != nil {
+		errs = append(errs, err)
+	}
 
 	// Iterate through members
 	for _, f := range rdr.File {
-		rstat := f.FileInfo()
-		if rstat.IsDir() {
-			dName := path.Join(basePath, rstat.Name())
-			err = os.MkdirAll(dName, 0770)
+		if f.FileInfo().IsDir() {
+			err = sink.Mkdir(f.Name)
 			if err != nil {
-				err = fmt.Errorf("%w: creating directory %s", err, dName)
+				err = fmt.Errorf("%w: creating directory %s", err, f.Name)
 				errs = append(errs, err)
 			}
 			continue
 		}
-		newFilePath := path.Join(basePath, rstat.Name())
-		var newFile *os.File
-		newFile, err = os.Create(newFilePath)
-		if err != nil {
-			err = fmt.Errorf("%w: creating file %s", err, newFilePath)
-			errs = append(errs, err)
-			continue
+
+		// Skip anything that looks like a decompression bomb.
+		if limiter, ok := sink.(Limiter); ok {
+			err = limiter.CheckRatio(f.Name, int64(f.CompressedSize64), int64(f.UncompressedSize64))
+			if err != nil {
+				continue
+			}
+		}
+		fileType := ""
+		if wb != nil {
+			fileType = wb.partType(f.Name)
+		}
+		err = extractZipMember(f, sink, &models.Result{FileType: fileType, Part: parts[f.Name]})
+		if errors.Is(err, ErrLimitExceeded) {
+			break
 		}
-		fHandle, err := f.Open()
 		if err != nil {
-			err = fmt.Errorf("%w: opening archive member %s", err, f.Name)
 			errs = append(errs, err)
-			continue
 		}
-		defer fHandle.Close()
-		_, err = io.Copy(newFile, fHandle)
+	}
+	if wb != nil {
+		result.Workbook, err = wb.parseMacroSheets(pkg)
 		if err != nil {
-			err = fmt.Errorf("%w: extracting member %s to %s", err, f.Name, newFilePath)
 			errs = append(errs, err)
-			continue
 		}
-		queue.PushBack(newFilePath)
 	}
-	results.ParsedFiles[inpath].Expanded = true
+	result.Expanded = true
 	err = errors.Join(errs...)
 	return
 }
+
+// OfficeType returns the MIME type of the Office Open XML document in a
+// zip archive. mimetype only tells .docx, .xlsx and .pptx files apart,
+// by the names of their members, so this goes by the content type of
+// the package's main part instead. This covers macro-enabled documents,
+// templates, add-ins and themes. Other archives with a
+// [Content_Types].xml part are OPC packages, and get OPCPackageType.
+//
+//	Args:
+//		stream (io.ReaderAt):	The zip archive.
+//		size (int64):		The size of the archive.
+//
+//	Returns:
+//		mimeType (string):	The MIME type, or "" if the archive isn't
+//					an OPC package.
+func OfficeType(stream io.ReaderAt, size int64) (mimeType string) {
+	rdr, err := zip.NewReader(stream, size)
+	if err != nil {
+		return
+	}
+	pkg := newZipPackage(rdr)
+	if doc := officeDocument(pkg); doc != nil {
+		mimeType = officeKinds[doc.ContentType].mimeType
+	} else if _, ok := pkg.files["[Content_Types].xml"]; ok {
+		mimeType = OPCPackageType
+	}
+	return
+}
+
+// Find the kind of Office Open XML document in a package, from
+// [Content_Types].xml and the package's main part. Packages without a
+// main part of a known content type get nil.
+func officeDocument(pkg *zipPackage) (doc *models.OfficeDocument) {
+	types, _ := pkg.contentTypes()
+	if types == nil {
+		return
+	}
+	main := pkg.mainPart()
+	if main == "" {
+		// Without relationships, fall back on any part with the
+		// content type of a main part. Slides and themes come last,
+		// as they're parts of most documents too.
+		parts := []string{}
+		for part, t := range types.Overrides {
+			if _, ok := officeKinds[t]; ok {
+				parts = append(parts, part)
+			}
+		}
+		sort.Slice(parts, func(i, j int) bool {
+			si, sj := sharedPartTypes[types.Overrides[parts[i]]], sharedPartTypes[types.Overrides[parts[j]]]
+			if si != sj {
+				return sj
+			}
+			return parts[i] < parts[j]
+		})
+		for _, part := range parts {
+			if _, ok := pkg.files[part]; ok {
+				main = part
+				break
+			}
+		}
+	}
+	contentType := types.PartType(main)
+	k, ok := officeKinds[contentType]
+	if !ok {
+		return
+	}
+	doc = &models.OfficeDocument{
+		Kind:         k.kind,
+		Application:  k.application,
+		MainPart:     main,
+		ContentType:  contentType,
+		MacroEnabled: k.macroEnabled,
+	}
+	return
+}
+
+// Write a single zip archive member to the sink, keeping its path
+// inside the archive. The sink takes care of sanitizing the name. What
+// we know of the member goes in described.
+func extractZipMember(f *zip.File, sink sinks.Sink, described *models.Result) (err error) {
+	name := f.Name
+	fHandle, err := f.Open()
+	if err != nil {
+		err = fmt.Errorf("%w: opening archive member %s", err, f.Name)
+		return
+	}
+	defer fHandle.Close()
+	newFile, err := createDescribed(sink, name, described)
+	if err != nil {
+		err = fmt.Errorf("%w: creating file %s", err, name)
+		return
+	}
+	_, err = io.Copy(newFile, fHandle)
+	if err != nil {
+		newFile.Close()
+		err = fmt.Errorf("%w: extracting member %s to %s", err, f.Name, name)
+		return
+	}
+	err = newFile.Close()
+	return
+}
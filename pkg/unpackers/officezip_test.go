@@ -0,0 +1,426 @@
+package unpackers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/xlsbtest"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+type zipMember struct {
+	name string
+	body []byte
+}
+
+// Build a zip archive of the members, in order.
+func zipArchive(t *testing.T, members []zipMember) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(m.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// A memory sink which keeps what unpackers describe each member with.
+type describingSink struct {
+	*sinks.Memory
+	described map[string]*models.Result
+}
+
+func newDescribingSink() *describingSink {
+	return &describingSink{Memory: sinks.NewMemory(), described: map[string]*models.Result{}}
+}
+
+func (s *describingSink) CreateDescribed(name string, result *models.Result) (io.WriteCloser, error) {
+	s.described[name] = result
+	return s.Create(name)
+}
+
+// The workbook of a real .xlsx file is listed on its result.
+func TestOfficeZipWorkbook(t *testing.T) {
+	r, sink := unpackSample(t, &OfficeZip{}, "xlsx.xlsx")
+	if r.Office == nil || r.Office.Kind != "xlsx" || r.Office.MainPart != "xl/workbook.xml" || r.Office.MacroEnabled {
+		t.Errorf("document mismatch: %+v", r.Office)
+	}
+	if r.Workbook == nil || len(r.Workbook.Sheets) != 1 || *r.Workbook.Sheets[0] != (models.Sheet{Name: "Foaie1", Type: "Worksheet", Visibility: "Visible"}) {
+		t.Errorf("workbook mismatch: %+v", r.Workbook)
+	} else if len(r.Workbook.Macros) != 0 {
+		t.Errorf("expected no macros, got %+v", r.Workbook.Macros)
+	}
+	if _, err := sink.Open("xl/worksheets/sheet1.xml"); err != nil {
+		t.Errorf("expected the worksheet to be extracted: %v", err)
+	}
+}
+
+// Build an .xlsm or .xlsb file with a worksheet and a macro sheet. The
+// workbook and macro sheet parts are given, along with the extension of
+// their names.
+func macroWorkbook(t *testing.T, ext string, workbook, macroSheet []byte) []byte {
+	t.Helper()
+	contentType := "application/vnd.ms-excel.sheet.macroEnabled.main+xml"
+	if ext == "bin" {
+		contentType = "application/vnd.ms-excel.sheet.binary.macroEnabled.main"
+	}
+	return zipArchive(t, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Override PartName="/xl/workbook.` + ext + `" ContentType="` + contentType + `"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.` + ext + `"/></Relationships>`)},
+		{"xl/workbook." + ext, workbook},
+		{"xl/_rels/workbook." + ext + ".rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.` + ext + `"/><Relationship Id="rId2" Type="http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet" Target="/xl/macrosheets/sheet1.` + ext + `"/></Relationships>`)},
+		{"xl/macrosheets/sheet1." + ext, macroSheet},
+	})
+}
+
+// Macro sheets of .xlsm and .xlsb files are typed as they're extracted,
+// and their formulas listed the way those of .xls files are.
+func TestOfficeZipXLM(t *testing.T) {
+	xlsb := &bytes.Buffer{}
+	xlsbtest.Record(xlsb, 131)
+	xlsbtest.Record(xlsb, 156, xlsbtest.U32(0, 1), xlsbtest.Wide("rId1"), xlsbtest.Wide("Sheet1"))
+	xlsbtest.Record(xlsb, 156, xlsbtest.U32(2, 2), xlsbtest.Wide("rId2"), xlsbtest.Wide("Macro1"))
+	xlsbtest.Record(xlsb, 357)
+	xlsbtest.Record(xlsb, 362, xlsbtest.U32(1, 0, 1, 1))
+	xlsbtest.Record(xlsb, 39, xlsbtest.U32(0x0020), []byte{0}, xlsbtest.U32(0xFFFFFFFF), xlsbtest.Wide("Auto_Open"), xlsbtest.U32(9), []byte{0x3A, 0, 0, 0, 0, 0, 0, 0, 0})
+	xlsbWorkbook := bytes.Clone(xlsb.Bytes())
+	formula := func(rgce []byte) []byte {
+		return bytes.Join([][]byte{xlsbtest.U32(0, 0), make([]byte, 8), {0, 0}, xlsbtest.U32(uint32(len(rgce))), rgce}, nil)
+	}
+	xlsb.Reset()
+	xlsbtest.Record(xlsb, 0, xlsbtest.U32(0), make([]byte, 13))
+	xlsbtest.Record(xlsb, 9, formula(append(append([]byte{0x17, 8, 0}, xlsbtest.Wide("calc.exe")[4:]...), 0x42, 0x01, 0x6E, 0x00)))
+	xlsbtest.Record(xlsb, 0, xlsbtest.U32(1), make([]byte, 13))
+	xlsbtest.Record(xlsb, 9, formula([]byte{0x42, 0x00, 0x36, 0x00}))
+
+	cases := []struct {
+		kind, ext, fileType  string
+		workbook, macroSheet []byte
+	}{
+		{"xlsm", "xml", XLSXMacroSheetType,
+			[]byte(`<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/><sheet name="Macro1" sheetId="2" state="veryHidden" r:id="rId2"/></sheets><definedNames><definedName name="_xlnm.Auto_Open">Macro1!$A$1</definedName></definedNames></workbook>`),
+			[]byte(`<?xml version="1.0" encoding="UTF-8"?><xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main"><sheetData><row r="1"><c r="A1"><f>EXEC("calc.exe")</f></c></row><row r="2"><c r="A2"><f>HALT()</f></c></row></sheetData></xm:macrosheet>`)},
+		{"xlsb", "bin", XLSBMacroSheetType, xlsbWorkbook, xlsb.Bytes()},
+	}
+	for _, c := range cases {
+		sink := newDescribingSink()
+		r, err := unpackTo(t, &OfficeZip{}, macroWorkbook(t, c.ext, c.workbook, c.macroSheet), sink)
+		if err != nil {
+			t.Fatalf("%s: %v", c.kind, err)
+		}
+		if r.Office == nil || r.Office.Kind != c.kind || !r.Office.MacroEnabled {
+			t.Errorf("%s: expected a macro-enabled document, got %+v", c.kind, r.Office)
+		}
+		if sheet := sink.described["xl/macrosheets/sheet1."+c.ext]; sheet == nil || sheet.FileType != c.fileType {
+			t.Errorf("%s: expected a macro sheet of type %s, got %+v", c.kind, c.fileType, sheet)
+		}
+		w := r.Workbook
+		if w == nil {
+			t.Fatalf("%s: expected a workbook", c.kind)
+		}
+		if len(w.Sheets) != 2 || *w.Sheets[1] != (models.Sheet{Name: "Macro1", Type: "Macro", Visibility: "VeryHidden"}) {
+			t.Errorf("%s: sheets mismatch: %+v", c.kind, w.Sheets)
+		}
+		if len(w.Names) != 1 || *w.Names[0] != (models.DefinedName{Name: "Auto_Open", Formula: "=Macro1!$A$1", Builtin: true, AutoRun: true}) {
+			t.Errorf("%s: names mismatch: %+v", c.kind, w.Names)
+		}
+		expected := []models.XLMFormula{
+			{Sheet: "Macro1", Cell: "A1", Formula: `=EXEC("calc.exe")`},
+			{Sheet: "Macro1", Cell: "A2", Formula: "=HALT()"},
+		}
+		if len(w.Macros) != len(expected) {
+			t.Fatalf("%s: expected %d macro formulas, got %+v", c.kind, len(expected), w.Macros)
+		}
+		for i := range expected {
+			if *w.Macros[i] != expected[i] {
+				t.Errorf("%s: formula %d mismatch - expected %+v got %+v", c.kind, i, expected[i], w.Macros[i])
+			}
+		}
+	}
+}
+
+// Only spreadsheets have their main part read for a workbook. A Word
+// document whose main part would pass for one is left alone.
+func TestOfficeZipWorkbookContentType(t *testing.T) {
+	data := zipArchive(t, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.document.macroEnabled.main+xml"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`)},
+		{"word/document.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Macro1" sheetId="1" r:id="rId1"/></sheets></workbook>`)},
+		{"word/_rels/document.xml.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.microsoft.com/office/2006/relationships/xlMacrosheet" Target="macrosheets/sheet1.xml"/></Relationships>`)},
+		{"word/macrosheets/sheet1.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><xm:macrosheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:xm="http://schemas.microsoft.com/office/excel/2006/main"/>`)},
+	})
+	sink := newDescribingSink()
+	r, err := unpackTo(t, &OfficeZip{}, data, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Office == nil || r.Office.Kind != "docm" {
+		t.Errorf("expected a .docm document, got %+v", r.Office)
+	}
+	if r.Workbook != nil {
+		t.Errorf("expected no workbook, got %+v", r.Workbook)
+	}
+	if sheet := sink.described["word/macrosheets/sheet1.xml"]; sheet == nil || sheet.FileType != "" {
+		t.Errorf("expected the part not to be typed as a macro sheet, got %+v", sheet)
+	}
+}
+
+// Build an Office Open XML package whose main part has the content type.
+func opcPackage(t *testing.T, mainPart, contentType string) []byte {
+	t.Helper()
+	return zipArchive(t, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/` + mainPart + `" ContentType="` + contentType + `"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="` + mainPart + `"/></Relationships>`)},
+		{mainPart, []byte(`<?xml version="1.0" encoding="UTF-8"?><main/>`)},
+	})
+}
+
+// Real .docx, .xlsx and .pptx files are told apart by the content type
+// of their main part.
+func TestOfficeTypeSamples(t *testing.T) {
+	cases := []struct {
+		sample, fileType string
+		expected         models.OfficeDocument
+	}{
+		{"docx.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			models.OfficeDocument{Kind: "docx", Application: "Word", MainPart: "word/document.xml", ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"}},
+		{"xlsx.xlsx", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			models.OfficeDocument{Kind: "xlsx", Application: "Excel", MainPart: "xl/workbook.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"}},
+		{"pptx.pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			models.OfficeDocument{Kind: "pptx", Application: "PowerPoint", MainPart: "ppt/presentation.xml", ContentType: "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml"}},
+	}
+	for _, c := range cases {
+		data, err := os.ReadFile(path.Join(pathToSampleDataDir, c.sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileType := OfficeType(bytes.NewReader(data), int64(len(data))); fileType != c.fileType {
+			t.Errorf("%s: type mismatch - expected %s got %s", c.sample, c.fileType, fileType)
+		}
+		r, _ := unpackSample(t, &OfficeZip{}, c.sample)
+		if r.Office == nil || *r.Office != c.expected {
+			t.Errorf("%s: document mismatch - expected %+v got %+v", c.sample, c.expected, r.Office)
+		}
+	}
+}
+
+// Variants of .docx, .xlsx and .pptx files are told apart by the content
+// type of their main part.
+func TestOfficeTypeVariants(t *testing.T) {
+	cases := []struct {
+		name, mainPart, contentType string
+		fileType                    string
+		expected                    models.OfficeDocument
+	}{
+		{"report.docm", "word/document.xml", "application/vnd.ms-word.document.macroEnabled.main+xml", "application/vnd.ms-word.document.macroEnabled.12",
+			models.OfficeDocument{Kind: "docm", Application: "Word", MacroEnabled: true}},
+		{"report.dotm", "word/document.xml", "application/vnd.ms-word.template.macroEnabledTemplate.main+xml", "application/vnd.ms-word.template.macroEnabled.12",
+			models.OfficeDocument{Kind: "dotm", Application: "Word", MacroEnabled: true}},
+		{"tools.xlam", "xl/workbook.xml", "application/vnd.ms-excel.addin.macroEnabled.main+xml", "application/vnd.ms-excel.addin.macroEnabled.12",
+			models.OfficeDocument{Kind: "xlam", Application: "Excel", MacroEnabled: true}},
+		{"deck.ppsm", "ppt/presentation.xml", "application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml", "application/vnd.ms-powerpoint.slideshow.macroEnabled.12",
+			models.OfficeDocument{Kind: "ppsm", Application: "PowerPoint", MacroEnabled: true}},
+		{"deck.potx", "ppt/presentation.xml", "application/vnd.openxmlformats-officedocument.presentationml.template.main+xml", "application/vnd.openxmlformats-officedocument.presentationml.template",
+			models.OfficeDocument{Kind: "potx", Application: "PowerPoint"}},
+		{"brand.thmx", "theme/theme/theme1.xml", "application/vnd.openxmlformats-officedocument.theme+xml", "application/vnd.ms-officetheme",
+			models.OfficeDocument{Kind: "thmx", Application: "Office"}},
+	}
+	for _, c := range cases {
+		data := opcPackage(t, c.mainPart, c.contentType)
+		if fileType := OfficeType(bytes.NewReader(data), int64(len(data))); fileType != c.fileType {
+			t.Errorf("%s: type mismatch - expected %s got %s", c.name, c.fileType, fileType)
+		}
+		r, sink, err := unpack(t, &OfficeZip{}, data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		c.expected.MainPart, c.expected.ContentType = c.mainPart, c.contentType
+		if r.Office == nil || *r.Office != c.expected {
+			t.Errorf("%s: document mismatch - expected %+v got %+v", c.name, c.expected, r.Office)
+		}
+		if _, err = sink.Open(c.mainPart); err != nil {
+			t.Errorf("%s: expected %s to be extracted", c.name, c.mainPart)
+		}
+	}
+
+	// Zip archives which aren't packages are left alone.
+	data := zipArchive(t, []zipMember{{"notes.txt", []byte("hello")}})
+	if fileType := OfficeType(bytes.NewReader(data), int64(len(data))); fileType != "" {
+		t.Errorf("expected a plain zip archive to have no type, got %s", fileType)
+	}
+}
+
+// Without a relationships part, the main part is found by its content
+// type, passing over the theme and slides every document may have.
+func TestOfficeZipWithoutRelationships(t *testing.T) {
+	cases := []struct {
+		name, mainPart, contentType string
+		expected                    string
+	}{
+		{"book.xlsx", "xl/workbook.xml", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml", "xlsx"},
+		{"deck.pptx", "ppt/presentation.xml", "application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml", "pptx"},
+	}
+	for _, c := range cases {
+		data := zipArchive(t, []zipMember{
+			{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="xml" ContentType="application/xml"/>` +
+				`<Override PartName="/` + c.mainPart + `" ContentType="` + c.contentType + `"/>` +
+				`<Override PartName="/a/slides/slide1.xml" ContentType="application/vnd.openxmlformats-officedocument.presentationml.slide+xml"/>` +
+				`<Override PartName="/a/theme/theme1.xml" ContentType="application/vnd.openxmlformats-officedocument.theme+xml"/></Types>`)},
+			{"a/slides/slide1.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><sld/>`)},
+			{"a/theme/theme1.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><theme/>`)},
+			{c.mainPart, []byte(`<?xml version="1.0" encoding="UTF-8"?><main/>`)},
+		})
+		r, _, err := unpack(t, &OfficeZip{}, data)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if r.Office == nil || r.Office.Kind != c.expected || r.Office.MainPart != c.mainPart {
+			t.Errorf("%s: expected %s with main part %s, got %+v", c.name, c.expected, c.mainPart, r.Office)
+		}
+	}
+}
+
+// Parts of OPC packages carry their content type, the relationships
+// pointing at them, and whether nothing does.
+func TestOfficeZipParts(t *testing.T) {
+	docx := zipArchive(t, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="png" ContentType="image/png"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`)},
+		{"word/document.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"/>`)},
+		{"word/_rels/document.xml.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId4" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image1.png"/><Relationship Id="rId5" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/attachedTemplate" Target="https://example.com/t.dotm" TargetMode="External"/><Relationship Id="rId6" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="media/image%203.png"/><Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="Media/IMAGE4.PNG"/></Relationships>`)},
+		{"word/media/image1.png", []byte("\x89PNG\r\n\x1a\n")},
+		{"word/media/image2.png", []byte("\x89PNG\r\n\x1a\n")},
+		{"word/media/image 3.png", []byte("\x89PNG\r\n\x1a\n")},
+		{"word/media/image4.png", []byte("\x89PNG\r\n\x1a\n")},
+	})
+	sink := newDescribingSink()
+	r, err := unpackTo(t, &OfficeZip{}, docx, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Office == nil || r.Office.Kind != "docx" {
+		t.Errorf("expected a docx, got %+v", r.Office)
+	}
+	cases := []struct {
+		member   string
+		expected models.PackagePart
+	}{
+		{"[Content_Types].xml", models.PackagePart{}},
+		{"_rels/.rels", models.PackagePart{ContentType: "application/vnd.openxmlformats-package.relationships+xml"}},
+		{"word/document.xml", models.PackagePart{
+			ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+			References:  []*models.PartReference{{Source: "", ID: "rId1", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"}},
+		}},
+		{"word/media/image1.png", models.PackagePart{
+			ContentType: "image/png",
+			References:  []*models.PartReference{{Source: "word/document.xml", ID: "rId4", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"}},
+		}},
+		{"word/media/image2.png", models.PackagePart{ContentType: "image/png", Orphaned: true}},
+		// Targets are percent-decoded, and part names compared without
+		// regard to case.
+		{"word/media/image 3.png", models.PackagePart{
+			ContentType: "image/png",
+			References:  []*models.PartReference{{Source: "word/document.xml", ID: "rId6", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"}},
+		}},
+		{"word/media/image4.png", models.PackagePart{
+			ContentType: "image/png",
+			References:  []*models.PartReference{{Source: "word/document.xml", ID: "rId7", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"}},
+		}},
+	}
+	for _, c := range cases {
+		described := sink.described[c.member]
+		if described == nil || described.Part == nil {
+			t.Errorf("%s: expected a package part, got %+v", c.member, described)
+			continue
+		}
+		actual, _ := json.Marshal(described.Part)
+		expected, _ := json.Marshal(c.expected)
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s: part mismatch - expected %s got %s", c.member, expected, actual)
+		}
+	}
+
+	// Other OPC packages, like XPS documents, are unpacked the same way.
+	xps := zipArchive(t, []zipMember{
+		{"[Content_Types].xml", []byte(`<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="fdseq" ContentType="application/vnd.ms-package.xps-fixeddocumentsequence+xml"/></Types>`)},
+		{"_rels/.rels", []byte(`<?xml version="1.0" encoding="UTF-8"?><Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="R0" Type="http://schemas.microsoft.com/xps/2005/06/fixedrepresentation" Target="/FixedDocSeq.fdseq"/></Relationships>`)},
+		{"FixedDocSeq.fdseq", []byte(`<FixedDocumentSequence xmlns="http://schemas.microsoft.com/xps/2005/06"/>`)},
+	})
+	if fileType := OfficeType(bytes.NewReader(xps), int64(len(xps))); fileType != OPCPackageType {
+		t.Errorf("expected an XPS document to be typed %s, got %s", OPCPackageType, fileType)
+	}
+	sink = newDescribingSink()
+	r, err = unpackTo(t, &OfficeZip{}, xps, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Office != nil {
+		t.Errorf("expected no Office document, got %+v", r.Office)
+	}
+	seq := sink.described["FixedDocSeq.fdseq"]
+	if seq == nil || seq.Part == nil || seq.Part.ContentType != "application/vnd.ms-package.xps-fixeddocumentsequence+xml" || len(seq.Part.References) != 1 || seq.Part.Orphaned {
+		t.Errorf("expected a referenced document sequence, got %+v", seq)
+	}
+}
+
+// The parts of a real .docx file carry the relationships pointing at
+// them.
+func TestOfficeZipPartsSample(t *testing.T) {
+	data, err := os.ReadFile(path.Join(pathToSampleDataDir, "docx.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := newDescribingSink()
+	if _, err = unpackTo(t, &OfficeZip{}, data, sink); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		member   string
+		expected models.PackagePart
+	}{
+		{"word/document.xml", models.PackagePart{
+			ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml",
+			References:  []*models.PartReference{{Source: "", ID: "rId3", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"}},
+		}},
+		{"word/styles.xml", models.PackagePart{
+			ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml",
+			References:  []*models.PartReference{{Source: "word/document.xml", ID: "rId1", Type: "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"}},
+		}},
+		{"docProps/core.xml", models.PackagePart{
+			ContentType: "application/vnd.openxmlformats-package.core-properties+xml",
+			References:  []*models.PartReference{{Source: "", ID: "rId1", Type: "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"}},
+		}},
+	}
+	for _, c := range cases {
+		described := sink.described[c.member]
+		if described == nil || described.Part == nil {
+			t.Errorf("%s: expected a package part, got %+v", c.member, described)
+			continue
+		}
+		actual, _ := json.Marshal(described.Part)
+		expected, _ := json.Marshal(c.expected)
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s: part mismatch - expected %s got %s", c.member, expected, actual)
+		}
+	}
+	for member, described := range sink.described {
+		if described.Part != nil && described.Part.Orphaned {
+			t.Errorf("%s: expected every part of the document to be referenced", member)
+		}
+	}
+}
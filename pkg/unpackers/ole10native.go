@@ -1,59 +1,153 @@
 package unpackers
 
 import (
-	"bytes"
-	"container/list"
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path"
+	"strings"
 
 	"github.com/ashdwilson/ole/pkg/models"
 	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
 )
 
+// The OLE10Native implementation of Unpacker extracts the object
+// embedded in an OLE 1.0 "Ole10Native" stream. Any data following the
+// object is extracted as a second member, named TrailingDataName.
+// Streams which don't hold a Packager object have their native data
+// extracted as is, named after its kind (see parsers.NativeDataName).
+// Linked Packager objects carry no file, only the path of the one they
+// link to, which is recorded on the stream's result.
 type OLE10Native struct{}
 
-func (o *OLE10Native) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, queue *list.List) (err error) {
-	// Set a base path for extracting embedded objects.
-	basePath := fmt.Sprintf("%s-members", inpath)
-	err = os.MkdirAll(basePath, 0770)
-	if err != nil {
-		return
-	}
+// Member name for the data found after the object in an Ole10Native stream.
+const TrailingDataName = "ole10-trailing.bin"
+
+func init() {
+	Register(Registration{
+		Name: "ole10native",
+		Match: Matcher{
+			MIMETypes: []string{"application/octet-stream"},
+			FileNames: []string{"Ole10Native"},
+		},
+		Unpacker: &OLE10Native{},
+	})
+
+	// Trailing data is recorded on the stream's result, there's
+	// nothing more to unpack unless it turns out to be a known type.
+	Register(Registration{
+		Name: "ole10-trailing",
+		Match: Matcher{
+			MIMETypes:   []string{"application/octet-stream"},
+			FileNames:   []string{TrailingDataName},
+			ExtractedBy: []string{"ole10native"},
+		},
+	})
+
+	// The same goes for native data we can't do anything more with.
+	// Its kind is recorded on the stream's result. Only members these
+	// unpackers named match, not any file which happens to share a name.
+	Register(Registration{
+		Name: "ole1-native",
+		Match: Matcher{
+			MIMETypes:   []string{"application/octet-stream"},
+			FileNames:   []string{"native.bin", "native.eqn", "native.biff"},
+			ExtractedBy: []string{"ole10native", "ole1object"},
+		},
+	})
+}
+
+func (o *OLE10Native) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
 	errs := []error{}
 
-	// Turn the io.ReaderAt into a buffer.
-	streamBuf := make([]byte, size)
-	_, err = stream.ReadAt(streamBuf, 0)
+	// The object is read straight from the stream, not buffered.
+	oleReader, err := parsers.NewOle10ReaderAt(stream, size)
 	if err != nil {
+		err = fmt.Errorf("%w: parsing OLE 1.0 container", err)
 		return
 	}
-	s := bytes.NewBuffer(streamBuf)
 
-	oleReader, err := parsers.NewOle10(s)
-	if err != nil {
-		err = fmt.Errorf("%w: parsing OLE 1.0 container", err)
+	// The Unicode fields after the object are read ahead of it, so all
+	// of the metadata is recorded even if extracting the object fails.
+	// The rest of the trailing data is copied out after the object.
+	trailing, trailingSize, trailingErr := oleReader.Trailing()
+	if trailingErr != nil {
+		errs = append(errs, fmt.Errorf("%w: reading trailing data", trailingErr))
+	}
+	result := results.Get(inpath)
+	result.Ole10 = &models.Ole10{
+		Kind:                oleReader.Kind,
+		TotalSize:           oleReader.TotalSize,
+		Flags:               oleReader.Flags,
+		Reserved:            oleReader.Reserved,
+		ObjectType:          oleReader.ObjectType,
+		Label:               oleReader.Label,
+		SourcePath:          oleReader.SourcePath,
+		TempPath:            oleReader.TempPath,
+		Size:                oleReader.Size,
+		UnicodeLabel:        oleReader.UnicodeLabel,
+		UnicodeSourcePath:   oleReader.UnicodeSourcePath,
+		UnicodeTempPath:     oleReader.UnicodeTempPath,
+		TrailingSize:        trailingSize,
+		UnknownTrailingSize: oleReader.UnknownTrailingSize,
+	}
+
+	// Linked objects only point at a file, there's nothing to extract
+	// but whatever follows the header.
+	if oleReader.ObjectType == parsers.Ole10Linked {
+		if trailingSize > 0 {
+			result.Expanded = true
+			errs = append(errs, extractTrailing(sink, trailing))
+		}
+		err = errors.Join(errs...)
 		return
 	}
 
+	// TrailingDataName is kept for the trailing data, so an object of
+	// that name can't pass itself off as it.
+	name := oleReader.Name
+	if strings.EqualFold(name, TrailingDataName) {
+		name = "_" + name
+	}
+
 	// Create an output file and read the object into it
-	fullNewFilePath := path.Join(basePath, oleReader.Name)
-	outFile, err := os.Create(fullNewFilePath)
+	outFile, err := sink.Create(name)
+	if errors.Is(err, ErrLimitExceeded) {
+		err = errors.Join(errs...)
+		return
+	}
 	if err != nil {
 		err = fmt.Errorf("%w: creating a new file to receive the object", err)
 		return
 	}
+	result.Expanded = true
 	_, err = io.Copy(outFile, oleReader)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrLimitExceeded) {
+		outFile.Close()
 		err = fmt.Errorf("%w: writing the extracted object to a file", err)
 		return
 	}
+	err = outFile.Close()
+	if err != nil {
+		err = fmt.Errorf("%w: closing the extracted object", err)
+		return
+	}
 
-	queue.PushBack(fullNewFilePath)
-
-	results.ParsedFiles[inpath].Expanded = true
+	if trailingSize > 0 {
+		errs = append(errs, extractTrailing(sink, trailing))
+	}
 	err = errors.Join(errs...)
 	return
 }
+
+// Anything after the object is extracted as a separate member.
+func extractTrailing(sink sinks.Sink, trailing io.Reader) (err error) {
+	err = copyMember(sink, TrailingDataName, nil, trailing)
+	if errors.Is(err, ErrLimitExceeded) {
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: writing trailing data", err)
+	}
+	return
+}
@@ -0,0 +1,165 @@
+package unpackers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// The OLE1Object implementation of Unpacker extracts the native and
+// presentation data from a serialized OLE 1.0 object, like the ones
+// found in RTF \objdata.
+//
+// Package objects are extracted as an Ole10Native stream, so the
+// OLE10Native unpacker takes it from there. The native data of other
+// objects is extracted as is, named by parsers.NativeDataName. The
+// presentation data is extracted as one of the Ole1Presentation names.
+type OLE1Object struct{}
+
+// Member names for OLE 1.0 presentation data. Metafiles are
+// extracted without their METAFILEPICT header, so they're
+// plain WMF files.
+const (
+	Ole1PresentationMetafileName = "presentation.wmf"
+	Ole1PresentationBitmapName   = "presentation.bitmap"
+	Ole1PresentationDIBName      = "presentation.dib"
+	Ole1PresentationOtherName    = "presentation.bin"
+)
+
+// Size of the header ahead of the metafile in METAFILEPICT presentation data.
+const metafilePictHeaderSize = 8
+
+func init() {
+	// Files which are a raw OLE 1.0 object from the start match, like
+	// the objects the RTF unpacker extracts from \objdata.
+	Register(Registration{
+		Name: "ole1object",
+		Match: Matcher{
+			Magic: [][]byte{
+				{0x01, 0x05, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00},
+				{0x01, 0x05, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00},
+			},
+		},
+		Unpacker: &OLE1Object{},
+	})
+
+	// Presentation data is only a picture of the object. The OlePres
+	// unpacker uses the same names for OLE 2.0 presentation streams.
+	Register(Registration{
+		Name: "ole1-presentation",
+		Match: Matcher{
+			MIMETypes:   []string{"application/octet-stream"},
+			FileNames:   []string{Ole1PresentationBitmapName, Ole1PresentationDIBName, Ole1PresentationOtherName},
+			ExtractedBy: []string{"ole1object", "olepres"},
+		},
+	})
+}
+
+func (o *OLE1Object) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
+	errs := []error{}
+	obj, err := parsers.NewOle1Object(stream, size)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing OLE 1.0 object", err)
+		return
+	}
+	result := results.Get(inpath)
+	result.Ole1Object = &models.Ole1Object{
+		FormatID:         obj.FormatID,
+		ClassName:        obj.ClassName,
+		TopicName:        obj.TopicName,
+		ItemName:         obj.ItemName,
+		NetworkName:      obj.NetworkName,
+		LinkUpdateOption: obj.LinkUpdateOption,
+		NativeSize:       obj.NativeSize,
+	}
+
+	if obj.FormatID == parsers.Ole1FormatEmbedded {
+		err = extractOle1Native(obj, sink)
+		if errors.Is(err, ErrLimitExceeded) {
+			err = nil
+			result.Expanded = true
+			return
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: extracting the native data", err))
+		}
+	}
+
+	if p := obj.Presentation; p != nil {
+		result.Ole1Object.Presentation = &models.Ole1Presentation{
+			ClassName:           p.ClassName,
+			Width:               p.Width,
+			Height:              p.Height,
+			ClipboardFormat:     p.ClipboardFormat,
+			ClipboardFormatName: p.ClipboardFormatName,
+			Size:                p.Size,
+		}
+		err = extractOle1Presentation(p, sink)
+		if err != nil && !errors.Is(err, ErrLimitExceeded) {
+			errs = append(errs, fmt.Errorf("%w: extracting the presentation data", err))
+		}
+	}
+
+	result.Expanded = true
+	err = errors.Join(errs...)
+	return
+}
+
+// Extract the native data of an embedded object. Package objects
+// get the size prefix that makes them an Ole10Native stream.
+func extractOle1Native(obj *parsers.Ole1Object, sink sinks.Sink) (err error) {
+	var name string
+	var prefix []byte
+	if obj.ClassName == parsers.Ole10KindPackage {
+		name = "Ole10Native"
+		prefix = binary.LittleEndian.AppendUint32(nil, uint32(obj.NativeSize))
+	} else {
+		name = parsers.NativeDataName(obj.ClassName)
+	}
+	err = copyMember(sink, name, prefix, obj.Native())
+	return
+}
+
+// Extract presentation data, named after its format.
+func extractOle1Presentation(p *parsers.Ole1Presentation, sink sinks.Sink) (err error) {
+	data := p.Data()
+	var name string
+	switch p.ClassName {
+	case parsers.Ole1PresentationMetafile:
+		name = Ole1PresentationMetafileName
+		if data.Size() > metafilePictHeaderSize {
+			data = io.NewSectionReader(data, metafilePictHeaderSize, data.Size()-metafilePictHeaderSize)
+		}
+	case parsers.Ole1PresentationBitmap:
+		name = Ole1PresentationBitmapName
+	case parsers.Ole1PresentationDIB:
+		name = Ole1PresentationDIBName
+	default:
+		name = Ole1PresentationOtherName
+	}
+	err = copyMember(sink, name, nil, data)
+	return
+}
+
+// Write a member made up of prefix, followed by everything in r.
+func copyMember(sink sinks.Sink, name string, prefix []byte, r io.Reader) (err error) {
+	w, err := sink.Create(name)
+	if err != nil {
+		return
+	}
+	_, err = w.Write(prefix)
+	if err == nil {
+		_, err = io.Copy(w, r)
+	}
+	if err != nil {
+		w.Close()
+		return
+	}
+	err = w.Close()
+	return
+}
@@ -0,0 +1,82 @@
+package unpackers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// The OlePres implementation of Unpacker extracts the picture held in
+// an OLE 2.0 "\x02OlePres000" presentation stream. It's named like OLE
+// 1.0 presentation data (see OLE1Object), with enhanced metafiles
+// extracted as Ole1PresentationEnhMetafileName.
+type OlePres struct{}
+
+// Member name for enhanced metafile presentation data, which only OLE
+// 2.0 presentation streams hold.
+const Ole1PresentationEnhMetafileName = "presentation.emf"
+
+func init() {
+	// An object can have up to 999 presentation streams, but more
+	// than a handful is never seen.
+	names := []string{}
+	for i := 0; i < 10; i++ {
+		names = append(names, fmt.Sprintf("OlePres%03d", i))
+	}
+	Register(Registration{
+		Name: "olepres",
+		Match: Matcher{
+			MIMETypes:   []string{"application/octet-stream"},
+			FileNames:   names,
+			ParentTypes: cfbMIMETypes,
+		},
+		Unpacker: &OlePres{},
+	})
+}
+
+func (o *OlePres) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
+	p, err := parsers.NewOlePresentation(stream, size)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing presentation stream", err)
+		return
+	}
+	result := results.Get(inpath)
+	result.OlePresentation = &models.OlePresentation{
+		ClipboardFormat:     p.ClipboardFormat,
+		ClipboardFormatName: p.ClipboardFormatName,
+		Aspect:              p.Aspect,
+		Width:               p.Width,
+		Height:              p.Height,
+		Size:                p.Size,
+	}
+
+	var name string
+	switch p.ClipboardFormat {
+	case parsers.ClipboardFormatMetafile:
+		name = Ole1PresentationMetafileName
+	case parsers.ClipboardFormatEnhMetafile:
+		name = Ole1PresentationEnhMetafileName
+	case parsers.ClipboardFormatBitmap:
+		name = Ole1PresentationBitmapName
+	case parsers.ClipboardFormatDIB:
+		name = Ole1PresentationDIBName
+	default:
+		name = Ole1PresentationOtherName
+	}
+	err = copyMember(sink, name, nil, p.Data())
+	if errors.Is(err, ErrLimitExceeded) {
+		err = nil
+		result.Expanded = true
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: extracting the presentation data", err)
+		return
+	}
+	result.Expanded = true
+	return
+}
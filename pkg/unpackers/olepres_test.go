@@ -0,0 +1,41 @@
+package unpackers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/fs"
+	"testing"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// The picture in a presentation stream is extracted, named after its format.
+func TestOlePresUnpackStream(t *testing.T) {
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, []uint32{0xFFFFFFFF, 3, 4})
+	binary.Write(buf, binary.LittleEndian, []uint32{1, 0xFFFFFFFF, 2, 0, 2540, 1270, 8})
+	buf.WriteString("WMF data")
+
+	results := models.NewResults()
+	results.Set("OlePres000", &models.Result{})
+	sink := sinks.NewMemory()
+	err := (&OlePres{}).UnpackStream("OlePres000", bytes.NewReader(buf.Bytes()), int64(buf.Len()), results, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := results.Get("OlePres000")
+	if p := r.OlePresentation; p == nil || p.ClipboardFormat != 3 || p.Width != 2540 || p.Size != 8 {
+		t.Errorf("presentation mismatch: %+v", p)
+	}
+	if !r.Expanded {
+		t.Errorf("expected the stream to be marked expanded")
+	}
+	wmf, err := fs.ReadFile(sink, Ole1PresentationMetafileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(wmf) != "WMF data" {
+		t.Errorf("data mismatch - expected %q got %q", "WMF data", wmf)
+	}
+}
@@ -0,0 +1,194 @@
+package unpackers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+)
+
+// Largest part of a zip package we'll read into memory, to parse.
+const maxPackagePartSize = 16 << 20
+
+// MIME type OfficeType gives OPC packages which aren't Office Open XML
+// documents, like XPS and Visio files. They're unpacked all the same.
+const OPCPackageType = "application/x-opc-package"
+
+// A zip archive holding an Open Packaging Conventions (OPC) package,
+// like an Office Open XML document.
+type zipPackage struct {
+	*zip.Reader
+
+	// Members, by name.
+	files map[string]*zip.File
+}
+
+func newZipPackage(rdr *zip.Reader) (p *zipPackage) {
+	p = &zipPackage{Reader: rdr, files: map[string]*zip.File{}}
+	for _, f := range rdr.File {
+		p.files[f.Name] = f
+	}
+	return
+}
+
+// Read a whole part into memory. Missing parts are nil, without an
+// error.
+func (p *zipPackage) read(name string) (data []byte, err error) {
+	f, ok := p.files[name]
+	if !ok {
+		return
+	}
+	if f.UncompressedSize64 > maxPackagePartSize {
+		err = fmt.Errorf("part %s of %d bytes is larger than the limit of %d", f.Name, f.UncompressedSize64, maxPackagePartSize)
+		return
+	}
+	r, err := f.Open()
+	if err != nil {
+		err = fmt.Errorf("%w: opening part %s", err, f.Name)
+		return
+	}
+	defer r.Close()
+	data, err = io.ReadAll(io.LimitReader(r, maxPackagePartSize))
+	if err != nil {
+		err = fmt.Errorf("%w: reading part %s", err, f.Name)
+	}
+	return
+}
+
+// Read and parse the relationships of a part, or nil if it has none.
+// The package's own relationships are those of "".
+func (p *zipPackage) relationships(part string) (rels []*parsers.Relationship, err error) {
+	data, err := p.read(parsers.RelationshipsPart(part))
+	if data == nil {
+		return
+	}
+	rels, err = parsers.NewRelationships(data)
+	return
+}
+
+// Read and parse [Content_Types].xml, or nil if it's missing.
+func (p *zipPackage) contentTypes() (c *parsers.ContentTypes, err error) {
+	data, err := p.read("[Content_Types].xml")
+	if data == nil {
+		return
+	}
+	c, err = parsers.NewContentTypes(data)
+	return
+}
+
+// mainPart returns the name of the package's main part, which its
+// officeDocument relationship points at, or "" if it has none.
+func (p *zipPackage) mainPart() string {
+	rels, _ := p.relationships("")
+	for _, rel := range rels {
+		if strings.HasSuffix(rel.Type, "/officeDocument") && rel.TargetMode != "External" {
+			return parsers.ResolveTarget("", rel.Target)
+		}
+	}
+	return ""
+}
+
+// Work out the content type of each part, and which relationships point
+// at it, from [Content_Types].xml and the relationships parts. Parts are
+// keyed by name. Part names are compared without regard to case, so a
+// relationship finds its part whatever the case of its target.
+// Relationships parts which can't be parsed are skipped, and reported
+// in err.
+func (p *zipPackage) parts() (parts map[string]*models.PackagePart, err error) {
+	errs := []error{}
+	types, err := p.contentTypes()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	parts = map[string]*models.PackagePart{}
+	folded := map[string]*models.PackagePart{}
+	for _, f := range p.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		part := &models.PackagePart{}
+		if types != nil {
+			part.ContentType = types.PartType(f.Name)
+		}
+		parts[f.Name] = part
+		folded[strings.ToLower(f.Name)] = part
+	}
+	for _, f := range p.File {
+		source, ok := parsers.RelationshipsSource(f.Name)
+		if !ok {
+			continue
+		}
+		rels, err := p.relationships(source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: in %s", err, f.Name))
+			continue
+		}
+		for _, rel := range rels {
+			if rel.TargetMode == "External" {
+				continue
+			}
+			if part := folded[strings.ToLower(parsers.ResolveTarget(source, rel.Target))]; part != nil {
+				part.References = append(part.References, &models.PartReference{Source: source, ID: rel.ID, Type: rel.Type})
+			}
+		}
+	}
+	// The content types and relationships parts describe the package,
+	// rather than being pointed at.
+	for name, part := range parts {
+		_, isRels := parsers.RelationshipsSource(name)
+		part.Orphaned = len(part.References) == 0 && !isRels && name != "[Content_Types].xml"
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// The kinds of Office Open XML document, by the content type of their
+// main part.
+var officeKinds = map[string]struct {
+	kind, application, mimeType string
+	macroEnabled                bool
+}{
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml":   {"docx", "Word", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", false},
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml":   {"dotx", "Word", "application/vnd.openxmlformats-officedocument.wordprocessingml.template", false},
+	"application/vnd.ms-word.document.macroEnabled.main+xml":                             {"docm", "Word", "application/vnd.ms-word.document.macroEnabled.12", true},
+	"application/vnd.ms-word.template.macroEnabledTemplate.main+xml":                     {"dotm", "Word", "application/vnd.ms-word.template.macroEnabled.12", true},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml":         {"xlsx", "Excel", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", false},
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.template.main+xml":      {"xltx", "Excel", "application/vnd.openxmlformats-officedocument.spreadsheetml.template", false},
+	"application/vnd.ms-excel.sheet.macroEnabled.main+xml":                               {"xlsm", "Excel", "application/vnd.ms-excel.sheet.macroEnabled.12", true},
+	"application/vnd.ms-excel.template.macroEnabled.main+xml":                            {"xltm", "Excel", "application/vnd.ms-excel.template.macroEnabled.12", true},
+	"application/vnd.ms-excel.addin.macroEnabled.main+xml":                               {"xlam", "Excel", "application/vnd.ms-excel.addin.macroEnabled.12", true},
+	"application/vnd.ms-excel.sheet.binary.macroEnabled.main":                            {"xlsb", "Excel", "application/vnd.ms-excel.sheet.binary.macroEnabled.12", true},
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation.main+xml": {"pptx", "PowerPoint", "application/vnd.openxmlformats-officedocument.presentationml.presentation", false},
+	"application/vnd.openxmlformats-officedocument.presentationml.slideshow.main+xml":    {"ppsx", "PowerPoint", "application/vnd.openxmlformats-officedocument.presentationml.slideshow", false},
+	"application/vnd.openxmlformats-officedocument.presentationml.template.main+xml":     {"potx", "PowerPoint", "application/vnd.openxmlformats-officedocument.presentationml.template", false},
+	"application/vnd.openxmlformats-officedocument.presentationml.slide+xml":             {"sldx", "PowerPoint", "application/vnd.openxmlformats-officedocument.presentationml.slide", false},
+	"application/vnd.ms-powerpoint.presentation.macroEnabled.main+xml":                   {"pptm", "PowerPoint", "application/vnd.ms-powerpoint.presentation.macroEnabled.12", true},
+	"application/vnd.ms-powerpoint.slideshow.macroEnabled.main+xml":                      {"ppsm", "PowerPoint", "application/vnd.ms-powerpoint.slideshow.macroEnabled.12", true},
+	"application/vnd.ms-powerpoint.template.macroEnabled.main+xml":                       {"potm", "PowerPoint", "application/vnd.ms-powerpoint.template.macroEnabled.12", true},
+	"application/vnd.ms-powerpoint.addin.macroEnabled.main+xml":                          {"ppam", "PowerPoint", "application/vnd.ms-powerpoint.addin.macroEnabled.12", true},
+	"application/vnd.ms-powerpoint.slide.macroEnabled.main+xml":                          {"sldm", "PowerPoint", "application/vnd.ms-powerpoint.slide.macroEnabled.12", true},
+	"application/vnd.openxmlformats-officedocument.theme+xml":                            {"thmx", "Office", "application/vnd.ms-officetheme", false},
+}
+
+// Content types in officeKinds which are also given to parts inside
+// other documents: the slides of a presentation, and the theme of any
+// document.
+var sharedPartTypes = map[string]bool{
+	"application/vnd.openxmlformats-officedocument.presentationml.slide+xml": true,
+	"application/vnd.openxmlformats-officedocument.theme+xml":                true,
+}
+
+// officeMIMETypes returns the MIME types of all kinds of Office Open XML
+// document.
+func officeMIMETypes() (mimeTypes []string) {
+	for _, k := range officeKinds {
+		mimeTypes = append(mimeTypes, k.mimeType)
+	}
+	sort.Strings(mimeTypes)
+	return
+}
@@ -0,0 +1,206 @@
+package unpackers
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// A Matcher describes which files a registered Unpacker applies to.
+//
+// Every non-empty field must match for the Matcher to match. Within a
+// field, matching any one of the listed values is sufficient. A Matcher
+// with no fields set matches nothing.
+type Matcher struct {
+	// MIME types, as reported by mimetype detection.
+	MIMETypes []string
+
+	// Magic byte sequences, compared against the start of the file.
+	Magic [][]byte
+
+	// Exact file names, without any leading directories. Members are
+	// matched by the name they have in their container, so a member the
+	// Namer had to rename ("Ole10Native~2") still matches.
+	FileNames []string
+
+	// File extensions, including the leading dot (".bin").
+	Extensions []string
+
+	// MIME types of the container the file was extracted from.
+	ParentTypes []string
+
+	// Names of the registrations whose Unpacker extracted the file. This
+	// is for members an Unpacker names after what it found in them, so
+	// a file which merely has the same name isn't taken for one.
+	ExtractedBy []string
+}
+
+// A Candidate describes a file the dispatcher is looking for an Unpacker for.
+type Candidate struct {
+	// MIME type of the file.
+	MIMEType string
+
+	// Base name of the file. For members, this is the sanitized base of
+	// their name in the container, before the Namer makes it unique.
+	FileName string
+
+	// The first bytes of the file, used for magic byte matching.
+	Header []byte
+
+	// MIME type of the container the file was extracted from. This
+	// is empty for the top-level input file.
+	ParentType string
+
+	// Name of the registration whose Unpacker extracted the file. This
+	// is empty for the top-level input file.
+	ExtractedBy string
+}
+
+// A Registration ties a Matcher to the Unpacker which handles matching files.
+type Registration struct {
+	// Unique name of the registration, used in logs and results.
+	Name string
+
+	// Files this registration applies to.
+	Match Matcher
+
+	// When several registrations match a file, the highest priority wins.
+	// Ties are broken in favor of the registration whose Matcher sets the
+	// most fields, then in favor of the earliest registration.
+	Priority int
+
+	// The Unpacker used to extract members. A nil Unpacker marks the file
+	// as supported, but not something we expand any further.
+	Unpacker Unpacker
+}
+
+// HeaderSize is the number of leading bytes the dispatcher reads from
+// each file for magic byte matching.
+const HeaderSize = 512
+
+var (
+	registryMu    sync.RWMutex
+	registrations []Registration
+)
+
+// Register makes an Unpacker available to the dispatcher. It is meant to
+// be called from an init function, which allows packages outside of this
+// module to add support for new formats:
+//
+//	func init() {
+//		unpackers.Register(unpackers.Registration{
+//			Name:     "example",
+//			Match:    unpackers.Matcher{MIMETypes: []string{"application/x-example"}},
+//			Unpacker: &Example{},
+//		})
+//	}
+//
+// Register panics if the name is empty or already registered.
+func Register(r Registration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if r.Name == "" {
+		panic("unpackers: Register called with an empty name")
+	}
+	for _, existing := range registrations {
+		if existing.Name == r.Name {
+			panic(fmt.Sprintf("unpackers: Register called twice for %s", r.Name))
+		}
+	}
+	registrations = append(registrations, r)
+}
+
+// Registrations returns a copy of all registrations, in registration order.
+func Registrations() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	regs := make([]Registration, len(registrations))
+	copy(regs, registrations)
+	return regs
+}
+
+// Lookup finds the best registration for the candidate file.
+//
+//	Args:
+//		c (Candidate):	Description of the file we want to unpack.
+//
+//	Returns:
+//		r (Registration):	The best matching registration.
+//		ok (bool):		False if no registration matches the candidate.
+func Lookup(c Candidate) (r Registration, ok bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	bestSpecificity := 0
+	for _, reg := range registrations {
+		specificity := reg.Match.match(c)
+		if specificity == 0 {
+			continue
+		}
+		if ok && (reg.Priority < r.Priority || (reg.Priority == r.Priority && specificity <= bestSpecificity)) {
+			continue
+		}
+		r = reg
+		bestSpecificity = specificity
+		ok = true
+	}
+	return
+}
+
+// match returns the number of fields matched, or 0 if the candidate
+// does not match.
+func (m Matcher) match(c Candidate) (specificity int) {
+	if len(m.MIMETypes) > 0 {
+		if !contains(m.MIMETypes, c.MIMEType) {
+			return 0
+		}
+		specificity++
+	}
+	if len(m.Magic) > 0 {
+		found := false
+		for _, magic := range m.Magic {
+			if bytes.HasPrefix(c.Header, magic) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+		specificity++
+	}
+	if len(m.FileNames) > 0 {
+		if !contains(m.FileNames, c.FileName) {
+			return 0
+		}
+		specificity++
+	}
+	if len(m.Extensions) > 0 {
+		if !contains(m.Extensions, filepath.Ext(c.FileName)) {
+			return 0
+		}
+		specificity++
+	}
+	if len(m.ParentTypes) > 0 {
+		if !contains(m.ParentTypes, c.ParentType) {
+			return 0
+		}
+		specificity++
+	}
+	if len(m.ExtractedBy) > 0 {
+		if !contains(m.ExtractedBy, c.ExtractedBy) {
+			return 0
+		}
+		specificity++
+	}
+	return
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package unpackers
+
+import "testing"
+
+// Make sure the built-in registrations dispatch the way the
+// old hand-maintained switch statement did.
+func TestLookupBuiltins(t *testing.T) {
+	cases := []struct {
+		candidate    Candidate
+		expectedName string
+	}{
+		{Candidate{MIMEType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", FileName: "a.docx"}, "officezip"},
+		{Candidate{MIMEType: "application/vnd.ms-word.document.macroEnabled.12", FileName: "a.docm"}, "officezip"},
+		{Candidate{MIMEType: "application/vnd.ms-officetheme", FileName: "a.thmx"}, "officezip"},
+		{Candidate{MIMEType: "application/x-ole-storage", FileName: "oleObject1.bin"}, "mscfb"},
+		{Candidate{MIMEType: "application/octet-stream", FileName: "Ole10Native"}, "ole10native"},
+		{Candidate{MIMEType: "application/octet-stream", FileName: "CompObj"}, "passive-streams"},
+		{Candidate{MIMEType: "application/octet-stream", FileName: "image1.emf"}, "passive-metafiles"},
+		{Candidate{MIMEType: "image/png", FileName: "image1.png"}, "passive-types"},
+	}
+	for _, c := range cases {
+		reg, ok := Lookup(c.candidate)
+		if !ok {
+			t.Errorf("no registration found for %s (%s)", c.candidate.FileName, c.candidate.MIMEType)
+			continue
+		}
+		if reg.Name != c.expectedName {
+			t.Errorf("registration mismatch for %s - expected %s got %s", c.candidate.FileName, c.expectedName, reg.Name)
+		}
+	}
+	if _, ok := Lookup(Candidate{MIMEType: "application/octet-stream", FileName: "mystery"}); ok {
+		t.Errorf("expected no registration for an unrecognized octet-stream")
+	}
+}
+
+// Priority wins first, then the number of fields the Matcher sets.
+func TestMatcherPrecedence(t *testing.T) {
+	regs := registrations
+	defer func() { registrations = regs }()
+	registrations = nil
+
+	Register(Registration{Name: "by-mime", Match: Matcher{MIMETypes: []string{"application/zip"}}})
+	Register(Registration{Name: "by-mime-and-parent", Match: Matcher{MIMETypes: []string{"application/zip"}, ParentTypes: []string{"application/x-ole-storage"}}})
+	Register(Registration{Name: "by-magic", Match: Matcher{Magic: [][]byte{[]byte("PK\x03\x04")}}, Priority: 10})
+
+	c := Candidate{MIMEType: "application/zip", ParentType: "application/x-ole-storage"}
+	if reg, _ := Lookup(c); reg.Name != "by-mime-and-parent" {
+		t.Errorf("expected the more specific matcher to win, got %s", reg.Name)
+	}
+	c.Header = []byte("PK\x03\x04\x14\x00")
+	if reg, _ := Lookup(c); reg.Name != "by-magic" {
+		t.Errorf("expected the higher priority matcher to win, got %s", reg.Name)
+	}
+}
+
+// Workbook streams are only parsed inside a compound file.
+func TestLookupWorkbook(t *testing.T) {
+	c := Candidate{MIMEType: "application/octet-stream", FileName: "Workbook", ParentType: "application/vnd.ms-excel"}
+	if reg, ok := Lookup(c); !ok || reg.Name != "workbook" {
+		t.Errorf("expected the Workbook stream of an .xls file to be parsed, got %s", reg.Name)
+	}
+	c.ParentType = "application/zip"
+	if reg, ok := Lookup(c); ok {
+		t.Errorf("expected no registration for a zip member named Workbook, got %s", reg.Name)
+	}
+}
+
+// Members named after what an unpacker found in them only match when
+// that unpacker extracted them.
+func TestLookupExtractedBy(t *testing.T) {
+	c := Candidate{MIMEType: "application/octet-stream", FileName: "native.bin", ExtractedBy: "ole10native"}
+	if reg, ok := Lookup(c); !ok || reg.Name != "ole1-native" {
+		t.Errorf("expected native data from an Ole10Native stream to match ole1-native, got %s", reg.Name)
+	}
+	c.ExtractedBy = "officezip"
+	if reg, ok := Lookup(c); ok {
+		t.Errorf("expected no registration for a zip member named native.bin, got %s", reg.Name)
+	}
+	c = Candidate{MIMEType: "application/octet-stream", FileName: Ole1PresentationDIBName, ExtractedBy: "olepres"}
+	if reg, ok := Lookup(c); !ok || reg.Name != "ole1-presentation" {
+		t.Errorf("expected presentation data from an OlePres stream to match ole1-presentation, got %s", reg.Name)
+	}
+	c.ExtractedBy = ""
+	if reg, ok := Lookup(c); ok {
+		t.Errorf("expected no registration for a top-level file named %s, got %s", c.FileName, reg.Name)
+	}
+}
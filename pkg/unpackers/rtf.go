@@ -0,0 +1,56 @@
+package unpackers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// The RTF implementation of Unpacker extracts the objects embedded in an
+// RTF document. The data of each \objdata destination is extracted as
+// RTFObjectName. It holds a serialized OLE 1.0 object, so the
+// OLE1Object unpacker takes it from there.
+type RTF struct{}
+
+// Member name for the data of an object embedded in an RTF document.
+const RTFObjectName = "objdata.bin"
+
+func init() {
+	Register(Registration{
+		Name:     "rtf",
+		Match:    Matcher{MIMETypes: []string{"text/rtf"}},
+		Unpacker: &RTF{},
+	})
+}
+
+func (u *RTF) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
+	result := results.Get(inpath)
+	r := parsers.NewRTFReader(io.NewSectionReader(stream, 0, size))
+	for {
+		var obj io.Reader
+		obj, err = r.Next()
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: finding the next object", err)
+			return
+		}
+		err = copyMember(sink, RTFObjectName, nil, obj)
+		if errors.Is(err, ErrLimitExceeded) {
+			err = nil
+			result.Expanded = true
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: extracting object data", err)
+			return
+		}
+		result.Expanded = true
+	}
+}
@@ -0,0 +1,65 @@
+package unpackers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// A sink which numbers the members, so several with the same name can
+// be told apart, as the dispatcher's Namer does.
+type numberingSink struct {
+	*sinks.Memory
+	created int
+}
+
+func (s *numberingSink) Create(name string) (io.WriteCloser, error) {
+	s.created++
+	return s.Memory.Create(fmt.Sprintf("%d-%s", s.created, name))
+}
+
+// Each object in the document is extracted, as it was serialized.
+func TestRTFUnpackStream(t *testing.T) {
+	data, err := os.ReadFile(path.Join(pathToSampleDataDir, "rtf.rtf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &numberingSink{Memory: sinks.NewMemory()}
+	r, err := unpackTo(t, &RTF{}, data, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Expanded {
+		t.Errorf("expected the document to be marked expanded")
+	}
+	if sink.created != 2 {
+		t.Fatalf("member count mismatch - expected 2 got %d", sink.created)
+	}
+	for _, name := range []string{"1-" + RTFObjectName, "2-" + RTFObjectName} {
+		obj, err := fs.ReadFile(sink, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(obj) != 21200 || !bytes.HasPrefix(obj, []byte{0x01, 0x05, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}) {
+			t.Errorf("%s: expected a 21200 byte OLE 1.0 embedded object, got %d bytes", name, len(obj))
+		}
+	}
+}
+
+// A document without objects has no members, and isn't expanded.
+func TestRTFUnpackStreamEmpty(t *testing.T) {
+	r, sink, err := unpack(t, &RTF{}, []byte(`{\rtf1\ansi Nothing to see here.\par}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := fs.ReadDir(sink, ".")
+	if r.Expanded || len(entries) != 0 {
+		t.Errorf("expected no members, got %d", len(entries))
+	}
+}
@@ -0,0 +1,347 @@
+package unpackers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/richardlehane/msoleps"
+	"github.com/richardlehane/msoleps/types"
+)
+
+// Properties which are part of the property set structure, rather
+// than metadata.
+var structuralProperties = map[string]bool{
+	"":           true,
+	"Dictionary": true,
+	"CodePage":   true,
+	"Locale":     true,
+	"Behaviour":  true,
+}
+
+// Parse a SummaryInformation property set into s.Summary.
+func parseSummaryInformation(data []byte, s *models.Storage) (err error) {
+	props, _, err := readPropertySets(data)
+	if err != nil {
+		return
+	}
+	summary := storageSummary(s)
+	for _, p := range props {
+		switch p.Name {
+		case "CodePage":
+			setCodePage(summary, p.T)
+		case "Title":
+			summary.Title = p.String()
+		case "Subject":
+			summary.Subject = p.String()
+		case "Author":
+			summary.Author = p.String()
+		case "LastAuthor":
+			summary.LastSavedBy = p.String()
+		case "Keywords":
+			summary.Keywords = p.String()
+		case "Comments":
+			summary.Comments = p.String()
+		case "Template":
+			summary.Template = p.String()
+		case "RevNumber":
+			summary.Revision = p.String()
+		case "AppName":
+			summary.Application = p.String()
+		case "CreateTime":
+			summary.Created = fileTime(p.T)
+		case "LastSaveTime":
+			summary.LastSaved = fileTime(p.T)
+		case "LastPrinted":
+			summary.LastPrinted = fileTime(p.T)
+		}
+	}
+	return
+}
+
+// Parse a DocumentSummaryInformation property set into s.Summary.
+// Its second section, if present, holds the user-defined properties.
+func parseDocumentSummaryInformation(data []byte, s *models.Storage) (err error) {
+	props, first, err := readPropertySets(data)
+	if err != nil {
+		return
+	}
+	summary := storageSummary(s)
+	for i, p := range props {
+		if i >= first {
+			if structuralProperties[p.Name] {
+				continue
+			}
+			if summary.Custom == nil {
+				summary.Custom = map[string]string{}
+			}
+			summary.Custom[p.Name] = propertyValue(p.T)
+			continue
+		}
+		switch p.Name {
+		case "CodePage":
+			setCodePage(summary, p.T)
+		case "Company":
+			summary.Company = p.String()
+		case "Manager":
+			summary.Manager = p.String()
+		case "Category":
+			summary.Category = p.String()
+		}
+	}
+	return
+}
+
+// Read a property set stream. msoleps returns the properties of both
+// sections in one list, so first is the number from the first section.
+// msoleps trusts the counts, offsets and lengths in the stream, so it
+// is only given streams which checkPropertySets has gone over first.
+// It also reads values of types it doesn't know (like the VT_BLOB of
+// _PID_HLINKS) as a zero VT_I1, so those properties are left out.
+func readPropertySets(data []byte) (props []*msoleps.Property, first int, err error) {
+	err = checkPropertySets(data)
+	if err != nil {
+		return
+	}
+	r, err := msoleps.NewFrom(bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+
+	// msoleps keeps the properties in the order of the sections'
+	// property tables.
+	sections := []uint32{binary.LittleEndian.Uint32(data[44:])}
+	if binary.LittleEndian.Uint32(data[24:]) == 2 {
+		sections = append(sections, binary.LittleEndian.Uint32(data[64:]))
+	}
+	i := 0
+	for s, offset := range sections {
+		numProperties := binary.LittleEndian.Uint32(data[offset+4:])
+		for j := uint32(0); j < numProperties; j++ {
+			entry := data[offset+8+j*8:]
+			id, valueOffset := binary.LittleEndian.Uint32(entry), binary.LittleEndian.Uint32(entry[4:])
+			vt := types.TypeID(binary.LittleEndian.Uint16(data[offset+valueOffset:]))
+			if _, known := types.MakeTypes[vt]; id == 0 || known {
+				props = append(props, r.Property[i])
+			}
+			i++
+		}
+		if s == 0 {
+			first = len(props)
+		}
+	}
+	return
+}
+
+// Type IDs msoleps gives special treatment to.
+const (
+	vtBSTR    = 0x08
+	vtVariant = 0x0C
+	vtLPSTR   = 0x1E
+	vtLPWSTR  = 0x1F
+)
+
+// Code page of strings stored as UTF-16.
+const codePageUnicode = 1200
+
+// Check that msoleps can read a property set stream without going out of
+// bounds or allocating more than the stream's size. Each section has to
+// lie within the stream, and its property table, values and dictionary
+// within the section. msoleps also slices strings up to their NUL, so
+// strings without one are rejected, as are the vectors and variants it
+// reads without checking their counts ([MS-OLEPS] only allows them
+// inside a vector, which msoleps doesn't recognize anyway).
+func checkPropertySets(data []byte) (err error) {
+	if len(data) < 48 || binary.LittleEndian.Uint16(data) != 0xFFFE {
+		err = fmt.Errorf("%w: bad header", msoleps.ErrFormat)
+		return
+	}
+	offsets := []uint32{binary.LittleEndian.Uint32(data[44:])}
+	switch binary.LittleEndian.Uint32(data[24:]) {
+	case 1:
+	case 2:
+		if len(data) < 68 {
+			err = fmt.Errorf("%w: bad header", msoleps.ErrFormat)
+			return
+		}
+		offsets = append(offsets, binary.LittleEndian.Uint32(data[64:]))
+	default:
+		err = fmt.Errorf("%w: bad number of sections", msoleps.ErrFormat)
+		return
+	}
+	for i, offset := range offsets {
+		err = checkPropertySection(data, int64(offset))
+		if err != nil {
+			err = fmt.Errorf("%w: section %d", err, i)
+			return
+		}
+	}
+	return
+}
+
+// Check one section of a property set stream, which starts at offset.
+func checkPropertySection(data []byte, offset int64) (err error) {
+	if offset+8 > int64(len(data)) {
+		err = fmt.Errorf("%w: offset %d out of range", msoleps.ErrFormat, offset)
+		return
+	}
+	size := int64(binary.LittleEndian.Uint32(data[offset:]))
+	numProperties := int64(binary.LittleEndian.Uint32(data[offset+4:]))
+	if size > int64(len(data))-offset {
+		err = fmt.Errorf("%w: size %d out of range", msoleps.ErrFormat, size)
+		return
+	}
+	if 8+numProperties*8 > size {
+		err = fmt.Errorf("%w: %d properties don't fit in %d bytes", msoleps.ErrFormat, numProperties, size)
+		return
+	}
+	section := data[offset : offset+size]
+
+	// The code page applies to every string in the section, wherever
+	// it is in the table.
+	codePage := uint16(0)
+	for i := int64(0); i < numProperties; i++ {
+		entry := section[8+i*8:]
+		id, valueOffset := binary.LittleEndian.Uint32(entry), int64(binary.LittleEndian.Uint32(entry[4:]))
+		if valueOffset+4 > size {
+			err = fmt.Errorf("%w: property %d offset %d out of range", msoleps.ErrFormat, id, valueOffset)
+			return
+		}
+		if id == 1 {
+			if valueOffset+6 > size {
+				err = fmt.Errorf("%w: code page out of range", msoleps.ErrFormat)
+				return
+			}
+			codePage = binary.LittleEndian.Uint16(section[valueOffset+4:])
+		}
+	}
+	for i := int64(0); i < numProperties; i++ {
+		entry := section[8+i*8:]
+		id, valueOffset := binary.LittleEndian.Uint32(entry), binary.LittleEndian.Uint32(entry[4:])
+		if id == 0 {
+			err = checkPropertyDictionary(section[valueOffset:], codePage)
+		} else {
+			err = checkPropertyValue(section[valueOffset:], codePage)
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: property %d", err, id)
+			return
+		}
+	}
+	return
+}
+
+// Check a property value, laid out as msoleps reads it: a uint16 type
+// ID, a uint16 it takes for the vector or array flag, then the value.
+// Values too short for their type are fine, msoleps leaves them empty.
+func checkPropertyValue(b []byte, codePage uint16) (err error) {
+	vt := binary.LittleEndian.Uint16(b)
+	if binary.LittleEndian.Uint16(b[2:]) != 0 || vt == vtVariant {
+		err = fmt.Errorf("%w: unsupported value type", msoleps.ErrFormat)
+		return
+	}
+	switch vt {
+	case vtBSTR, vtLPSTR:
+		if codePage == codePageUnicode {
+			err = checkPropertyString(b[4:], 1, 2)
+		} else {
+			err = checkPropertyString(b[4:], 1, 1)
+		}
+	case vtLPWSTR:
+		err = checkPropertyString(b[4:], 2, 2)
+	}
+	return
+}
+
+// Check a length-prefixed string of unitSize-byte units (or bytes, for
+// a unitSize of 1) has a NUL of nulSize bytes.
+func checkPropertyString(b []byte, unitSize, nulSize int64) (err error) {
+	if len(b) < 4 {
+		return
+	}
+	length := int64(binary.LittleEndian.Uint32(b)) * unitSize
+	if length == 0 || length > int64(len(b))-4 {
+		return
+	}
+	chars := b[4 : 4+length]
+	for i := int64(0); i+nulSize <= length; i += nulSize {
+		if bytes.Count(chars[i:i+nulSize], []byte{0}) == int(nulSize) {
+			return
+		}
+	}
+	err = fmt.Errorf("%w: string isn't terminated", msoleps.ErrFormat)
+	return
+}
+
+// Check a dictionary of property names. msoleps reads the names
+// as it does VT_LPWSTR values when the code page is Unicode, and as
+// VT_LPSTR values otherwise, but steps over them as they're laid out
+// in the dictionary.
+func checkPropertyDictionary(b []byte, codePage uint16) (err error) {
+	numEntries := int64(binary.LittleEndian.Uint32(b))
+	e := int64(4)
+	for i := int64(0); i < numEntries; i++ {
+		if int64(len(b))-e < 8 {
+			err = fmt.Errorf("%w: dictionary entry %d out of range", msoleps.ErrFormat, i)
+			return
+		}
+		length := int64(binary.LittleEndian.Uint32(b[e+4:]))
+		if codePage == codePageUnicode {
+			err = checkPropertyString(b[e+4:], 2, 2)
+			e += 8 + length*2 + 2*(length%2)
+		} else {
+			err = checkPropertyString(b[e+4:], 1, 1)
+			e += 8 + length
+		}
+		if err != nil {
+			return
+		}
+		if e > int64(len(b)) {
+			err = fmt.Errorf("%w: dictionary entry %d out of range", msoleps.ErrFormat, i)
+			return
+		}
+	}
+	return
+}
+
+func storageSummary(s *models.Storage) *models.Summary {
+	if s.Summary == nil {
+		s.Summary = &models.Summary{}
+	}
+	return s.Summary
+}
+
+// The code page is a VT_I2, but values like 65001 (UTF-8) only
+// make sense unsigned.
+func setCodePage(summary *models.Summary, t types.Type) {
+	if summary.CodePage != 0 {
+		return
+	}
+	switch v := t.(type) {
+	case types.I2:
+		summary.CodePage = uint16(v)
+	case types.UI2:
+		summary.CodePage = uint16(v)
+	}
+}
+
+// Convert a VT_FILETIME property. Unset times are zero, and left out.
+func fileTime(t types.Type) *time.Time {
+	ft, ok := t.(types.FileTime)
+	if !ok || (ft.Low == 0 && ft.High == 0) {
+		return nil
+	}
+	tm := ft.Time().UTC()
+	return &tm
+}
+
+// Format a property's value. Times are in RFC 3339 form, rather
+// than in the local time zone.
+func propertyValue(t types.Type) string {
+	if tm := fileTime(t); tm != nil {
+		return tm.Format(time.RFC3339)
+	}
+	return t.String()
+}
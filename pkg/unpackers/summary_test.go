@@ -0,0 +1,279 @@
+package unpackers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/richardlehane/msoleps"
+)
+
+// Offsets past the end of a property set are errors, not panics.
+func TestParseSummaryInformationTruncated(t *testing.T) {
+	data := make([]byte, 48)
+	binary.LittleEndian.PutUint16(data, 0xFFFE)
+	binary.LittleEndian.PutUint32(data[24:], 1)
+	binary.LittleEndian.PutUint32(data[44:], 0x1000)
+	s := &models.Storage{}
+	err := parseSummaryInformation(data, s)
+	if !errors.Is(err, msoleps.ErrFormat) {
+		t.Errorf("expected a format error, got %v", err)
+	}
+	if s.Summary != nil {
+		t.Errorf("expected no summary, got %+v", s.Summary)
+	}
+}
+
+// Build the 48-byte header of a property set stream with one section
+// at offset 48.
+func propertySetHeader() []byte {
+	data := make([]byte, 48)
+	binary.LittleEndian.PutUint16(data, 0xFFFE)
+	binary.LittleEndian.PutUint32(data[24:], 1)
+	binary.LittleEndian.PutUint32(data[44:], 48)
+	return data
+}
+
+// A section claiming more properties than it has room for is rejected
+// before msoleps allocates a table for them all.
+func TestParseSummaryInformationPropertyCount(t *testing.T) {
+	data := append(propertySetHeader(), make([]byte, 16)...)
+	binary.LittleEndian.PutUint32(data[48:], 16)
+	binary.LittleEndian.PutUint32(data[52:], 0xFFFFFFFF)
+	s := &models.Storage{}
+	err := parseSummaryInformation(data, s)
+	if !errors.Is(err, msoleps.ErrFormat) {
+		t.Errorf("expected a format error, got %v", err)
+	}
+}
+
+// Values msoleps would read past the end of, or panic on, are rejected.
+func TestParseSummaryInformationValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []byte
+	}{
+		{"offset out of range", nil},
+		{"unterminated string", []byte{0x1E, 0, 0, 0, 4, 0, 0, 0, 'A', 'B', 'C', 'D'}},
+		{"unterminated unicode string", []byte{0x1F, 0, 0, 0, 2, 0, 0, 0, 'A', 0, 'B', 0}},
+		{"vector", []byte{0x03, 0, 1, 0, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{"variant", []byte{0x0C, 0, 0, 0, 0x1E, 0, 0, 0, 1, 0, 0, 0, 'A'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(propertySetHeader(), make([]byte, 16)...)
+			binary.LittleEndian.PutUint32(data[48:], uint32(16+len(tt.value)))
+			binary.LittleEndian.PutUint32(data[52:], 1)
+			binary.LittleEndian.PutUint32(data[56:], 2)
+			binary.LittleEndian.PutUint32(data[60:], 16)
+			if tt.value == nil {
+				binary.LittleEndian.PutUint32(data[60:], 14)
+			}
+			data = append(data, tt.value...)
+			err := parseSummaryInformation(data, &models.Storage{})
+			if !errors.Is(err, msoleps.ErrFormat) {
+				t.Errorf("expected a format error, got %v", err)
+			}
+		})
+	}
+}
+
+// Dictionary entries running past the end of their section are rejected.
+func TestParseDocumentSummaryInformationDictionary(t *testing.T) {
+	data := append(propertySetHeader(), make([]byte, 28)...)
+	binary.LittleEndian.PutUint32(data[48:], 28)
+	binary.LittleEndian.PutUint32(data[52:], 1)
+	binary.LittleEndian.PutUint32(data[60:], 16)
+	binary.LittleEndian.PutUint32(data[64:], 1)
+	binary.LittleEndian.PutUint32(data[68:], 2)
+	binary.LittleEndian.PutUint32(data[72:], 0x7FFFFFFF)
+	err := parseDocumentSummaryInformation(data, &models.Storage{})
+	if !errors.Is(err, msoleps.ErrFormat) {
+		t.Errorf("expected a format error, got %v", err)
+	}
+}
+
+// A property to put in a property set stream: a VT_LPSTR for strings,
+// a VT_I2 for int16s, a VT_FILETIME for times and a VT_BLOB for bytes.
+type property struct {
+	id    uint32
+	value any
+}
+
+// Build a property set stream, with a section per FMTID. Each section
+// gets a code page of 1252, and a dictionary if names are given.
+func propertySetStream(fmtids []string, sections [][]property, names map[uint32]string) []byte {
+	value := func(v any) []byte {
+		buf := &bytes.Buffer{}
+		switch v := v.(type) {
+		case string:
+			binary.Write(buf, binary.LittleEndian, []uint32{0x1E, uint32(len(v) + 1)})
+			buf.WriteString(v + "\x00")
+		case int16:
+			binary.Write(buf, binary.LittleEndian, uint32(0x02))
+			binary.Write(buf, binary.LittleEndian, []int16{v, 0})
+		case []byte:
+			binary.Write(buf, binary.LittleEndian, []uint32{0x41, uint32(len(v))})
+			buf.Write(v)
+		case time.Time:
+			binary.Write(buf, binary.LittleEndian, uint32(0x40))
+			binary.Write(buf, binary.LittleEndian, uint64(v.Unix()+11644473600)*10000000)
+		}
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+		return buf.Bytes()
+	}
+	stream := &bytes.Buffer{}
+	binary.Write(stream, binary.LittleEndian, []uint16{0xFFFE, 0})
+	stream.Write(make([]byte, 4+16))
+	binary.Write(stream, binary.LittleEndian, uint32(len(fmtids)))
+	offset := 28 + 20*len(fmtids)
+	bodies := [][]byte{}
+	for i, fmtid := range fmtids {
+		props := append([]property{{1, int16(1252)}}, sections[i]...)
+		values := [][]byte{}
+		for _, p := range props {
+			values = append(values, value(p.value))
+		}
+		if i > 0 && names != nil {
+			dict := &bytes.Buffer{}
+			binary.Write(dict, binary.LittleEndian, uint32(len(names)))
+			for id, name := range names {
+				binary.Write(dict, binary.LittleEndian, []uint32{id, uint32(len(name) + 1)})
+				dict.WriteString(name + "\x00")
+			}
+			for dict.Len()%4 != 0 {
+				dict.WriteByte(0)
+			}
+			props = append(props, property{id: 0})
+			values = append(values, dict.Bytes())
+		}
+		body := &bytes.Buffer{}
+		at := 8 + 8*len(props)
+		size := at
+		for _, v := range values {
+			size += len(v)
+		}
+		binary.Write(body, binary.LittleEndian, []uint32{uint32(size), uint32(len(props))})
+		for j, p := range props {
+			binary.Write(body, binary.LittleEndian, []uint32{p.id, uint32(at)})
+			at += len(values[j])
+		}
+		for _, v := range values {
+			body.Write(v)
+		}
+		clsid := cfbtest.CLSID(fmtid)
+		stream.Write(clsid[:])
+		binary.Write(stream, binary.LittleEndian, uint32(offset))
+		offset += body.Len()
+		bodies = append(bodies, body.Bytes())
+	}
+	for _, body := range bodies {
+		stream.Write(body)
+	}
+	return stream.Bytes()
+}
+
+// The summary property sets of real documents are read.
+func TestMSCFBSummaryInformation(t *testing.T) {
+	novpapplan, _ := unpackSample(t, &MSCFB{}, "novpapplan.doc")
+	s := novpapplan.Storage.Summary
+	if s == nil {
+		t.Fatalf("expected a summary on novpapplan.doc")
+	}
+	if s.CodePage != 1252 || s.Author != "Richard Lehane" || s.LastSavedBy != "Richard Lehane" || s.Template != "Normal.dot" || s.Revision != "23" || s.Application != "Microsoft Word 9.0" {
+		t.Errorf("summary mismatch: %+v", s)
+	}
+	created := time.Date(2001, 11, 5, 3, 8, 0, 0, time.UTC)
+	if s.Created == nil || !s.Created.Equal(created) {
+		t.Errorf("creation time mismatch - expected %s got %v", created, s.Created)
+	}
+	if s.Company != "Richcorp" {
+		t.Errorf("company mismatch - expected %s got %s", "Richcorp", s.Company)
+	}
+
+	xls, _ := unpackSample(t, &MSCFB{}, "test.xls")
+	s = xls.Storage.Summary
+	if s == nil || s.CodePage != 65001 || s.Author != "Lehane, Richard" || s.LastSavedBy != "Richard Lehane" {
+		t.Errorf("summary mismatch on test.xls: %+v", s)
+	}
+
+	// The user-defined properties of test.ppt are VT_BLOBs, which
+	// msoleps can't read.
+	ppt, _ := unpackSample(t, &MSCFB{}, "test.ppt")
+	s = ppt.Storage.Summary
+	if s == nil || s.Author != "Richard Lehane" {
+		t.Errorf("summary mismatch on test.ppt: %+v", s)
+	} else if len(s.Custom) != 0 {
+		t.Errorf("expected no custom properties, got %v", s.Custom)
+	}
+}
+
+// Each property of the summary property sets lands in its field, and
+// the user-defined ones in Custom.
+func TestParseSummaryInformation(t *testing.T) {
+	created := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	summary := propertySetStream([]string{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}"}, [][]property{{
+		{2, "Invoice"},
+		{4, "Alice"},
+		{7, "Normal.dotm"},
+		{8, "Bob"},
+		{12, created},
+	}}, nil)
+	docSummary := propertySetStream([]string{
+		"{D5CDD502-2E9C-101B-9397-08002B2CF9AE}",
+		"{D5CDD505-2E9C-101B-9397-08002B2CF9AE}",
+	}, [][]property{
+		{{15, "Example Corp"}},
+		{{2, "Reviewed"}, {3, []byte{1, 2, 3, 4}}},
+	}, map[uint32]string{2: "Status", 3: "Links"})
+
+	storage := &models.Storage{}
+	if err := parseSummaryInformation(summary, storage); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseDocumentSummaryInformation(docSummary, storage); err != nil {
+		t.Fatal(err)
+	}
+	s := storage.Summary
+	if s.CodePage != 1252 || s.Title != "Invoice" || s.Author != "Alice" || s.LastSavedBy != "Bob" || s.Template != "Normal.dotm" {
+		t.Errorf("summary mismatch: %+v", s)
+	}
+	if s.Created == nil || !s.Created.Equal(created) || s.LastSaved != nil {
+		t.Errorf("expected only a creation time of %s, got %v, %v", created, s.Created, s.LastSaved)
+	}
+	if s.Company != "Example Corp" {
+		t.Errorf("company mismatch - expected %s got %s", "Example Corp", s.Company)
+	}
+	if s.Custom["Status"] != "Reviewed" || len(s.Custom) != 1 {
+		t.Errorf("expected only the Status property, got %v", s.Custom)
+	}
+}
+
+// A 64-byte SummaryInformation stream claiming 0xFFFFFFFF properties used
+// to have msoleps allocate a table for them all, which killed the process.
+// The stream is still extracted.
+func TestMSCFBSummaryInformationPropertyCount(t *testing.T) {
+	summary := make([]byte, 64)
+	binary.LittleEndian.PutUint16(summary, 0xFFFE)
+	binary.LittleEndian.PutUint32(summary[24:], 1)
+	binary.LittleEndian.PutUint32(summary[44:], 48)
+	binary.LittleEndian.PutUint32(summary[48:], 16)
+	binary.LittleEndian.PutUint32(summary[52:], 0xFFFFFFFF)
+	cfb := cfbtest.New()
+	cfb.Stream("\x05SummaryInformation", summary)
+
+	_, sink, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "SummaryInformation") {
+		t.Errorf("expected an error parsing SummaryInformation, got %v", err)
+	}
+	if _, err = sink.Open("SummaryInformation"); err != nil {
+		t.Errorf("expected the stream to be extracted anyway: %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+package unpackers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+	"github.com/richardlehane/mscfb"
+)
+
+// MIME types the MSCFB unpacker gives the streams of a VBA project.
+// Module streams hold p-code and compressed source, which is extracted
+// separately. The rest (dir, _VBA_PROJECT, __SRP_0 and so on) describe
+// the project.
+const (
+	VBAModuleType = "application/x-vba-module"
+	VBAStreamType = "application/x-vba-stream"
+)
+
+// Largest module stream we'll read into memory to extract the source.
+const maxVBAModuleSize = 16 << 20
+
+func init() {
+	Register(Registration{
+		Name:  "vba-streams",
+		Match: Matcher{MIMETypes: []string{VBAModuleType, VBAStreamType}},
+	})
+
+	// Decompressed module source and disassembled p-code, written next
+	// to the module streams.
+	Register(Registration{
+		Name:  "vba-source",
+		Match: Matcher{Extensions: []string{".bas", ".cls", ".pcode"}},
+	})
+}
+
+// A VBA project in an MS-CFB file: a storage named "VBA" with a dir
+// stream. This is the root of vbaProject.bin in OOXML files, and sits
+// under Macros in .doc files, or _VBA_PROJECT_CUR in .xls files.
+type vbaProject struct {
+	// Path of the VBA storage.
+	storage string
+
+	dir   *parsers.VBADir
+	model *models.VBAProject
+
+	// Modules, by stream name.
+	modules map[string]*parsers.VBAModule
+
+	// Streams in the VBA storage, by name.
+	streams map[string]*mscfb.File
+}
+
+// Find and parse the dir stream of every VBA project in the file, by
+// storage path. This happens ahead of extraction, so the project's
+// streams can be typed as they're written.
+func findVBAProjects(rdr *mscfb.Reader) (projects map[string]*vbaProject, errs []error) {
+	projects = map[string]*vbaProject{}
+	streams := map[string]map[string]*mscfb.File{}
+	for _, f := range rdr.File {
+		if !f.FileInfo().IsDir() {
+			storage := path.Join(f.Path...)
+			if streams[storage] == nil {
+				streams[storage] = map[string]*mscfb.File{}
+			}
+			streams[storage][f.Name] = f
+		}
+	}
+	for _, f := range rdr.File {
+		if f.Name != "dir" || len(f.Path) == 0 || f.Path[len(f.Path)-1] != "VBA" || f.FileInfo().IsDir() {
+			continue
+		}
+		storage := path.Join(f.Path...)
+		data, err := readStream(f)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: reading %s/dir", err, storage))
+			continue
+		}
+		dir, err := parsers.NewVBADir(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: parsing %s/dir", err, storage))
+		}
+		p := &vbaProject{
+			storage: storage,
+			dir:     dir,
+			model:   &models.VBAProject{Name: dir.Name, CodePage: dir.CodePage, SysKind: dir.SysKind},
+			modules: map[string]*parsers.VBAModule{},
+			streams: streams[storage],
+		}
+		for _, m := range dir.Modules {
+			p.modules[m.StreamName] = m
+		}
+		projects[storage] = p
+	}
+	return
+}
+
+// streamType returns the MIME type of a stream in a VBA project's storage.
+func (p *vbaProject) streamType(name string) string {
+	if _, ok := p.modules[name]; ok {
+		return VBAModuleType
+	}
+	return VBAStreamType
+}
+
+// Decompress the source code of each module into a .bas or .cls file
+// in the VBA storage, disassemble its p-code into a .pcode file, and
+// record the modules on the project.
+func (p *vbaProject) extract(sink sinks.Sink) (err error) {
+	errs := []error{}
+	project, err := p.projectStream()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if project != nil {
+		p.model.Version = project.Version
+	}
+	for _, m := range p.dir.Modules {
+		module := &models.VBAModule{
+			Name:       m.Name,
+			StreamName: m.StreamName,
+			Type:       m.Type,
+			TextOffset: m.TextOffset,
+			ReadOnly:   m.ReadOnly,
+			Private:    m.Private,
+		}
+		p.model.Modules = append(p.model.Modules, module)
+
+		streamPath := path.Join(p.storage, m.StreamName)
+		f, ok := p.streams[m.StreamName]
+		if !ok {
+			errs = append(errs, fmt.Errorf("module stream %s is missing", streamPath))
+			continue
+		}
+		var stream, source []byte
+		stream, err = readStream(f)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: reading %s", err, streamPath))
+			continue
+		}
+		source, err = m.Source(stream)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: decompressing the source of %s", err, streamPath))
+			continue
+		}
+		name := path.Join(p.storage, m.Name+m.Extension())
+		if limiter, ok := sink.(Limiter); ok {
+			err = limiter.CheckRatio(name, int64(len(stream))-int64(m.TextOffset), int64(len(source)))
+			if err != nil {
+				continue
+			}
+		}
+		err = copyMember(sink, name, nil, bytes.NewReader(source))
+		if errors.Is(err, ErrLimitExceeded) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: writing %s", err, name))
+			continue
+		}
+		module.Source = name
+
+		if !p.hasPCode() {
+			continue
+		}
+		var lines []*parsers.PCodeLine
+		lines, err = parsers.DisassemblePCode(stream[:m.TextOffset], project.Version, project.Identifiers, p.dir.SysKind == parsers.VBASysKindWin64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: disassembling the p-code of %s", err, streamPath))
+			continue
+		}
+		module.PCodeLines = len(lines)
+		module.StompingReason = stompingReason(lines, source)
+		module.Stomped = module.StompingReason != ""
+		name = path.Join(p.storage, m.Name+".pcode")
+		err = copyMember(sink, name, nil, bytes.NewReader(pcodeListing(lines)))
+		if errors.Is(err, ErrLimitExceeded) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: writing %s", err, name))
+			continue
+		}
+		module.PCode = name
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// Parse the _VBA_PROJECT stream, for the VBA version and the project's
+// identifiers. Projects without one get nil.
+func (p *vbaProject) projectStream() (project *parsers.VBAProjectStream, err error) {
+	f, ok := p.streams["_VBA_PROJECT"]
+	if !ok {
+		return
+	}
+	data, err := readStream(f)
+	if err != nil {
+		err = fmt.Errorf("%w: reading %s/_VBA_PROJECT", err, p.storage)
+		return
+	}
+	project, err = parsers.NewVBAProjectStream(data)
+	if err != nil {
+		err = fmt.Errorf("%w: parsing %s/_VBA_PROJECT", err, p.storage)
+	}
+	return
+}
+
+// hasPCode reports whether the module streams should hold p-code we can
+// disassemble. Projects written by tools rather than Office have a
+// version of 0xFFFF, and no p-code. Mac projects are big-endian.
+func (p *vbaProject) hasPCode() bool {
+	switch {
+	case p.model.Version == 0 || p.model.Version == 0xFFFF:
+		return false
+	case p.dir.SysKind != parsers.VBASysKindWin32 && p.dir.SysKind != parsers.VBASysKindWin64:
+		return false
+	}
+	return true
+}
+
+// List p-code the way pcodedmp does, a line number followed by its
+// instructions.
+func pcodeListing(lines []*parsers.PCodeLine) []byte {
+	buf := &bytes.Buffer{}
+	for i, line := range lines {
+		fmt.Fprintf(buf, "Line #%d:\n", i)
+		for _, instruction := range line.Instructions {
+			fmt.Fprintf(buf, "\t%s\n", instruction)
+		}
+	}
+	return buf.Bytes()
+}
+
+// stompingReason compares a module's p-code with its source, and explains
+// how they disagree. It returns "" if they could have come from the same
+// code. Every string literal in the p-code has to be in the source, and
+// so does every identifier the p-code refers to, whether called or not.
+// Identifiers come from _VBA_PROJECT's cache, which is read by guesswork.
+// If the cache couldn't be read, or the p-code refers to identifiers past
+// the end of it, the table is taken to be wrong, and nothing is reported
+// rather than a mismatch which may not be there.
+func stompingReason(lines []*parsers.PCodeLine, source []byte) string {
+	for _, line := range lines {
+		for _, i := range line.Instructions {
+			if i.Unresolved {
+				return ""
+			}
+		}
+	}
+
+	code := false
+	for _, line := range strings.Split(string(source), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "Attribute ") {
+			code = true
+			break
+		}
+	}
+	tokens := sourceTokens(source)
+	instructions := 0
+	for _, line := range lines {
+		instructions += len(line.Instructions)
+		for _, i := range line.Instructions {
+			for _, id := range i.Identifiers {
+				if tokens[strings.ToLower(id)] {
+					continue
+				}
+				if strings.Contains(i.Mnemonic, "Call") {
+					return fmt.Sprintf("call to %s is in the p-code, but not the source", id)
+				}
+				return fmt.Sprintf("identifier %s is in the p-code, but not the source", id)
+			}
+			if i.Mnemonic != "LitStr" || i.Literal == "" {
+				continue
+			}
+			// Quotes are doubled in the source, but not in the p-code.
+			escaped := strings.ReplaceAll(i.Literal, `"`, `""`)
+			if !bytes.Contains(source, []byte(i.Literal)) && !bytes.Contains(source, []byte(escaped)) {
+				return fmt.Sprintf("string literal %q is in the p-code, but not the source", i.Literal)
+			}
+		}
+	}
+	if instructions > 0 && !code {
+		return fmt.Sprintf("%d lines of p-code, but the source has no code", len(lines))
+	}
+	return ""
+}
+
+// The words of VBA source, lower-cased, as VBA ignores case. Bytes
+// outside ASCII are kept in words, as they may be letters in the
+// project's code page.
+func sourceTokens(source []byte) (tokens map[string]bool) {
+	tokens = map[string]bool{}
+	words := bytes.FieldsFunc(source, func(r rune) bool {
+		return !(r == '_' || r >= utf8.RuneSelf || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	for _, word := range words {
+		tokens[strings.ToLower(string(word))] = true
+	}
+	return
+}
+
+// Read a whole stream, without disturbing the reader's place in it.
+func readStream(f *mscfb.File) (data []byte, err error) {
+	if f.Size > maxVBAModuleSize {
+		err = fmt.Errorf("stream of %d bytes is larger than the limit of %d", f.Size, maxVBAModuleSize)
+		return
+	}
+	data, err = io.ReadAll(io.NewSectionReader(f, 0, f.Size))
+	return
+}
+
+// VBA projects in path order, so members are written in the same order
+// every time.
+func sortedProjects(projects map[string]*vbaProject) (sorted []*vbaProject) {
+	for _, p := range projects {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].storage < sorted[j].storage })
+	return
+}
@@ -0,0 +1,206 @@
+package unpackers
+
+import (
+	"encoding/binary"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/ashdwilson/ole/internal/cfbtest"
+	"github.com/ashdwilson/ole/internal/vbatest"
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+)
+
+var autoOpen = vbatest.Module{Name: "Module1", Source: "Attribute VB_Name = \"Module1\"\r\nSub AutoOpen()\r\n    Shell \"calc.exe\"\r\nEnd Sub\r\n"}
+
+// VBA source is decompressed out of vbaProject.bin, and the project
+// ends up on its storage.
+func TestMSCFBVBAProject(t *testing.T) {
+	cfb := cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{
+		{Name: "ThisDocument", Class: true, Source: "Attribute VB_Name = \"ThisDocument\"\r\n"},
+		autoOpen,
+	})
+	r, sink, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storages["VBA"] == nil || r.Storages["VBA"].VBA == nil {
+		t.Fatalf("expected a VBA project in the VBA storage, got %+v", r.Storages)
+	}
+	project := r.Storages["VBA"].VBA
+	if project.Name != "Project" || project.CodePage != 1252 || len(project.Modules) != 2 {
+		t.Fatalf("project mismatch: %+v", project)
+	}
+	for i, expected := range []models.VBAModule{
+		{Name: "ThisDocument", StreamName: "ThisDocument", Type: "Class", TextOffset: 64, Source: "VBA/ThisDocument.cls"},
+		{Name: "Module1", StreamName: "Module1", Type: "Procedural", TextOffset: 64, Source: "VBA/Module1.bas"},
+	} {
+		if *project.Modules[i] != expected {
+			t.Errorf("module %d mismatch - expected %+v got %+v", i, expected, project.Modules[i])
+		}
+	}
+	source, err := fs.ReadFile(sink, "VBA/Module1.bas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(source) != autoOpen.Source {
+		t.Errorf("source mismatch: %q", source)
+	}
+}
+
+// Binary documents keep their VBA project under Macros.
+func TestMSCFBVBAMacrosStorage(t *testing.T) {
+	cfb := cfbtest.New()
+	cfb.SetCLSID("", cfbtest.CLSID("{00020906-0000-0000-C000-000000000046}"))
+	cfb.Stream("WordDocument", []byte("not much of a document"))
+	vbatest.AddProject(cfb, "Macros", []vbatest.Module{autoOpen})
+	r, sink, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storages["Macros/VBA"] == nil || r.Storages["Macros/VBA"].VBA == nil {
+		t.Fatalf("expected a VBA project in Macros/VBA, got %+v", r.Storages)
+	}
+	source, err := fs.ReadFile(sink, "Macros/VBA/Module1.bas")
+	if err != nil || string(source) != autoOpen.Source {
+		t.Errorf("expected the module source, got %q, %v", source, err)
+	}
+}
+
+// P-code is disassembled next to the source, and modules whose p-code
+// doesn't match their source are flagged.
+func TestMSCFBVBAStomping(t *testing.T) {
+	// 32-bit opcodes for a Sub, a string literal, and End Sub
+	sub := binary.LittleEndian.AppendUint32([]byte{150, 0}, 0x10)
+	litStr := func(s string) []byte {
+		b := binary.LittleEndian.AppendUint16([]byte{182, 0}, uint16(len(s)))
+		b = append(b, s...)
+		if len(s)%2 != 0 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	endSub := []byte{111, 0}
+	// Call the project's identifier at index, with one argument.
+	call := func(index int) []byte {
+		return binary.LittleEndian.AppendUint16(binary.LittleEndian.AppendUint16([]byte{65, 0}, vbatest.ID(index)), 1)
+	}
+	identifiers := []string{"AutoOpen", "Shell"}
+
+	clean := autoOpen
+	clean.PCode = [][]byte{sub, append(litStr("calc.exe"), call(1)...), endSub}
+	cfb := cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{
+		clean,
+		{Name: "Module2", Source: "Attribute VB_Name = \"Module2\"\r\n", PCode: [][]byte{sub, litStr("cmd.exe /c whoami"), endSub}},
+	}, identifiers...)
+	r, sink, err := unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storages["VBA"] == nil || r.Storages["VBA"].VBA == nil {
+		t.Fatalf("expected a VBA project in the VBA storage, got %+v", r.Storages)
+	}
+	project := r.Storages["VBA"].VBA
+	if project.Version != 0xB2 || project.SysKind != 1 {
+		t.Errorf("project mismatch: %+v", project)
+	}
+	modules := project.Modules
+	if len(modules) != 2 {
+		t.Fatalf("expected 2 modules, got %d", len(modules))
+	}
+	if m := modules[0]; m.Stomped || m.PCode != "VBA/Module1.pcode" || m.PCodeLines != 3 {
+		t.Errorf("expected Module1 to be disassembled and not stomped, got %+v", m)
+	}
+	if m := modules[1]; !m.Stomped || !strings.Contains(m.StompingReason, "cmd.exe /c whoami") {
+		t.Errorf("expected Module2 to be stomped, got %+v", m)
+	}
+	listing, err := fs.ReadFile(sink, "VBA/Module2.pcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Line #0:\n\tFuncDefn func_00000010\nLine #1:\n\tLitStr 0x0011 \"cmd.exe /c whoami\"\nLine #2:\n\tEndSub\n"
+	if string(listing) != expected {
+		t.Errorf("listing mismatch - expected %q got %q", expected, listing)
+	}
+
+	listing, err = fs.ReadFile(sink, "VBA/Module1.pcode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(listing), "ArgsCall (Call) Shell 0x0001") {
+		t.Errorf("expected the call to Shell to be resolved, got %q", listing)
+	}
+
+	// Without the stomped module, nothing is flagged.
+	cfb = cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{clean}, identifiers...)
+	r, _, err = unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Storages["VBA"].VBA.Modules[0]; m.Stomped {
+		t.Errorf("expected a clean project, got %+v", m)
+	}
+
+	// Source swapped for other code with the same string literals is
+	// caught by the identifiers the p-code calls.
+	swapped := clean
+	swapped.Source = "Attribute VB_Name = \"Module1\"\r\nSub AutoOpen()\r\n    MsgBox \"calc.exe\"\r\nEnd Sub\r\n"
+	cfb = cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{swapped}, identifiers...)
+	r, _, err = unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Storages["VBA"] == nil || r.Storages["VBA"].VBA == nil {
+		t.Fatalf("expected a VBA project in the VBA storage, got %+v", r.Storages)
+	}
+	if m := r.Storages["VBA"].VBA.Modules[0]; !m.Stomped || !strings.Contains(m.StompingReason, "Shell") {
+		t.Errorf("expected Module1 to be stomped for its call to Shell, got %+v", m)
+	}
+
+	// The same, with an identifier table which doesn't look right, isn't
+	// flagged, as the p-code's identifiers can't be told.
+	cfb = cfbtest.New()
+	vbatest.AddProject(cfb, "", []vbatest.Module{swapped}, "AutoOpen", "Sh\x01ll")
+	r, _, err = unpack(t, &MSCFB{}, cfb.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := r.Storages["VBA"].VBA.Modules[0]; m.Stomped {
+		t.Errorf("expected a project with a garbled identifier table not to be flagged, got %+v", m)
+	}
+}
+
+func TestStompingReason(t *testing.T) {
+	source := []byte("Attribute VB_Name = \"Module1\"\r\nSub AutoOpen()\r\n    MsgBox \"Hello\"\r\nEnd Sub\r\n")
+	call := func(id string) *parsers.PCodeInstruction {
+		return &parsers.PCodeInstruction{Mnemonic: "ArgsCall", Identifiers: []string{id}}
+	}
+	literal := &parsers.PCodeInstruction{Mnemonic: "LitStr", Literal: "Hello"}
+	unresolved := &parsers.PCodeInstruction{Mnemonic: "ArgsCall", Operands: []string{"id_0400"}, Unresolved: true}
+	tests := []struct {
+		name         string
+		instructions []*parsers.PCodeInstruction
+		want         string
+	}{
+		{"matching", []*parsers.PCodeInstruction{literal, call("msgbox")}, ""},
+		{"call", []*parsers.PCodeInstruction{literal, call("Shell")}, "call to Shell"},
+		{"literal", []*parsers.PCodeInstruction{{Mnemonic: "LitStr", Literal: "calc.exe"}}, `string literal "calc.exe"`},
+		// An identifier past the end of the table means the table
+		// doesn't fit the module, so nothing can be said.
+		{"unresolved", []*parsers.PCodeInstruction{call("Shell"), unresolved}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []*parsers.PCodeLine{{Instructions: tt.instructions}}
+			got := stompingReason(lines, source)
+			if (tt.want == "") != (got == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("reason mismatch - expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package unpackers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+	"github.com/ashdwilson/ole/pkg/sinks"
+)
+
+// The Workbook implementation of Unpacker parses the BIFF8 Workbook
+// stream of an .xls file, for its sheets, defined names and Excel 4.0
+// (XLM) macros. Nothing is extracted: it's all recorded on the stream's
+// result.
+type Workbook struct{}
+
+// Largest Workbook stream we'll read into memory.
+const maxWorkbookSize = 64 << 20
+
+func init() {
+	Register(Registration{
+		Name: "workbook",
+		// Only the stream of a compound file, not any file of that name.
+		Match: Matcher{
+			MIMETypes:   []string{"application/octet-stream"},
+			FileNames:   []string{"Workbook"},
+			ParentTypes: cfbMIMETypes,
+		},
+		Unpacker: &Workbook{},
+	})
+}
+
+func (u *Workbook) UnpackStream(inpath string, stream io.ReaderAt, size int64, results *models.Results, sink sinks.Sink) (err error) {
+	if size > maxWorkbookSize {
+		err = fmt.Errorf("workbook stream of %d bytes is larger than the limit of %d", size, maxWorkbookSize)
+		return
+	}
+	data, err := io.ReadAll(io.NewSectionReader(stream, 0, size))
+	if err != nil {
+		err = fmt.Errorf("%w: reading the Workbook stream", err)
+		return
+	}
+	workbook, err := parsers.NewWorkbook(data)
+	if workbook != nil {
+		results.Get(inpath).Workbook = workbookModel(workbook)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: parsing the Workbook stream", err)
+	}
+	return
+}
+
+// Convert a parsed workbook to its model, with the formulas of all its
+// macro sheets in one listing.
+func workbookModel(w *parsers.Workbook) (m *models.Workbook) {
+	m = &models.Workbook{Encrypted: w.Encrypted}
+	for _, s := range w.Sheets {
+		m.Sheets = append(m.Sheets, &models.Sheet{Name: s.Name, Type: s.Type, Visibility: s.Visibility})
+		for _, f := range s.Formulas {
+			m.Macros = append(m.Macros, &models.XLMFormula{Sheet: s.Name, Cell: f.Cell(), Formula: f.Formula})
+		}
+	}
+	for _, n := range w.Names {
+		m.Names = append(m.Names, &models.DefinedName{
+			Name:    n.Name,
+			Sheet:   n.Sheet,
+			Formula: n.Formula,
+			Hidden:  n.Hidden,
+			Builtin: n.Builtin,
+			Macro:   n.Macro,
+			AutoRun: n.AutoRun(),
+		})
+	}
+	return
+}
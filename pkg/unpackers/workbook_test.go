@@ -0,0 +1,72 @@
+package unpackers
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+)
+
+// The Workbook stream of a real .xls file is parsed, and nothing is
+// extracted from it.
+func TestWorkbookUnpackStream(t *testing.T) {
+	_, cfb := unpackSample(t, &MSCFB{}, "test.xls")
+	data, err := fs.ReadFile(cfb, "Workbook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, sink, err := unpack(t, &Workbook{}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Workbook == nil {
+		t.Fatalf("expected a workbook on the stream's result")
+	}
+	if r.Expanded {
+		t.Errorf("expected the stream not to be marked expanded")
+	}
+	if entries, _ := fs.ReadDir(sink, "."); len(entries) != 0 {
+		t.Errorf("expected nothing to be extracted, got %v", entries)
+	}
+	if len(r.Workbook.Sheets) != 3 || *r.Workbook.Sheets[0] != (models.Sheet{Name: "Test sheet 1", Type: "Worksheet", Visibility: "Visible"}) {
+		t.Errorf("sheets mismatch: %+v", r.Workbook.Sheets)
+	}
+	if len(r.Workbook.Macros) != 0 || len(r.Workbook.Names) != 0 {
+		t.Errorf("expected no macros or names, got %+v", r.Workbook)
+	}
+}
+
+// The formulas of all macro sheets are listed together, with the sheet
+// they're on.
+func TestWorkbookModel(t *testing.T) {
+	w := &parsers.Workbook{
+		Sheets: []*parsers.Sheet{
+			{Name: "Sheet1", Type: parsers.SheetWorksheet, Visibility: parsers.SheetVisible},
+			{Name: "Macro1", Type: parsers.SheetMacro, Visibility: parsers.SheetVeryHidden, Formulas: []*parsers.CellFormula{
+				{Row: 0, Col: 0, Formula: `=EXEC("calc.exe")`},
+				{Row: 1, Col: 0, Formula: "=HALT()"},
+			}},
+		},
+		Names: []*parsers.DefinedName{{Name: "Auto_Open", Formula: "=Macro1!$A$1", Builtin: true}},
+	}
+	m := workbookModel(w)
+	if len(m.Sheets) != 2 || *m.Sheets[1] != (models.Sheet{Name: "Macro1", Type: "Macro", Visibility: "VeryHidden"}) {
+		t.Errorf("sheets mismatch: %+v", m.Sheets)
+	}
+	if len(m.Names) != 1 || *m.Names[0] != (models.DefinedName{Name: "Auto_Open", Formula: "=Macro1!$A$1", Builtin: true, AutoRun: true}) {
+		t.Errorf("names mismatch: %+v", m.Names)
+	}
+	expected := []models.XLMFormula{
+		{Sheet: "Macro1", Cell: "A1", Formula: `=EXEC("calc.exe")`},
+		{Sheet: "Macro1", Cell: "A2", Formula: "=HALT()"},
+	}
+	if len(m.Macros) != len(expected) {
+		t.Fatalf("expected %d macro formulas, got %+v", len(expected), m.Macros)
+	}
+	for i := range expected {
+		if *m.Macros[i] != expected[i] {
+			t.Errorf("formula %d mismatch - expected %+v got %+v", i, expected[i], m.Macros[i])
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package unpackers
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/ashdwilson/ole/pkg/models"
+	"github.com/ashdwilson/ole/pkg/parsers"
+)
+
+// MIME types OfficeZip gives the macro sheet parts of .xlsm and .xlsb
+// files, which hold Excel 4.0 (XLM) macros. These are the content types
+// Excel writes for them.
+const (
+	XLSXMacroSheetType = "application/vnd.ms-excel.macrosheet+xml"
+	XLSBMacroSheetType = "application/vnd.ms-excel.macrosheet"
+)
+
+func init() {
+	// Their formulas are decoded by OfficeZip, and listed on the
+	// document's result.
+	Register(Registration{
+		Name:  "xlm-macrosheets",
+		Match: Matcher{MIMETypes: []string{XLSXMacroSheetType, XLSBMacroSheetType}},
+	})
+}
+
+// The workbook of an .xlsx, .xlsm or .xlsb file.
+type officeWorkbook struct {
+	// Name of the workbook part, like xl/workbook.xml.
+	part     string
+	workbook *parsers.Workbook
+
+	// Macro sheets, by part name.
+	macroSheets map[string]*parsers.Sheet
+}
+
+// Find and parse the workbook of an OOXML spreadsheet, the package's
+// main part. This happens ahead of extraction, so macro sheet parts can
+// be typed as they're written. Other documents, as told by the content
+// type of their main part (see officeDocument), have no workbook, and
+// get a nil wb without their main part being read.
+func findOfficeWorkbook(pkg *zipPackage, doc *models.OfficeDocument) (wb *officeWorkbook, err error) {
+	if doc == nil || doc.Application != "Excel" {
+		return
+	}
+	main := doc.MainPart
+	data, err := pkg.read(main)
+	if data == nil {
+		return
+	}
+	workbookRels, err := pkg.relationships(main)
+	if err != nil {
+		return
+	}
+	relTypes := map[string]string{}
+	relTargets := map[string]string{}
+	for _, rel := range workbookRels {
+		relTypes[rel.ID] = rel.Type
+		relTargets[rel.ID] = parsers.ResolveTarget(main, rel.Target)
+	}
+	var workbook *parsers.Workbook
+	if path.Ext(main) == ".bin" {
+		workbook, err = parsers.NewXLSBWorkbook(data, relTypes)
+	} else {
+		workbook, err = parsers.NewXLSXWorkbook(data, relTypes)
+	}
+	if errors.Is(err, parsers.ErrNotXLSBWorkbook) || errors.Is(err, parsers.ErrNotXLSXWorkbook) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: parsing %s", err, main)
+	}
+	if workbook == nil {
+		return
+	}
+	wb = &officeWorkbook{part: main, workbook: workbook, macroSheets: map[string]*parsers.Sheet{}}
+	for _, s := range workbook.Sheets {
+		if s.Type == parsers.SheetMacro && relTargets[s.RelID] != "" {
+			wb.macroSheets[relTargets[s.RelID]] = s
+		}
+	}
+	return
+}
+
+// partType returns the MIME type of a part of the workbook's package,
+// or "" to leave it to detection.
+func (wb *officeWorkbook) partType(name string) string {
+	if _, ok := wb.macroSheets[name]; !ok {
+		return ""
+	}
+	if path.Ext(name) == ".bin" {
+		return XLSBMacroSheetType
+	}
+	return XLSXMacroSheetType
+}
+
+// Decode the formulas of each macro sheet, and convert the workbook to
+// its model.
+func (wb *officeWorkbook) parseMacroSheets(pkg *zipPackage) (m *models.Workbook, err error) {
+	errs := []error{}
+	parts := []string{}
+	for part := range wb.macroSheets {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	for _, part := range parts {
+		var data []byte
+		data, err = pkg.read(part)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if data == nil {
+			errs = append(errs, fmt.Errorf("macro sheet %s is missing", part))
+			continue
+		}
+		if path.Ext(part) == ".bin" {
+			err = wb.workbook.ParseXLSBSheet(wb.macroSheets[part], data)
+		} else {
+			err = wb.workbook.ParseXLSXSheet(wb.macroSheets[part], data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: parsing macro sheet %s", err, part))
+		}
+	}
+	m = workbookModel(wb.workbook)
+	err = errors.Join(errs...)
+	return
+}